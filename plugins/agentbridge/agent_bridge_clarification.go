@@ -0,0 +1,280 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"strings"
+
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+	"github.com/TykTechnologies/kin-openapi/routers"
+)
+
+const (
+	DEFAULT_ASK_FOR_CLARIFICATION = false
+	CLARIFICATION_KEY_PREFIX      = "clarification:"
+	CLARIFICATION_TTL             = 600 // In seconds
+	REQUEST_BODY_FIELD            = "requestBody"
+)
+
+// pendingClarification is a request waiting for the user to provide the
+// missing information. It is saved in the store until the follow-up request.
+type pendingClarification struct {
+	ConversationID string                  `json:"conversation_id"`
+	APIID          string                  `json:"api_id"`
+	OperationID    string                  `json:"operation_id"`
+	Sentence       string                  `json:"sentence"`
+	PathParams     map[string]string       `json:"path_params"`
+	Params         *openAPIOperationParams `json:"params"`
+	MissingFields  []string                `json:"missing_fields"`
+}
+
+// missingField is a required parameter that was not found in the query.
+type missingField struct {
+	Name        string
+	In          string
+	Description string
+}
+
+func newRandomToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func saveClarification(pending *pendingClarification) error {
+	if agentBridgeStore == nil {
+		return fmt.Errorf("storage is not configured")
+	}
+
+	jsonPending, err := json.Marshal(pending)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the pending clarification: %w", err)
+	}
+
+	logger.Debugf("[+] Save pending clarification: %s", pending.ConversationID)
+	return agentBridgeStore.SetKey(CLARIFICATION_KEY_PREFIX+pending.ConversationID, string(jsonPending), CLARIFICATION_TTL)
+}
+
+func loadClarification(conversationID string) (*pendingClarification, error) {
+	if agentBridgeStore == nil {
+		return nil, fmt.Errorf("storage is not configured")
+	}
+
+	value, err := agentBridgeStore.GetKey(CLARIFICATION_KEY_PREFIX + conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("unknown or expired conversation %s: %w", conversationID, err)
+	}
+
+	pending := &pendingClarification{}
+	if err := json.Unmarshal([]byte(value), pending); err != nil {
+		return nil, fmt.Errorf("conversion error for the pending clarification: %w", err)
+	}
+	return pending, nil
+}
+
+func deleteClarification(conversationID string) {
+	if agentBridgeStore == nil {
+		return
+	}
+	logger.Debugf("[+] Delete pending clarification: %s", conversationID)
+	agentBridgeStore.DeleteKey(CLARIFICATION_KEY_PREFIX + conversationID)
+}
+
+// getConversation returns the pending clarification referenced by the
+// request, if any. The error can be sent back to the client.
+func getConversation(r *http.Request) (*pendingClarification, error) {
+	conversationID := r.Header.Get(HEADER_X_NL_CONVERSATION)
+	if conversationID == "" {
+		return nil, nil
+	}
+
+	pending, err := loadClarification(conversationID)
+	if err != nil {
+		logger.Errorf("[+] Error loading the conversation: %s", err)
+		return nil, &nlQueryError{
+			StatusCode: http.StatusNotFound,
+			Message:    "i'm sorry but I can't find the conversation you are referring to, it may have expired",
+		}
+	}
+	return pending, nil
+}
+
+// mergeClarification adds the answer of the user to the pending request.
+// Parameters found in the answer take precedence.
+func mergeClarification(pending *pendingClarification, params *openAPIOperationParams) *openAPIOperationParams {
+	if pending.Params == nil {
+		return params
+	}
+
+	merged := *pending.Params
+	merged.InPathParams = maps.Clone(pending.Params.InPathParams)
+	if merged.InPathParams == nil {
+		merged.InPathParams = map[string]string{}
+	}
+	maps.Copy(merged.InPathParams, params.InPathParams)
+
+	merged.InQueryParams = maps.Clone(pending.Params.InQueryParams)
+	if merged.InQueryParams == nil {
+		merged.InQueryParams = map[string][]string{}
+	}
+	maps.Copy(merged.InQueryParams, params.InQueryParams)
+
+	merged.InHeaderParams = maps.Clone(pending.Params.InHeaderParams)
+	if merged.InHeaderParams == nil {
+		merged.InHeaderParams = map[string][]string{}
+	}
+	maps.Copy(merged.InHeaderParams, params.InHeaderParams)
+
+	merged.InCookieParams = maps.Clone(pending.Params.InCookieParams)
+	if merged.InCookieParams == nil {
+		merged.InCookieParams = map[string]string{}
+	}
+	maps.Copy(merged.InCookieParams, params.InCookieParams)
+
+	if params.hasRequestBody() {
+		merged.RequestBody = mergeRequestBodies(pending.Params.RequestBody, params.RequestBody)
+	}
+	return &merged
+}
+
+// mergeRequestBodies adds the fields of the answer to the body of the pending
+// request when both are objects. Otherwise the body of the answer is used.
+func mergeRequestBodies(pending json.RawMessage, answer json.RawMessage) json.RawMessage {
+	fields := map[string]json.RawMessage{}
+	answerFields := map[string]json.RawMessage{}
+	if json.Unmarshal(pending, &fields) != nil || fields == nil || json.Unmarshal(answer, &answerFields) != nil {
+		return answer
+	}
+	maps.Copy(fields, answerFields)
+	merged, err := json.Marshal(fields)
+	if err != nil {
+		return answer
+	}
+	return merged
+}
+
+// findMissingFields returns the required parameters of the operation that
+// are neither in the generated parameters nor in the original request.
+func findMissingFields(r *http.Request, route *routers.Route, pathParams map[string]string, params *openAPIOperationParams) []missingField {
+	missing := []missingField{}
+
+	// Operation parameters override the ones defined at the path level
+	parameters := append(openapi3.Parameters{}, route.Operation.Parameters...)
+	if route.PathItem != nil {
+		parameters = append(parameters, route.PathItem.Parameters...)
+	}
+
+	seen := map[string]bool{}
+	for _, parameterRef := range parameters {
+		parameter := parameterRef.Value
+		if parameter == nil {
+			continue
+		}
+		key := parameter.In + ":" + parameter.Name
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if parameter.Required && !hasParameter(r, pathParams, params, parameter) {
+			missing = append(missing, missingField{Name: parameter.Name, In: parameter.In, Description: parameter.Description})
+		}
+	}
+
+	requestBody := route.Operation.RequestBody
+	if requestBody != nil && requestBody.Value != nil && requestBody.Value.Required && !params.hasRequestBody() {
+		missing = append(missing, missingField{Name: REQUEST_BODY_FIELD, In: "body", Description: requestBody.Value.Description})
+	}
+
+	return missing
+}
+
+func hasParameter(r *http.Request, pathParams map[string]string, params *openAPIOperationParams, parameter *openapi3.Parameter) bool {
+	switch parameter.In {
+	case openapi3.ParameterInPath:
+		if v := params.InPathParams[parameter.Name]; v != "" {
+			return true
+		}
+		v := pathParams[parameter.Name]
+		return v != "" && v != "{"+parameter.Name+"}"
+	case openapi3.ParameterInQuery:
+		return len(params.InQueryParams[parameter.Name]) > 0 || r.URL.Query().Has(parameter.Name)
+	case openapi3.ParameterInHeader:
+		for hName, hValues := range params.InHeaderParams {
+			if strings.EqualFold(hName, parameter.Name) && len(hValues) > 0 {
+				return true
+			}
+		}
+		return r.Header.Get(parameter.Name) != ""
+	case openapi3.ParameterInCookie:
+		if v := params.InCookieParams[parameter.Name]; v != "" {
+			return true
+		}
+		_, err := r.Cookie(parameter.Name)
+		return err == nil
+	}
+	// Other locations can't be filled by the LLM
+	return true
+}
+
+func buildClarificationQuestion(missing []missingField) string {
+	fields := []string{}
+	for _, field := range missing {
+		if field.Description != "" {
+			fields = append(fields, fmt.Sprintf("%s (%s)", field.Name, strings.TrimSpace(field.Description)))
+		} else {
+			fields = append(fields, field.Name)
+		}
+	}
+	return fmt.Sprintf("i'm sorry but some information is missing from your query, could you please provide the following: %s?", strings.Join(fields, ", "))
+}
+
+// askForClarification saves the incomplete request and returns the error
+// asking the user for the missing information.
+func askForClarification(conversationID string, config *PluginDataConfig, route *routers.Route, nlSentence string, pathParams map[string]string, params *openAPIOperationParams, missing []missingField) error {
+	missingFields := []string{}
+	for _, field := range missing {
+		missingFields = append(missingFields, field.Name)
+	}
+	queryErr := &nlQueryError{
+		StatusCode:    http.StatusUnprocessableEntity,
+		Message:       buildClarificationQuestion(missing),
+		MissingFields: missingFields,
+	}
+
+	if conversationID == "" {
+		var err error
+		conversationID, err = newRandomToken()
+		if err != nil {
+			logger.Errorf("[+] Error creating the conversation id: %s", err)
+			return queryErr
+		}
+	}
+
+	pending := &pendingClarification{
+		ConversationID: conversationID,
+		APIID:          config.APIID,
+		OperationID:    route.Operation.OperationID,
+		Sentence:       nlSentence,
+		PathParams:     pathParams,
+		Params:         params,
+		MissingFields:  missingFields,
+	}
+	if err := saveClarification(pending); err != nil {
+		// The user can still rephrase the whole query
+		logger.Errorf("[+] Error saving the pending clarification: %s", err)
+		return queryErr
+	}
+
+	queryErr.ConversationID = conversationID
+	return queryErr
+}
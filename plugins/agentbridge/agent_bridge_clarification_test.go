@@ -0,0 +1,162 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+const clarificationTestSpec = `{
+	"openapi": "3.0.0",
+	"info": {"title": "GitHub", "version": "1.0.0"},
+	"paths": {
+		"/repos/{owner}/{repo}/issues": {
+			"parameters": [
+				{"name": "owner", "in": "path", "required": true, "description": "The account owner of the repository", "schema": {"type": "string"}}
+			],
+			"post": {
+				"operationId": "createIssue",
+				"parameters": [
+					{"name": "repo", "in": "path", "required": true, "schema": {"type": "string"}},
+					{"name": "X-GitHub-Api-Version", "in": "header", "required": true, "schema": {"type": "string"}},
+					{"name": "labels", "in": "query", "schema": {"type": "string"}}
+				],
+				"requestBody": {
+					"required": true,
+					"description": "The issue to create",
+					"content": {"application/json": {"schema": {"type": "object"}}}
+				},
+				"responses": {"201": {"description": "Created"}}
+			}
+		}
+	}
+}`
+
+func TestFindMissingFields(t *testing.T) {
+	tests := []struct {
+		description    string
+		params         string
+		pathParams     map[string]string
+		headers        map[string]string
+		expectedFields []string
+	}{
+		{
+			"Everything is missing",
+			`{}`,
+			map[string]string{},
+			map[string]string{},
+			[]string{"repo", "X-GitHub-Api-Version", "owner", REQUEST_BODY_FIELD},
+		},
+		{
+			"Generated by the LLM",
+			`{"in_path_params": {"owner": "agntcy", "repo": "api-bridge-agnt"}, "in_header_params": {"x-github-api-version": ["2022-11-28"]}, "request_body": {}}`,
+			map[string]string{},
+			map[string]string{},
+			[]string{},
+		},
+		{
+			"Provided by the original request",
+			`{"request_body": {}}`,
+			map[string]string{"owner": "agntcy", "repo": "{repo}"},
+			map[string]string{"X-GitHub-Api-Version": "2022-11-28"},
+			[]string{"repo"},
+		},
+	}
+
+	route := getTestRoute(t, clarificationTestSpec, "/repos/{owner}/{repo}/issues", http.MethodPost)
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			params := &openAPIOperationParams{}
+			assert.Nil(t, json.Unmarshal([]byte(tt.params), params))
+
+			r := httptest.NewRequest(http.MethodPost, "/repos/{owner}/{repo}/issues", nil)
+			for name, value := range tt.headers {
+				r.Header.Set(name, value)
+			}
+
+			fields := []string{}
+			for _, field := range findMissingFields(r, route, tt.pathParams, params) {
+				fields = append(fields, field.Name)
+			}
+			assert.Equal(t, tt.expectedFields, fields)
+		})
+	}
+}
+
+func TestHasCookieParameter(t *testing.T) {
+	parameter := &openapi3.Parameter{Name: "theme", In: openapi3.ParameterInCookie}
+
+	r := httptest.NewRequest(http.MethodPost, "/dashboards", nil)
+	assert.False(t, hasParameter(r, nil, &openAPIOperationParams{}, parameter))
+	assert.True(t, hasParameter(r, nil, &openAPIOperationParams{InCookieParams: map[string]string{"theme": "dark"}}, parameter))
+	r.Header.Set("Cookie", "theme=light")
+	assert.True(t, hasParameter(r, nil, &openAPIOperationParams{}, parameter))
+}
+
+func TestBuildClarificationQuestion(t *testing.T) {
+	question := buildClarificationQuestion([]missingField{
+		{Name: "owner", In: "path", Description: "The account owner of the repository"},
+		{Name: "repo", In: "path"},
+	})
+	assert.Equal(t, "i'm sorry but some information is missing from your query, could you please provide the following: owner (The account owner of the repository), repo?", question)
+}
+
+func TestMergeClarification(t *testing.T) {
+	pending := &pendingClarification{
+		Params: &openAPIOperationParams{
+			InPathParams:   map[string]string{"repo": "api-bridge-agnt"},
+			InQueryParams:  map[string][]string{"labels": {"bug"}},
+			InHeaderParams: map[string][]string{},
+			RequestBody:    json.RawMessage(`{"title": "Crash on startup"}`),
+		},
+	}
+	answer := &openAPIOperationParams{
+		InPathParams:   map[string]string{"owner": "agntcy"},
+		InCookieParams: map[string]string{"theme": "dark"},
+		RequestBody:    json.RawMessage(`{"labels": ["bug"]}`),
+	}
+
+	merged := mergeClarification(pending, answer)
+	assert.Equal(t, map[string]string{"owner": "agntcy", "repo": "api-bridge-agnt"}, merged.InPathParams)
+	assert.Equal(t, []string{"bug"}, merged.InQueryParams["labels"])
+	assert.Equal(t, map[string]string{"theme": "dark"}, merged.InCookieParams)
+	assert.JSONEq(t, `{"title": "Crash on startup", "labels": ["bug"]}`, string(merged.RequestBody))
+	// The pending request must not be modified
+	assert.Equal(t, map[string]string{"repo": "api-bridge-agnt"}, pending.Params.InPathParams)
+}
+
+func TestAskForClarification(t *testing.T) {
+	route := getTestRoute(t, clarificationTestSpec, "/repos/{owner}/{repo}/issues", http.MethodPost)
+	config := &PluginDataConfig{APIID: "github"}
+	params := &openAPIOperationParams{InPathParams: map[string]string{"repo": "api-bridge-agnt"}}
+	missing := []missingField{{Name: "owner", In: "path"}}
+
+	err := askForClarification("", config, route, "open an issue on api-bridge-agnt", map[string]string{}, params, missing)
+	var queryErr *nlQueryError
+	assert.ErrorAs(t, err, &queryErr)
+	assert.Equal(t, http.StatusUnprocessableEntity, queryErr.StatusCode)
+	assert.Equal(t, []string{"owner"}, queryErr.MissingFields)
+	assert.NotEmpty(t, queryErr.ConversationID)
+
+	// The follow-up request references the conversation
+	r := httptest.NewRequest(http.MethodPost, "/repos/{owner}/{repo}/issues", nil)
+	r.Header.Set(HEADER_X_NL_CONVERSATION, queryErr.ConversationID)
+	pending, err := getConversation(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "github", pending.APIID)
+	assert.Equal(t, "createIssue", pending.OperationID)
+	assert.Equal(t, "open an issue on api-bridge-agnt", pending.Sentence)
+	assert.Equal(t, params, pending.Params)
+
+	deleteClarification(queryErr.ConversationID)
+	_, err = getConversation(r)
+	assert.ErrorAs(t, err, &queryErr)
+	assert.Equal(t, http.StatusNotFound, queryErr.StatusCode)
+}
@@ -0,0 +1,708 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/TykTechnologies/kin-openapi/routers"
+	"github.com/TykTechnologies/tyk/apidef/oas"
+	"github.com/kelindar/search"
+)
+
+const (
+	SPEC_EXT_AI_INPUT_EXAMPLES = "x-nl-input-examples"
+
+	DEFAULT_MODEL_EMBEDDINGS_PATH  = "models"
+	DEFAULT_MODEL_EMBEDDINGS_MODEL = "jina-embeddings-v2-base-en-q5_k_m.gguf"
+	DEFAULT_OPENAI_ENDPOINT        = "https://api.openai.com/v1"
+	DEFAULT_OPENAI_MODEL           = "gpt-4o-mini"
+
+	MAX_UTERANCE_LENGTH      = 1500
+	VECTORIZER_GPU_LAYERS    = 1
+	DEFAULT_MAX_REQUEST_SIZE = -1 // in characters; -1 means no limit
+)
+
+type AzureConfig struct {
+	OpenAIKey       string `json:"openAIKey"`
+	OpenAIEndpoint  string `json:"openAIEndpoint"`
+	ModelDeployment string `json:"modelDeployment"`
+	// Provider is one of "openai", "azure" or "openai-compatible"; by default
+	// it is "openai" for the OpenAI endpoint and "azure" for any other one
+	Provider string            `json:"provider"`
+	NoAuth   bool              `json:"noAuth"`  // Don't send any credential, for local servers
+	Headers  map[string]string `json:"headers"` // Extra headers sent with every LLM request
+}
+
+type NLAPIConfig struct {
+	AzureConfig AzureConfig
+	// MaxOperationPromptTokens is the budget of the operation in the prompt; 0 is no limit
+	MaxOperationPromptTokens int
+	provider                 LLMProvider
+}
+
+var embeddingModels = map[string]Embedder{} // embedder name -> embedder
+var embeddingModelsLock = &sync.RWMutex{}
+var apiSpecIndices = map[string]*search.Index[string]{}     // apiId -> indices for ops in API spec
+var apiLexicalIndices = map[string]*lexicalIndex{}          // apiId -> BM25 index for ops in API spec, same lock
+var apiNegativeIndices = map[string]*search.Index[string]{} // apiId -> negative examples of ops in API spec, same lock
+var apiSpecIndicesLock = &sync.RWMutex{}
+
+// PluginConfig is only supported at the API definition level.
+var pluginConfig = map[string]*PluginDataConfig{} // api id -> config
+var pluginConfigLock = &sync.RWMutex{}
+
+type AIExtensionConfig struct {
+	InputExamples []string `json:"x-nl-input-examples"`
+	// NegativeExamples are queries that must not select the operation
+	NegativeExamples []string `json:"x-nl-negative-examples"`
+	// ReplaceExamples ignores the examples of the OpenAPI spec; by default the examples are appended
+	ReplaceExamples bool `json:"replaceExamples"`
+}
+
+type PluginDataConfig struct {
+	AzureConfig          AzureConfig                   `json:"azureConfig"`
+	SelectOperations     map[string]*AIExtensionConfig `json:"selectOperations"`
+	SelectModelEmbedding string                        `json:"selectModelEmbedding"`
+	SelectModelsPath     string                        `json:"selectModelsPath"`
+	EmbeddingConfig      EmbeddingConfig               `json:"embeddingConfig"`
+	LlmConfig            *NLAPIConfig                  `json:"llmConfig"`
+	// LlmProvider is the name of the LLM backend; default is "openai"
+	LlmProvider string `json:"llmProvider"`
+	// RelevanceThreshold is the minimum matching score to select an operation; default is 0.5
+	RelevanceThreshold float64 `json:"relevanceThreshold,omitempty"`
+	// SelectTopK is the number of candidate operations re-ranked by the LLM; default is 1 (no re-ranking)
+	SelectTopK int `json:"selectTopK"`
+	// RerankConfidenceThreshold is the minimum confidence of the LLM re-ranking the candidates; default is 0.5
+	RerankConfidenceThreshold float64 `json:"rerankConfidenceThreshold"`
+	// LexicalWeight is the weight of the BM25 score fused with the embeddings relevance, between 0 and 1; default is 0.3
+	LexicalWeight float64 `json:"lexicalWeight"`
+	// NegativeWeight is the penalty of the operations whose negative examples match the input better; default is 1
+	NegativeWeight float64 `json:"negativeWeight"`
+	// ValidateRequests checks the generated request against the OpenAPI operation; default is false
+	ValidateRequests bool `json:"validateRequests"`
+	// MaxRepairAttempts is the number of times the LLM can fix an invalid request; default is 2
+	MaxRepairAttempts int `json:"maxRepairAttempts"`
+	// AskForClarification asks the user for the missing required parameters; default is false
+	AskForClarification bool `json:"askForClarification"`
+	// RequireConfirmation lists the operations to confirm before calling the upstream
+	RequireConfirmation ConfirmationConfig `json:"requireConfirmation"`
+	// MaxPlanSteps is the maximum number of calls made by the planner; default is 5
+	MaxPlanSteps int `json:"maxPlanSteps"`
+	// PlannerGatewayURL is the address of the gateway used by the planner to call the API
+	PlannerGatewayURL string `json:"plannerGatewayURL"`
+	// MaxOperationPromptTokens is the size of the operation in the prompts, larger schemas are compacted; default is 4000, 0 is no limit
+	MaxOperationPromptTokens int `json:"maxOperationPromptTokens"`
+	// IncludeOperations restricts the Natural Language queries to these operations; default is all of them
+	IncludeOperations OperationFilter `json:"includeOperations"`
+	// ExcludeOperations can't be reached with Natural Language queries
+	ExcludeOperations OperationFilter `json:"excludeOperations"`
+
+	APIID      string
+	ListenPath string
+
+	MaxRequestLength int64 `json:"maxRequestLength"` // MaxRequestSize is the maximum size of the request in characters; default is -1 (no limit)
+}
+
+func getApiId(r *http.Request) (string, error) {
+	apidef := getOASDefinition(r)
+
+	if apidef == nil {
+		// TOOD: fallback on classic...
+		return "", fmt.Errorf("API definition is nil")
+	}
+	gateway := apidef.GetTykExtension()
+	if gateway == nil {
+		return "", fmt.Errorf("the Tyk gateway definition is nil")
+	}
+	return gateway.Info.ID, nil
+}
+
+// parseSelectOperations reads the examples added to the operations in the
+// plugin configuration, in the same format as the x-nl-input-examples extension.
+func parseSelectOperations(configData map[string]any) map[string]*AIExtensionConfig {
+	selectOperations := map[string]*AIExtensionConfig{}
+	v, exists := configData["selectOperations"]
+	if !exists {
+		return selectOperations
+	}
+	operationsData, ok := v.(map[string]any)
+	if !ok {
+		logger.Warningf("[+] Invalid type for selectOperations: %T; ignoring", v)
+		return selectOperations
+	}
+
+	for operationId, operationData := range operationsData {
+		data, ok := operationData.(map[string]any)
+		if !ok {
+			logger.Warningf("[+] Invalid type for selectOperations.%s: %T; ignoring", operationId, operationData)
+			continue
+		}
+		aiExtentionConfig := &AIExtensionConfig{
+			InputExamples: getConfigStrings(data, SPEC_EXT_AI_INPUT_EXAMPLES),
+		}
+		if _, exists := data[SPEC_EXT_AI_NEGATIVE_EXAMPLES]; exists {
+			aiExtentionConfig.NegativeExamples = getConfigStrings(data, SPEC_EXT_AI_NEGATIVE_EXAMPLES)
+		}
+		if replace, exists := data["replaceExamples"]; exists {
+			if b, ok := replace.(bool); ok {
+				aiExtentionConfig.ReplaceExamples = b
+			} else {
+				logger.Warningf("[+] Invalid type for selectOperations.%s.replaceExamples: %T; ignoring", operationId, replace)
+			}
+		}
+		selectOperations[operationId] = aiExtentionConfig
+	}
+	return selectOperations
+}
+
+func getConfigValue(defaultValue string, configData map[string]any, configMapKey string, envValue string) string {
+	ret := defaultValue
+	if v, exists := configData[configMapKey]; exists {
+		if s, ok := v.(string); ok {
+			ret = s
+		} else {
+			logger.Warningf("[+] Invalid value for %s: %v; using default %s", configMapKey, v, defaultValue)
+		}
+	}
+	if envValue != "" && os.Getenv(envValue) != "" {
+		ret = os.Getenv(envValue)
+	}
+
+	return ret
+}
+
+func getEnvOrDefault(value string, envKey string, defaultValue string) string {
+	if value == "" {
+		value = os.Getenv(envKey)
+		if value == "" {
+			value = defaultValue
+		}
+	}
+	return value
+}
+
+func getEnvAsInt(envKey string, defaultValue int) int {
+	valueStr := os.Getenv(envKey)
+	if valueStr != "" {
+		if value, err := strconv.Atoi(valueStr); err == nil {
+			return value
+		}
+	}
+
+	return defaultValue
+}
+
+func parseAzureConfig(configData map[string]any) AzureConfig {
+	azureConfigData, exists := configData["azureConfig"].(map[string]any)
+	if !exists {
+		azureConfigData = map[string]any{}
+	}
+
+	azureConfig := AzureConfig{
+		OpenAIEndpoint:  getConfigValue(DEFAULT_OPENAI_ENDPOINT, azureConfigData, "openAIEndpoint", "OPENAI_ENDPOINT"),
+		OpenAIKey:       getConfigValue("", azureConfigData, "openAIKey", "OPENAI_API_KEY"),
+		ModelDeployment: getConfigValue(DEFAULT_OPENAI_MODEL, azureConfigData, "modelDeployment", "OPENAI_MODEL"),
+		Provider:        getConfigValue("", azureConfigData, "provider", "OPENAI_PROVIDER"),
+		Headers:         parseConfigHeaders(azureConfigData, "azureConfig"),
+	}
+	if azureConfig.Provider == "" {
+		azureConfig.Provider = getDefaultOpenAIProvider(azureConfig.OpenAIEndpoint)
+	}
+
+	if v, exists := azureConfigData["noAuth"]; exists {
+		if noAuth, ok := v.(bool); ok {
+			azureConfig.NoAuth = noAuth
+		} else {
+			logger.Warningf("[+] Invalid type for azureConfig.noAuth: %T; using default %t", v, azureConfig.NoAuth)
+		}
+	}
+
+	return azureConfig
+}
+
+// parseConfigHeaders reads the extra HTTP headers of a section of the config.
+func parseConfigHeaders(sectionData map[string]any, section string) map[string]string {
+	configHeaders := map[string]string{}
+	v, exists := sectionData["headers"]
+	if !exists {
+		return configHeaders
+	}
+	headers, ok := v.(map[string]any)
+	if !ok {
+		logger.Warningf("[+] Invalid type for %s.headers: %T; ignoring", section, v)
+		return configHeaders
+	}
+	for name, value := range headers {
+		if valueStr, ok := value.(string); ok {
+			configHeaders[name] = valueStr
+		} else {
+			logger.Warningf("[+] Invalid type for %s.headers.%s: %T; ignoring", section, name, value)
+		}
+	}
+	return configHeaders
+}
+
+func getDefaultOpenAIProvider(endpoint string) string {
+	if endpoint == DEFAULT_OPENAI_ENDPOINT {
+		return OPENAI_PROVIDER_OPENAI
+	}
+	return OPENAI_PROVIDER_AZURE
+}
+
+func parseConfigData(apiId string, configData map[string]any) (*PluginDataConfig, error) {
+	logger.Debugf("[+] Parsing config for api id: %s", apiId)
+
+	// Determine relevance threshold (default or overridden)
+	threshold := DEFAULT_RELEVANCE_THRESHOLD
+	if v, exists := configData["relevanceThreshold"]; exists {
+		if f, ok := v.(float64); ok {
+			threshold = f
+		} else {
+			logger.Warningf("[+] Invalid type for relevanceThreshold: %T; using default %f", v, threshold)
+		}
+	}
+	selectTopK := DEFAULT_SELECT_TOP_K
+	if v, exists := configData["selectTopK"]; exists {
+		if f, ok := v.(float64); ok && f >= 1 {
+			selectTopK = int(f)
+		} else {
+			logger.Warningf("[+] Invalid value for selectTopK: %v; using default %d", v, selectTopK)
+		}
+	}
+	rerankConfidenceThreshold := DEFAULT_RERANK_CONFIDENCE_THRESHOLD
+	if v, exists := configData["rerankConfidenceThreshold"]; exists {
+		if f, ok := v.(float64); ok && f >= 0 && f <= 1 {
+			rerankConfidenceThreshold = f
+		} else {
+			logger.Warningf("[+] Invalid value for rerankConfidenceThreshold: %v; using default %f", v, rerankConfidenceThreshold)
+		}
+	}
+	lexicalWeight := DEFAULT_LEXICAL_WEIGHT
+	if v, exists := configData["lexicalWeight"]; exists {
+		if f, ok := v.(float64); ok && f >= 0 && f <= 1 {
+			lexicalWeight = f
+		} else {
+			logger.Warningf("[+] Invalid value for lexicalWeight: %v; using default %f", v, lexicalWeight)
+		}
+	}
+	negativeWeight := DEFAULT_NEGATIVE_WEIGHT
+	if v, exists := configData["negativeWeight"]; exists {
+		if f, ok := v.(float64); ok && f >= 0 {
+			negativeWeight = f
+		} else {
+			logger.Warningf("[+] Invalid value for negativeWeight: %v; using default %f", v, negativeWeight)
+		}
+	}
+	validateRequests := DEFAULT_VALIDATE_REQUESTS
+	if v, exists := configData["validateRequests"]; exists {
+		if b, ok := v.(bool); ok {
+			validateRequests = b
+		} else {
+			logger.Warningf("[+] Invalid type for validateRequests: %T; using default %t", v, validateRequests)
+		}
+	}
+	maxPlanSteps := DEFAULT_MAX_PLAN_STEPS
+	if v, exists := configData["maxPlanSteps"]; exists {
+		if f, ok := v.(float64); ok && f >= 1 {
+			maxPlanSteps = int(f)
+		} else {
+			logger.Warningf("[+] Invalid value for maxPlanSteps: %v; using default %d", v, maxPlanSteps)
+		}
+	}
+	askForClarification := DEFAULT_ASK_FOR_CLARIFICATION
+	if v, exists := configData["askForClarification"]; exists {
+		if b, ok := v.(bool); ok {
+			askForClarification = b
+		} else {
+			logger.Warningf("[+] Invalid type for askForClarification: %T; using default %t", v, askForClarification)
+		}
+	}
+	maxRepairAttempts := DEFAULT_MAX_REPAIR_ATTEMPTS
+	if v, exists := configData["maxRepairAttempts"]; exists {
+		if f, ok := v.(float64); ok && f >= 0 {
+			maxRepairAttempts = int(f)
+		} else {
+			logger.Warningf("[+] Invalid value for maxRepairAttempts: %v; using default %d", v, maxRepairAttempts)
+		}
+	}
+	maxOperationPromptTokens := DEFAULT_MAX_OPERATION_PROMPT_TOKENS
+	if v, exists := configData["maxOperationPromptTokens"]; exists {
+		if f, ok := v.(float64); ok && f >= 0 {
+			maxOperationPromptTokens = int(f)
+		} else {
+			logger.Warningf("[+] Invalid value for maxOperationPromptTokens: %v; using default %d", v, maxOperationPromptTokens)
+		}
+	}
+	pluginDataConfig := &PluginDataConfig{
+		AzureConfig:               parseAzureConfig(configData),
+		LlmProvider:               getConfigValue(DEFAULT_LLM_PROVIDER, configData, "llmProvider", ""),
+		SelectOperations:          parseSelectOperations(configData),
+		SelectModelEmbedding:      getConfigValue(DEFAULT_MODEL_EMBEDDINGS_MODEL, configData, "selectModelEmbedding", ""),
+		SelectModelsPath:          getConfigValue(DEFAULT_MODEL_EMBEDDINGS_PATH, configData, "selectModelsPath", ""),
+		EmbeddingConfig:           parseEmbeddingConfig(configData),
+		RelevanceThreshold:        threshold,
+		SelectTopK:                selectTopK,
+		RerankConfidenceThreshold: rerankConfidenceThreshold,
+		LexicalWeight:             lexicalWeight,
+		NegativeWeight:            negativeWeight,
+		ValidateRequests:          validateRequests,
+		MaxRepairAttempts:         maxRepairAttempts,
+		AskForClarification:       askForClarification,
+		RequireConfirmation:       parseConfirmationConfig(configData),
+		MaxPlanSteps:              maxPlanSteps,
+		PlannerGatewayURL:         getConfigValue(DEFAULT_PLANNER_GATEWAY_URL, configData, "plannerGatewayURL", "PLANNER_GATEWAY_URL"),
+		MaxOperationPromptTokens:  maxOperationPromptTokens,
+		IncludeOperations:         parseOperationFilter(configData, "includeOperations"),
+		ExcludeOperations:         parseOperationFilter(configData, "excludeOperations"),
+
+		APIID:            apiId,
+		MaxRequestLength: int64(getEnvAsInt("MAX_REQUEST_SIZE", DEFAULT_MAX_REQUEST_SIZE)),
+	}
+
+	logger.Debugf("[+] Finished parsing config for api id: %s", apiId)
+	return pluginDataConfig, nil
+}
+
+func initPluginFromRequest(apiId string, apiDef *oas.OAS) (*PluginDataConfig, error) {
+	logger.Debugf("[+] Initializing for api id: %s", apiId)
+
+	if apiDef == nil {
+		err := fmt.Errorf("API definition is nil for api id: %s", apiId)
+		logger.Errorf("[+] initPluginFromRequest: %s", err)
+		return nil, err
+	}
+
+	middleware := apiDef.GetTykMiddleware()
+	if middleware == nil {
+		err := fmt.Errorf("the Tyk middleware definition is nil for api id: %s", apiId)
+		logger.Errorf("[+] initPluginFromRequest: %s", err)
+		return nil, err
+	}
+	globalPluginConfig := middleware.Global.PluginConfig
+	if globalPluginConfig == nil {
+		err := fmt.Errorf("the Tyk global.pluginConfig definition is nil for api id: %s", apiId)
+		logger.Errorf("[+] initPluginFromRequest: %s", err)
+		return nil, err
+	}
+	apiConfigData := globalPluginConfig.Data
+	if apiConfigData == nil {
+		err := fmt.Errorf("the Tyk pluginConfig.data definition is nil for api id: %s", apiId)
+		logger.Errorf("[+] initPluginFromRequest: %s", err)
+		return nil, err
+	}
+
+	pluginDataConfig, err := parseConfigData(apiId, apiConfigData.Value)
+	if err != nil {
+		logger.Errorf("[+] Unable to parse configuration data: %s", err)
+		return pluginDataConfig, err
+	}
+
+	addSpecSelectOperations(pluginDataConfig, apiDef)
+
+	provider, err := newLLMProvider(pluginDataConfig.LlmProvider, apiConfigData.Value)
+	if err != nil {
+		logger.Errorf("[+] Unable to create LLM provider %s: %s", pluginDataConfig.LlmProvider, err)
+		return pluginDataConfig, err
+	}
+
+	pluginDataConfig.LlmConfig = &NLAPIConfig{
+		AzureConfig:              pluginDataConfig.AzureConfig,
+		MaxOperationPromptTokens: pluginDataConfig.MaxOperationPromptTokens,
+		provider:                 provider,
+	}
+
+	if len(pluginDataConfig.SelectOperations) > 0 {
+		// Note: create embedder before initializing indices!
+		if err := loadEmbedder(apiId, pluginDataConfig); err != nil {
+			logger.Errorf("[+] %s", err)
+			return pluginDataConfig, err
+		}
+
+		if err := initSelectOperations(apiId, pluginDataConfig, apiDef); err != nil {
+			logger.Errorf("[+] failed to initialize select operations for api id %s: %s", apiId, err)
+			return pluginDataConfig, err
+		}
+	}
+
+	gateway := apiDef.GetTykExtension()
+	if gateway == nil {
+		return pluginDataConfig, fmt.Errorf("the Tyk gateway definition is nil")
+	}
+	pluginDataConfig.ListenPath = gateway.Server.ListenPath.Value
+
+	if err := initAPIRouter(apiId, apiDef); err != nil {
+		logger.Errorf("[+] %s", err)
+		return pluginDataConfig, err
+	}
+
+	// Save the plugin data config to the Redis store
+	if err := saveApiUterances(apiId, pluginDataConfig); err != nil {
+		logger.Errorf("[+] failed to save plugin data config to redis store: %s", err)
+		return pluginDataConfig, err
+	}
+
+	return pluginDataConfig, nil
+}
+
+func getPluginFromRequest(r *http.Request) (*PluginDataConfig, error) {
+	apiId, err := getApiId(r)
+	if err != nil {
+		logger.Errorf("[+] getPluginFromRequest cannot find api id: %s", err)
+		return nil, err
+	}
+
+	// Note: we really need to just to be able to clear the cache on API def
+	// reloads to fix everything complicated.
+	pluginConfigLock.RLock()
+	pluginDataConfig, present := pluginConfig[apiId]
+	pluginConfigLock.RUnlock()
+	if present {
+		logger.Debugf("[+] Config data already cached for api id: %s", apiId)
+		return pluginDataConfig, nil
+	}
+
+	apiDef := getOASDefinition(r)
+	// TOOD: fallback on classic...
+	if apiDef == nil {
+		err := fmt.Errorf("API definition is nil")
+		logger.Errorf("[+] getPluginFromRequest: %s", err)
+		return nil, err
+	}
+
+	pluginDataConfig, err = initPluginFromRequest(apiId, apiDef)
+	if err != nil {
+		logger.Errorf("[+] Unable to parse configuration data: %s", err)
+		return nil, err
+	}
+
+	pluginConfigLock.Lock()
+	pluginConfig[apiId] = pluginDataConfig
+	pluginConfigLock.Unlock()
+
+	logConfig()
+
+	logger.Debugf("[+] Finished getPluginFromRequest for api id: %s", apiId)
+	return pluginDataConfig, nil
+}
+
+// addSpecSelectOperations merges the examples of the OpenAPI spec with the
+// selectOperations of the plugin configuration.
+func addSpecSelectOperations(pluginDataConfig *PluginDataConfig, apiDef *oas.OAS) {
+	// Iterate through all paths and operations in the API definition
+	enabledOperations := map[string]bool{}
+	generatedExamples := getGeneratedExamples(pluginDataConfig.APIID)
+	hasGeneratedExamples := false
+	for pathName, path := range apiDef.Paths {
+		for method, operation := range path.Operations() {
+			if !isOperationEnabled(pluginDataConfig, pathName, method, operation) {
+				continue
+			}
+			enabledOperations[operation.OperationID] = true
+			// Check if this operation has AI input examples defined, either in
+			// x-nl-input-examples or in the utterances of x-tyk-natural-language
+			aiExamples := getInputExamples(operation)
+			if generated, exists := generatedExamples[operation.OperationID]; exists && len(aiExamples) == 0 && pluginDataConfig.SelectOperations[operation.OperationID] == nil {
+				// Examples generated by the nl-generate command, unless the
+				// operation changed since
+				route := &routers.Route{Spec: &apiDef.T, Path: pathName, PathItem: path, Method: method, Operation: operation}
+				if generated.Fingerprint == getOperationFingerprint(route) {
+					aiExamples = generated.Examples
+					hasGeneratedExamples = true
+				} else {
+					logger.Warningf("[+] The generated examples of operation %s are stale; ignoring, run nl-generate again", operation.OperationID)
+				}
+			}
+			negativeExamples := getNegativeExamples(operation)
+			if len(aiExamples) == 0 && len(negativeExamples) == 0 {
+				continue
+			}
+
+			operationId := operation.OperationID
+			aiExtentionConfig := pluginDataConfig.SelectOperations[operationId]
+			if aiExtentionConfig == nil {
+				aiExtentionConfig = &AIExtensionConfig{}
+			}
+
+			// Add each example to the operation's config. The examples of the
+			// plugin configuration can replace the ones of the spec, the
+			// negative examples are always kept
+			if !aiExtentionConfig.ReplaceExamples {
+				aiExtentionConfig.InputExamples = append(aiExtentionConfig.InputExamples, aiExamples...)
+			}
+			aiExtentionConfig.NegativeExamples = append(aiExtentionConfig.NegativeExamples, negativeExamples...)
+
+			pluginDataConfig.SelectOperations[operationId] = aiExtentionConfig
+		}
+	}
+
+	// The operations of the plugin configuration must exist and be enabled
+	for operationId := range pluginDataConfig.SelectOperations {
+		if !enabledOperations[operationId] {
+			logger.Warningf("[+] Unknown or disabled operation %s in selectOperations; ignoring", operationId)
+			delete(pluginDataConfig.SelectOperations, operationId)
+		}
+	}
+
+	// If we have no operation with x-nl-input-examples then we rely only on the
+	// descriptions, the negative examples are kept. The generated examples may
+	// only cover some operations, the others rely on their descriptions
+	if !hasInputExamples(pluginDataConfig.SelectOperations) || hasGeneratedExamples {
+		for pathName, path := range apiDef.Paths {
+			for method, operation := range path.Operations() {
+				if operation.OperationID == "" || !isOperationEnabled(pluginDataConfig, pathName, method, operation) {
+					continue
+				}
+				summary, description := getOperationDescriptions(operation)
+				aiExtentionConfig := pluginDataConfig.SelectOperations[operation.OperationID]
+				if aiExtentionConfig == nil {
+					aiExtentionConfig = &AIExtensionConfig{}
+				} else if len(aiExtentionConfig.InputExamples) > 0 {
+					continue
+				}
+				aiExtentionConfig.InputExamples = append(aiExtentionConfig.InputExamples, description)
+				if summary != "" {
+					aiExtentionConfig.InputExamples = append(aiExtentionConfig.InputExamples, summary)
+				}
+				pluginDataConfig.SelectOperations[operation.OperationID] = aiExtentionConfig
+			}
+		}
+	}
+}
+
+func hasInputExamples(selectOperations map[string]*AIExtensionConfig) bool {
+	for _, aiExtension := range selectOperations {
+		if len(aiExtension.InputExamples) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// newOperationsIndex embeds the examples of the operations.
+func newOperationsIndex(ctx context.Context, modelEmbedder Embedder, selectOperations map[string]*AIExtensionConfig) *search.Index[string] {
+	apiSpecIndex := search.NewIndex[string]()
+	for apiOperation, aiExtension := range selectOperations {
+		for _, example := range aiExtension.InputExamples {
+			if len(example) > MAX_UTERANCE_LENGTH {
+				logger.Warningf("[+] example too long: %s", example)
+				continue
+			}
+			embedding, err := embedTextWithCache(ctx, modelEmbedder, example)
+			if err != nil {
+				logger.Warningf("[+] embedding model %s failed for text \"%s\": %s", modelEmbedder.Name(), example, err)
+			} else {
+				apiSpecIndex.Add(embedding, apiOperation) // map embedding to operation name
+			}
+		}
+	}
+	return apiSpecIndex
+}
+
+func initSelectOperations(apiId string, pluginDataConfig *PluginDataConfig, apiDef *oas.OAS) error {
+	modelEmbedder, present := getEmbedder(pluginDataConfig)
+	if !present {
+		return fmt.Errorf("no embedding model found for api id: %s", apiId)
+	}
+
+	apiSpecIndicesLock.RLock()
+	_, present = apiSpecIndices[apiId]
+	apiSpecIndicesLock.RUnlock()
+	if present {
+		logger.Debugf("[+] replacing index found for operations for api id: %s", apiId)
+	}
+
+	// The indices outlive the request loading the API, if any
+	ctx := context.Background()
+	apiSpecIndex := newOperationsIndex(ctx, modelEmbedder, pluginDataConfig.SelectOperations)
+	addLearnedExamples(ctx, apiSpecIndex, modelEmbedder, pluginDataConfig, getLearnedExamples(apiId))
+
+	apiNegativeIndex := newNegativeExamplesIndex(ctx, modelEmbedder, pluginDataConfig.SelectOperations)
+
+	// Always recreate. This is obviously a race condition on the loading of specs, but that should
+	// be handled at a higher level than this plugin.
+	apiSpecIndicesLock.Lock()
+	apiSpecIndices[apiId] = apiSpecIndex
+	apiLexicalIndices[apiId] = newOperationsLexicalIndex(apiDef, pluginDataConfig.SelectOperations)
+	apiNegativeIndices[apiId] = apiNegativeIndex
+	apiSpecIndicesLock.Unlock()
+
+	getEmbeddingCache().Prune()
+	return nil
+}
+
+func logConfig() {
+	pluginConfigLock.RLock()
+	defer pluginConfigLock.RUnlock()
+
+	for apiId, pluginDataConfig := range pluginConfig {
+		logger.Infof("[+] Config %s: LLM provider: %s", apiId, pluginDataConfig.LlmProvider)
+		logger.Infof("[+] Config %s: Azure OpenAI API Key: %s", apiId, "**REDACTED**")
+		logger.Infof("[+] Config %s: Azure OpenAI Provider: %s", apiId, pluginDataConfig.AzureConfig.Provider)
+		logger.Infof("[+] Config %s: Azure OpenAI Endpoint: %s", apiId, pluginDataConfig.AzureConfig.OpenAIEndpoint)
+		logger.Infof("[+] Config %s: Azure OpenAI Model Deployment ID: %s", apiId, pluginDataConfig.AzureConfig.ModelDeployment)
+		if len(pluginDataConfig.SelectOperations) > 0 {
+			logger.Infof("[+] Config %s: Select operations: %d", apiId, len(pluginDataConfig.SelectOperations))
+			if pluginDataConfig.EmbeddingConfig.Provider == EMBEDDING_PROVIDER_LOCAL {
+				logger.Infof("[+] Config %s: Select embedding model: %s", apiId, filepath.Join(pluginDataConfig.SelectModelsPath, pluginDataConfig.SelectModelEmbedding))
+			} else {
+				logger.Infof("[+] Config %s: Select embedding model: %s (%s)", apiId, pluginDataConfig.EmbeddingConfig.Model, pluginDataConfig.EmbeddingConfig.Endpoint)
+			}
+		}
+	}
+}
+
+func deletePluginConfig(apiId string) {
+	pluginConfigLock.Lock()
+	defer pluginConfigLock.Unlock()
+
+	logger.Debugf("[+] Deleting api id: %s", apiId)
+	if _, present := pluginConfig[apiId]; present {
+		err := deleteApiUterances(apiId, pluginConfig[apiId])
+		if err != nil {
+			logger.Errorf("[+] Error while deleting utterances for api id %s: %s", apiId, err)
+			return
+		}
+		delete(pluginConfig, apiId)
+	}
+
+	apiSpecIndicesLock.Lock()
+	delete(apiSpecIndices, apiId)
+	delete(apiLexicalIndices, apiId)
+	delete(apiNegativeIndices, apiId)
+	apiSpecIndicesLock.Unlock()
+
+	deleteAPIRouter(apiId)
+}
+
+func updatePluginConfig(apiId string, r *http.Request) error {
+	logger.Debugf("[+] Updating api id: %s", apiId)
+	deleteAPIRouter(apiId)
+	apiDef := getOASDefinition(r)
+	// TOOD: fallback on classic...
+	if apiDef == nil {
+		err := fmt.Errorf("API definition is nil")
+		logger.Errorf("[+] updatePluginConfig: %s", err)
+		return err
+	}
+
+	pluginDataConfig, err := initPluginFromRequest(apiId, apiDef)
+	if err != nil {
+		logger.Errorf("[+] Unable to parse configuration data: %s", err)
+		return err
+	}
+
+	// Not held while loading the API, the requests of the other APIs go on
+	pluginConfigLock.Lock()
+	pluginConfig[apiId] = pluginDataConfig
+	pluginConfigLock.Unlock()
+
+	return nil
+}
@@ -0,0 +1,592 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+	"github.com/TykTechnologies/tyk/apidef/oas"
+	"github.com/TykTechnologies/tyk/ctx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigParseConfigData(t *testing.T) {
+	tests := []struct {
+		description    string
+		configData     any
+		expectedConfig PluginDataConfig
+	}{
+		{
+			"Some default values",
+			map[string]any{
+				"azureConfig": map[string]string{
+					"openAIEndpoint": "https://tests-agents.openai.azure.com",
+					"openAIKey":      "xxx",
+				},
+			},
+			PluginDataConfig{
+				AzureConfig: AzureConfig{
+					OpenAIEndpoint:  "https://tests-agents.openai.azure.com",
+					OpenAIKey:       "xxx",
+					ModelDeployment: "gpt-4o-mini",
+					Provider:        "azure",
+					Headers:         map[string]string{},
+				},
+				LlmProvider:               DEFAULT_LLM_PROVIDER,
+				SelectOperations:          map[string]*AIExtensionConfig{},
+				SelectModelEmbedding:      DEFAULT_MODEL_EMBEDDINGS_MODEL,
+				SelectModelsPath:          "models",
+				EmbeddingConfig:           EmbeddingConfig{Provider: EMBEDDING_PROVIDER_LOCAL, Endpoint: DEFAULT_OPENAI_ENDPOINT, Model: DEFAULT_OPENAI_EMBEDDING_MODEL, Headers: map[string]string{}},
+				APIID:                     "httpbin",
+				RelevanceThreshold:        DEFAULT_RELEVANCE_THRESHOLD,
+				SelectTopK:                DEFAULT_SELECT_TOP_K,
+				RerankConfidenceThreshold: DEFAULT_RERANK_CONFIDENCE_THRESHOLD,
+				LexicalWeight:             DEFAULT_LEXICAL_WEIGHT,
+				NegativeWeight:            DEFAULT_NEGATIVE_WEIGHT,
+				ValidateRequests:          DEFAULT_VALIDATE_REQUESTS,
+				MaxRepairAttempts:         DEFAULT_MAX_REPAIR_ATTEMPTS,
+				AskForClarification:       DEFAULT_ASK_FOR_CLARIFICATION,
+				RequireConfirmation:       ConfirmationConfig{Methods: []string{}, OperationIDs: []string{}, TTL: DEFAULT_CONFIRMATION_TTL},
+				MaxPlanSteps:              DEFAULT_MAX_PLAN_STEPS,
+				MaxOperationPromptTokens:  DEFAULT_MAX_OPERATION_PROMPT_TOKENS,
+				PlannerGatewayURL:         DEFAULT_PLANNER_GATEWAY_URL,
+				MaxRequestLength:          DEFAULT_MAX_REQUEST_SIZE,
+			},
+		},
+		{
+			"All default values",
+			map[string]any{},
+			PluginDataConfig{
+				AzureConfig: AzureConfig{
+					OpenAIEndpoint:  "https://api.openai.com/v1",
+					OpenAIKey:       "",
+					ModelDeployment: "gpt-4o-mini",
+					Provider:        "openai",
+					Headers:         map[string]string{},
+				},
+				LlmProvider:               DEFAULT_LLM_PROVIDER,
+				SelectOperations:          map[string]*AIExtensionConfig{},
+				SelectModelEmbedding:      DEFAULT_MODEL_EMBEDDINGS_MODEL,
+				SelectModelsPath:          "models",
+				EmbeddingConfig:           EmbeddingConfig{Provider: EMBEDDING_PROVIDER_LOCAL, Endpoint: DEFAULT_OPENAI_ENDPOINT, Model: DEFAULT_OPENAI_EMBEDDING_MODEL, Headers: map[string]string{}},
+				APIID:                     "httpbin",
+				RelevanceThreshold:        DEFAULT_RELEVANCE_THRESHOLD,
+				SelectTopK:                DEFAULT_SELECT_TOP_K,
+				RerankConfidenceThreshold: DEFAULT_RERANK_CONFIDENCE_THRESHOLD,
+				LexicalWeight:             DEFAULT_LEXICAL_WEIGHT,
+				NegativeWeight:            DEFAULT_NEGATIVE_WEIGHT,
+				ValidateRequests:          DEFAULT_VALIDATE_REQUESTS,
+				MaxRepairAttempts:         DEFAULT_MAX_REPAIR_ATTEMPTS,
+				AskForClarification:       DEFAULT_ASK_FOR_CLARIFICATION,
+				RequireConfirmation:       ConfirmationConfig{Methods: []string{}, OperationIDs: []string{}, TTL: DEFAULT_CONFIRMATION_TTL},
+				MaxPlanSteps:              DEFAULT_MAX_PLAN_STEPS,
+				MaxOperationPromptTokens:  DEFAULT_MAX_OPERATION_PROMPT_TOKENS,
+				PlannerGatewayURL:         DEFAULT_PLANNER_GATEWAY_URL,
+				MaxRequestLength:          DEFAULT_MAX_REQUEST_SIZE,
+			},
+		},
+		{
+			"Select the LLM provider",
+			map[string]any{
+				"llmProvider": "fake",
+			},
+			PluginDataConfig{
+				AzureConfig: AzureConfig{
+					OpenAIEndpoint:  "https://api.openai.com/v1",
+					OpenAIKey:       "",
+					ModelDeployment: "gpt-4o-mini",
+					Provider:        "openai",
+					Headers:         map[string]string{},
+				},
+				LlmProvider:               "fake",
+				SelectOperations:          map[string]*AIExtensionConfig{},
+				SelectModelEmbedding:      DEFAULT_MODEL_EMBEDDINGS_MODEL,
+				SelectModelsPath:          "models",
+				EmbeddingConfig:           EmbeddingConfig{Provider: EMBEDDING_PROVIDER_LOCAL, Endpoint: DEFAULT_OPENAI_ENDPOINT, Model: DEFAULT_OPENAI_EMBEDDING_MODEL, Headers: map[string]string{}},
+				APIID:                     "httpbin",
+				RelevanceThreshold:        DEFAULT_RELEVANCE_THRESHOLD,
+				SelectTopK:                DEFAULT_SELECT_TOP_K,
+				RerankConfidenceThreshold: DEFAULT_RERANK_CONFIDENCE_THRESHOLD,
+				LexicalWeight:             DEFAULT_LEXICAL_WEIGHT,
+				NegativeWeight:            DEFAULT_NEGATIVE_WEIGHT,
+				ValidateRequests:          DEFAULT_VALIDATE_REQUESTS,
+				MaxRepairAttempts:         DEFAULT_MAX_REPAIR_ATTEMPTS,
+				AskForClarification:       DEFAULT_ASK_FOR_CLARIFICATION,
+				RequireConfirmation:       ConfirmationConfig{Methods: []string{}, OperationIDs: []string{}, TTL: DEFAULT_CONFIRMATION_TTL},
+				MaxPlanSteps:              DEFAULT_MAX_PLAN_STEPS,
+				MaxOperationPromptTokens:  DEFAULT_MAX_OPERATION_PROMPT_TOKENS,
+				PlannerGatewayURL:         DEFAULT_PLANNER_GATEWAY_URL,
+				MaxRequestLength:          DEFAULT_MAX_REQUEST_SIZE,
+			},
+		},
+		{
+			"OpenAI compatible local server",
+			map[string]any{
+				"azureConfig": map[string]any{
+					"provider":        "openai-compatible",
+					"openAIEndpoint":  "http://localhost:11434/v1",
+					"modelDeployment": "llama3.1:8b",
+					"noAuth":          true,
+					"headers": map[string]string{
+						"X-Tenant": "agntcy",
+					},
+				},
+			},
+			PluginDataConfig{
+				AzureConfig: AzureConfig{
+					OpenAIEndpoint:  "http://localhost:11434/v1",
+					OpenAIKey:       "",
+					ModelDeployment: "llama3.1:8b",
+					Provider:        "openai-compatible",
+					NoAuth:          true,
+					Headers:         map[string]string{"X-Tenant": "agntcy"},
+				},
+				LlmProvider:               DEFAULT_LLM_PROVIDER,
+				SelectOperations:          map[string]*AIExtensionConfig{},
+				SelectModelEmbedding:      DEFAULT_MODEL_EMBEDDINGS_MODEL,
+				SelectModelsPath:          "models",
+				EmbeddingConfig:           EmbeddingConfig{Provider: EMBEDDING_PROVIDER_LOCAL, Endpoint: DEFAULT_OPENAI_ENDPOINT, Model: DEFAULT_OPENAI_EMBEDDING_MODEL, Headers: map[string]string{}},
+				APIID:                     "httpbin",
+				RelevanceThreshold:        DEFAULT_RELEVANCE_THRESHOLD,
+				SelectTopK:                DEFAULT_SELECT_TOP_K,
+				RerankConfidenceThreshold: DEFAULT_RERANK_CONFIDENCE_THRESHOLD,
+				LexicalWeight:             DEFAULT_LEXICAL_WEIGHT,
+				NegativeWeight:            DEFAULT_NEGATIVE_WEIGHT,
+				ValidateRequests:          DEFAULT_VALIDATE_REQUESTS,
+				MaxRepairAttempts:         DEFAULT_MAX_REPAIR_ATTEMPTS,
+				AskForClarification:       DEFAULT_ASK_FOR_CLARIFICATION,
+				RequireConfirmation:       ConfirmationConfig{Methods: []string{}, OperationIDs: []string{}, TTL: DEFAULT_CONFIRMATION_TTL},
+				MaxPlanSteps:              DEFAULT_MAX_PLAN_STEPS,
+				MaxOperationPromptTokens:  DEFAULT_MAX_OPERATION_PROMPT_TOKENS,
+				PlannerGatewayURL:         DEFAULT_PLANNER_GATEWAY_URL,
+				MaxRequestLength:          DEFAULT_MAX_REQUEST_SIZE,
+			},
+		},
+		{
+			"Request validation, clarification and confirmation",
+			map[string]any{
+				"validateRequests":    true,
+				"maxRepairAttempts":   0,
+				"askForClarification": true,
+				"requireConfirmation": map[string]any{
+					"methods":      []string{"delete", "PUT"},
+					"operationIds": []string{"createIssue"},
+					"ttl":          60,
+				},
+			},
+			PluginDataConfig{
+				AzureConfig: AzureConfig{
+					OpenAIEndpoint:  DEFAULT_OPENAI_ENDPOINT,
+					OpenAIKey:       "",
+					ModelDeployment: DEFAULT_OPENAI_MODEL,
+					Provider:        "openai",
+					Headers:         map[string]string{},
+				},
+				LlmProvider:               DEFAULT_LLM_PROVIDER,
+				SelectOperations:          map[string]*AIExtensionConfig{},
+				SelectModelEmbedding:      DEFAULT_MODEL_EMBEDDINGS_MODEL,
+				SelectModelsPath:          "models",
+				EmbeddingConfig:           EmbeddingConfig{Provider: EMBEDDING_PROVIDER_LOCAL, Endpoint: DEFAULT_OPENAI_ENDPOINT, Model: DEFAULT_OPENAI_EMBEDDING_MODEL, Headers: map[string]string{}},
+				APIID:                     "httpbin",
+				RelevanceThreshold:        DEFAULT_RELEVANCE_THRESHOLD,
+				SelectTopK:                DEFAULT_SELECT_TOP_K,
+				RerankConfidenceThreshold: DEFAULT_RERANK_CONFIDENCE_THRESHOLD,
+				LexicalWeight:             DEFAULT_LEXICAL_WEIGHT,
+				NegativeWeight:            DEFAULT_NEGATIVE_WEIGHT,
+				ValidateRequests:          true,
+				MaxRepairAttempts:         0,
+				AskForClarification:       true,
+				RequireConfirmation:       ConfirmationConfig{Methods: []string{"DELETE", "PUT"}, OperationIDs: []string{"createIssue"}, TTL: 60},
+				MaxPlanSteps:              DEFAULT_MAX_PLAN_STEPS,
+				MaxOperationPromptTokens:  DEFAULT_MAX_OPERATION_PROMPT_TOKENS,
+				PlannerGatewayURL:         DEFAULT_PLANNER_GATEWAY_URL,
+				MaxRequestLength:          DEFAULT_MAX_REQUEST_SIZE,
+			},
+		},
+		{
+			"Planner",
+			map[string]any{
+				"maxPlanSteps":      3,
+				"plannerGatewayURL": "http://tyk-gateway:8080",
+			},
+			PluginDataConfig{
+				AzureConfig: AzureConfig{
+					OpenAIEndpoint:  DEFAULT_OPENAI_ENDPOINT,
+					OpenAIKey:       "",
+					ModelDeployment: DEFAULT_OPENAI_MODEL,
+					Provider:        "openai",
+					Headers:         map[string]string{},
+				},
+				LlmProvider:               DEFAULT_LLM_PROVIDER,
+				SelectOperations:          map[string]*AIExtensionConfig{},
+				SelectModelEmbedding:      DEFAULT_MODEL_EMBEDDINGS_MODEL,
+				SelectModelsPath:          "models",
+				EmbeddingConfig:           EmbeddingConfig{Provider: EMBEDDING_PROVIDER_LOCAL, Endpoint: DEFAULT_OPENAI_ENDPOINT, Model: DEFAULT_OPENAI_EMBEDDING_MODEL, Headers: map[string]string{}},
+				APIID:                     "httpbin",
+				RelevanceThreshold:        DEFAULT_RELEVANCE_THRESHOLD,
+				SelectTopK:                DEFAULT_SELECT_TOP_K,
+				RerankConfidenceThreshold: DEFAULT_RERANK_CONFIDENCE_THRESHOLD,
+				LexicalWeight:             DEFAULT_LEXICAL_WEIGHT,
+				NegativeWeight:            DEFAULT_NEGATIVE_WEIGHT,
+				ValidateRequests:          DEFAULT_VALIDATE_REQUESTS,
+				MaxRepairAttempts:         DEFAULT_MAX_REPAIR_ATTEMPTS,
+				AskForClarification:       DEFAULT_ASK_FOR_CLARIFICATION,
+				RequireConfirmation:       ConfirmationConfig{Methods: []string{}, OperationIDs: []string{}, TTL: DEFAULT_CONFIRMATION_TTL},
+				MaxPlanSteps:              3,
+				MaxOperationPromptTokens:  DEFAULT_MAX_OPERATION_PROMPT_TOKENS,
+				PlannerGatewayURL:         "http://tyk-gateway:8080",
+				MaxRequestLength:          DEFAULT_MAX_REQUEST_SIZE,
+			},
+		},
+		{
+			"Operation prompt budget",
+			map[string]any{
+				"maxOperationPromptTokens": 1500,
+			},
+			PluginDataConfig{
+				AzureConfig: AzureConfig{
+					OpenAIEndpoint:  DEFAULT_OPENAI_ENDPOINT,
+					OpenAIKey:       "",
+					ModelDeployment: DEFAULT_OPENAI_MODEL,
+					Provider:        "openai",
+					Headers:         map[string]string{},
+				},
+				LlmProvider:               DEFAULT_LLM_PROVIDER,
+				SelectOperations:          map[string]*AIExtensionConfig{},
+				SelectModelEmbedding:      DEFAULT_MODEL_EMBEDDINGS_MODEL,
+				SelectModelsPath:          "models",
+				EmbeddingConfig:           EmbeddingConfig{Provider: EMBEDDING_PROVIDER_LOCAL, Endpoint: DEFAULT_OPENAI_ENDPOINT, Model: DEFAULT_OPENAI_EMBEDDING_MODEL, Headers: map[string]string{}},
+				APIID:                     "httpbin",
+				RelevanceThreshold:        DEFAULT_RELEVANCE_THRESHOLD,
+				SelectTopK:                DEFAULT_SELECT_TOP_K,
+				RerankConfidenceThreshold: DEFAULT_RERANK_CONFIDENCE_THRESHOLD,
+				LexicalWeight:             DEFAULT_LEXICAL_WEIGHT,
+				NegativeWeight:            DEFAULT_NEGATIVE_WEIGHT,
+				ValidateRequests:          DEFAULT_VALIDATE_REQUESTS,
+				MaxRepairAttempts:         DEFAULT_MAX_REPAIR_ATTEMPTS,
+				AskForClarification:       DEFAULT_ASK_FOR_CLARIFICATION,
+				RequireConfirmation:       ConfirmationConfig{Methods: []string{}, OperationIDs: []string{}, TTL: DEFAULT_CONFIRMATION_TTL},
+				MaxPlanSteps:              DEFAULT_MAX_PLAN_STEPS,
+				MaxOperationPromptTokens:  1500,
+				PlannerGatewayURL:         DEFAULT_PLANNER_GATEWAY_URL,
+				MaxRequestLength:          DEFAULT_MAX_REQUEST_SIZE,
+			},
+		},
+		{
+			"Select operations and embedding model",
+			map[string]any{
+				"selectOperations": map[string]any{
+					"getIssue": map[string]any{
+						"x-nl-input-examples":    []any{"show me the ticket"},
+						"x-nl-negative-examples": []any{"update the ticket"},
+					},
+					"searchIssues": map[string]any{
+						"x-nl-input-examples": []any{"find the tickets", "look for the bugs"},
+						"replaceExamples":     true,
+					},
+				},
+				"selectModelEmbedding": "all-MiniLM-L6-v2.Q8_0.gguf",
+				"selectModelsPath":     "/opt/models",
+			},
+			PluginDataConfig{
+				AzureConfig: AzureConfig{
+					OpenAIEndpoint:  DEFAULT_OPENAI_ENDPOINT,
+					OpenAIKey:       "",
+					ModelDeployment: DEFAULT_OPENAI_MODEL,
+					Provider:        "openai",
+					Headers:         map[string]string{},
+				},
+				LlmProvider: DEFAULT_LLM_PROVIDER,
+				SelectOperations: map[string]*AIExtensionConfig{
+					"getIssue":     {InputExamples: []string{"show me the ticket"}, NegativeExamples: []string{"update the ticket"}},
+					"searchIssues": {InputExamples: []string{"find the tickets", "look for the bugs"}, ReplaceExamples: true},
+				},
+				SelectModelEmbedding:      "all-MiniLM-L6-v2.Q8_0.gguf",
+				SelectModelsPath:          "/opt/models",
+				EmbeddingConfig:           EmbeddingConfig{Provider: EMBEDDING_PROVIDER_LOCAL, Endpoint: DEFAULT_OPENAI_ENDPOINT, Model: DEFAULT_OPENAI_EMBEDDING_MODEL, Headers: map[string]string{}},
+				APIID:                     "httpbin",
+				RelevanceThreshold:        DEFAULT_RELEVANCE_THRESHOLD,
+				SelectTopK:                DEFAULT_SELECT_TOP_K,
+				RerankConfidenceThreshold: DEFAULT_RERANK_CONFIDENCE_THRESHOLD,
+				LexicalWeight:             DEFAULT_LEXICAL_WEIGHT,
+				NegativeWeight:            DEFAULT_NEGATIVE_WEIGHT,
+				ValidateRequests:          DEFAULT_VALIDATE_REQUESTS,
+				MaxRepairAttempts:         DEFAULT_MAX_REPAIR_ATTEMPTS,
+				AskForClarification:       DEFAULT_ASK_FOR_CLARIFICATION,
+				RequireConfirmation:       ConfirmationConfig{Methods: []string{}, OperationIDs: []string{}, TTL: DEFAULT_CONFIRMATION_TTL},
+				MaxPlanSteps:              DEFAULT_MAX_PLAN_STEPS,
+				MaxOperationPromptTokens:  DEFAULT_MAX_OPERATION_PROMPT_TOKENS,
+				PlannerGatewayURL:         DEFAULT_PLANNER_GATEWAY_URL,
+				MaxRequestLength:          DEFAULT_MAX_REQUEST_SIZE,
+			},
+		},
+		{
+			"Operation filters",
+			map[string]any{
+				"includeOperations": map[string]any{
+					"tags":  []any{"Issues", "Projects"},
+					"paths": []any{"/rest/api/3/*"},
+				},
+				"excludeOperations": map[string]any{
+					"operationIds": []any{"deleteIssue"},
+					"methods":      []any{"delete", "PATCH"},
+				},
+			},
+			PluginDataConfig{
+				AzureConfig: AzureConfig{
+					OpenAIEndpoint:  DEFAULT_OPENAI_ENDPOINT,
+					OpenAIKey:       "",
+					ModelDeployment: DEFAULT_OPENAI_MODEL,
+					Provider:        "openai",
+					Headers:         map[string]string{},
+				},
+				LlmProvider:               DEFAULT_LLM_PROVIDER,
+				SelectOperations:          map[string]*AIExtensionConfig{},
+				SelectModelEmbedding:      DEFAULT_MODEL_EMBEDDINGS_MODEL,
+				SelectModelsPath:          "models",
+				EmbeddingConfig:           EmbeddingConfig{Provider: EMBEDDING_PROVIDER_LOCAL, Endpoint: DEFAULT_OPENAI_ENDPOINT, Model: DEFAULT_OPENAI_EMBEDDING_MODEL, Headers: map[string]string{}},
+				APIID:                     "httpbin",
+				RelevanceThreshold:        DEFAULT_RELEVANCE_THRESHOLD,
+				SelectTopK:                DEFAULT_SELECT_TOP_K,
+				RerankConfidenceThreshold: DEFAULT_RERANK_CONFIDENCE_THRESHOLD,
+				LexicalWeight:             DEFAULT_LEXICAL_WEIGHT,
+				NegativeWeight:            DEFAULT_NEGATIVE_WEIGHT,
+				ValidateRequests:          DEFAULT_VALIDATE_REQUESTS,
+				MaxRepairAttempts:         DEFAULT_MAX_REPAIR_ATTEMPTS,
+				AskForClarification:       DEFAULT_ASK_FOR_CLARIFICATION,
+				RequireConfirmation:       ConfirmationConfig{Methods: []string{}, OperationIDs: []string{}, TTL: DEFAULT_CONFIRMATION_TTL},
+				MaxPlanSteps:              DEFAULT_MAX_PLAN_STEPS,
+				MaxOperationPromptTokens:  DEFAULT_MAX_OPERATION_PROMPT_TOKENS,
+				PlannerGatewayURL:         DEFAULT_PLANNER_GATEWAY_URL,
+				IncludeOperations:         OperationFilter{Tags: []string{"Issues", "Projects"}, Paths: []string{"/rest/api/3/*"}, pathRegexps: []*regexp.Regexp{regexp.MustCompile(`^/rest/api/3/.*$`)}},
+				ExcludeOperations:         OperationFilter{OperationIDs: []string{"deleteIssue"}, Methods: []string{"DELETE", "PATCH"}},
+				MaxRequestLength:          DEFAULT_MAX_REQUEST_SIZE,
+			},
+		},
+		{
+			"Top-k selection, hybrid search and negative examples",
+			map[string]any{
+				"relevanceThreshold":        0.8,
+				"selectTopK":                5,
+				"rerankConfidenceThreshold": 0.7,
+				"lexicalWeight":             0.3,
+				"negativeWeight":            0.5,
+			},
+			PluginDataConfig{
+				AzureConfig: AzureConfig{
+					OpenAIEndpoint:  DEFAULT_OPENAI_ENDPOINT,
+					OpenAIKey:       "",
+					ModelDeployment: DEFAULT_OPENAI_MODEL,
+					Provider:        "openai",
+					Headers:         map[string]string{},
+				},
+				LlmProvider:               DEFAULT_LLM_PROVIDER,
+				SelectOperations:          map[string]*AIExtensionConfig{},
+				SelectModelEmbedding:      DEFAULT_MODEL_EMBEDDINGS_MODEL,
+				SelectModelsPath:          "models",
+				EmbeddingConfig:           EmbeddingConfig{Provider: EMBEDDING_PROVIDER_LOCAL, Endpoint: DEFAULT_OPENAI_ENDPOINT, Model: DEFAULT_OPENAI_EMBEDDING_MODEL, Headers: map[string]string{}},
+				APIID:                     "httpbin",
+				RelevanceThreshold:        0.8,
+				SelectTopK:                5,
+				RerankConfidenceThreshold: 0.7,
+				LexicalWeight:             0.3,
+				NegativeWeight:            0.5,
+				ValidateRequests:          DEFAULT_VALIDATE_REQUESTS,
+				MaxRepairAttempts:         DEFAULT_MAX_REPAIR_ATTEMPTS,
+				AskForClarification:       DEFAULT_ASK_FOR_CLARIFICATION,
+				RequireConfirmation:       ConfirmationConfig{Methods: []string{}, OperationIDs: []string{}, TTL: DEFAULT_CONFIRMATION_TTL},
+				MaxPlanSteps:              DEFAULT_MAX_PLAN_STEPS,
+				MaxOperationPromptTokens:  DEFAULT_MAX_OPERATION_PROMPT_TOKENS,
+				PlannerGatewayURL:         DEFAULT_PLANNER_GATEWAY_URL,
+				MaxRequestLength:          DEFAULT_MAX_REQUEST_SIZE,
+			},
+		},
+		{
+			"Remote embedding server",
+			map[string]any{
+				"embeddingConfig": map[string]any{
+					"provider": "openai-compatible",
+					"endpoint": "http://localhost:11434/v1",
+					"model":    "nomic-embed-text",
+					"noAuth":   true,
+					"headers": map[string]string{
+						"X-Tenant": "agntcy",
+					},
+				},
+			},
+			PluginDataConfig{
+				AzureConfig: AzureConfig{
+					OpenAIEndpoint:  DEFAULT_OPENAI_ENDPOINT,
+					OpenAIKey:       "",
+					ModelDeployment: DEFAULT_OPENAI_MODEL,
+					Provider:        "openai",
+					Headers:         map[string]string{},
+				},
+				LlmProvider:          DEFAULT_LLM_PROVIDER,
+				SelectOperations:     map[string]*AIExtensionConfig{},
+				SelectModelEmbedding: DEFAULT_MODEL_EMBEDDINGS_MODEL,
+				SelectModelsPath:     "models",
+				EmbeddingConfig: EmbeddingConfig{
+					Provider: EMBEDDING_PROVIDER_OPENAI_COMPATIBLE,
+					Endpoint: "http://localhost:11434/v1",
+					Model:    "nomic-embed-text",
+					NoAuth:   true,
+					Headers:  map[string]string{"X-Tenant": "agntcy"},
+				},
+				APIID:                     "httpbin",
+				RelevanceThreshold:        DEFAULT_RELEVANCE_THRESHOLD,
+				SelectTopK:                DEFAULT_SELECT_TOP_K,
+				RerankConfidenceThreshold: DEFAULT_RERANK_CONFIDENCE_THRESHOLD,
+				LexicalWeight:             DEFAULT_LEXICAL_WEIGHT,
+				NegativeWeight:            DEFAULT_NEGATIVE_WEIGHT,
+				ValidateRequests:          DEFAULT_VALIDATE_REQUESTS,
+				MaxRepairAttempts:         DEFAULT_MAX_REPAIR_ATTEMPTS,
+				AskForClarification:       DEFAULT_ASK_FOR_CLARIFICATION,
+				RequireConfirmation:       ConfirmationConfig{Methods: []string{}, OperationIDs: []string{}, TTL: DEFAULT_CONFIRMATION_TTL},
+				MaxPlanSteps:              DEFAULT_MAX_PLAN_STEPS,
+				MaxOperationPromptTokens:  DEFAULT_MAX_OPERATION_PROMPT_TOKENS,
+				PlannerGatewayURL:         DEFAULT_PLANNER_GATEWAY_URL,
+				MaxRequestLength:          DEFAULT_MAX_REQUEST_SIZE,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			// convert config to json
+			configJSON, err := json.Marshal(tt.configData)
+			assert.Nil(t, err)
+
+			// convert config json to map
+			configMap := make(map[string]any)
+			err = json.Unmarshal(configJSON, &configMap)
+			assert.Nil(t, err)
+
+			dataconfig, err := parseConfigData("httpbin", configMap)
+			assert.Nil(t, err)
+			assert.NotNil(t, dataconfig)
+
+			expectedDataConfig, err := json.Marshal(tt.expectedConfig)
+			assert.Nil(t, err)
+
+			jsonData, err := json.Marshal(dataconfig)
+			assert.Nil(t, err)
+			assert.Equal(t, string(expectedDataConfig), string(jsonData))
+		})
+	}
+}
+
+func TestAddSpecSelectOperations(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(`{
+		"openapi": "3.0.0",
+		"info": {"title": "Issues", "version": "1.0.0"},
+		"paths": {
+			"/issues": {
+				"get": {"operationId": "searchIssues", "x-nl-input-examples": ["search the issues"], "x-nl-negative-examples": ["search the releases"], "responses": {"200": {"description": "OK"}}},
+				"delete": {"operationId": "deleteIssues", "x-nl-input-examples-disabled": ["delete the issues"], "responses": {"204": {"description": "Deleted"}}}
+			},
+			"/issues/{id}": {
+				"get": {"operationId": "getIssue", "x-nl-input-examples": ["show the issue"], "responses": {"200": {"description": "OK"}}},
+				"put": {"operationId": "updateIssue", "summary": "Update an issue", "x-nl-negative-examples": ["show the issue"], "responses": {"200": {"description": "OK"}}}
+			}
+		}
+	}`))
+	assert.Nil(t, err)
+
+	pluginDataConfig := &PluginDataConfig{
+		SelectOperations: map[string]*AIExtensionConfig{
+			"getIssue":     {InputExamples: []string{"show me the ticket"}},
+			"searchIssues": {InputExamples: []string{"find the tickets"}, ReplaceExamples: true},
+			"updateIssue":  {InputExamples: []string{"change the ticket"}},
+			"deleteIssues": {InputExamples: []string{"remove all the tickets"}},
+			"unknown":      {InputExamples: []string{"do something"}},
+		},
+	}
+	addSpecSelectOperations(pluginDataConfig, &oas.OAS{T: *doc})
+
+	assert.Equal(t, map[string]*AIExtensionConfig{
+		"getIssue":     {InputExamples: []string{"show me the ticket", "show the issue"}},
+		"searchIssues": {InputExamples: []string{"find the tickets"}, NegativeExamples: []string{"search the releases"}, ReplaceExamples: true},
+		"updateIssue":  {InputExamples: []string{"change the ticket"}, NegativeExamples: []string{"show the issue"}},
+	}, pluginDataConfig.SelectOperations)
+}
+
+func TestUpdatePluginConfig(t *testing.T) {
+	// No operationId, so no embedding model is needed
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(`{
+		"openapi": "3.0.0",
+		"info": {"title": "Issues", "version": "1.0.0"},
+		"paths": {
+			"/issues": {"get": {"summary": "List the issues", "responses": {"200": {"description": "OK"}}}}
+		}
+	}`))
+	assert.Nil(t, err)
+	apiDef := &oas.OAS{T: *doc}
+	apiDef.SetTykExtension(&oas.XTykAPIGateway{
+		Server: oas.Server{ListenPath: oas.ListenPath{Value: "/issues-api/"}},
+		Middleware: &oas.Middleware{Global: &oas.Global{
+			PluginConfig: &oas.PluginConfig{Data: &oas.PluginConfigData{Enabled: true, Value: map[string]any{
+				"azureConfig": map[string]any{"openAIKey": "xxx"},
+			}}},
+		}},
+	})
+
+	apiId := "test-update-plugin-config"
+	t.Cleanup(func() { deletePluginConfig(apiId) })
+	r := httptest.NewRequest(http.MethodPut, "/issues-api/", nil)
+	r = r.WithContext(context.WithValue(r.Context(), ctx.OASDefinition, apiDef))
+
+	updated := make(chan error)
+	go func() { updated <- updatePluginConfig(apiId, r) }()
+	select {
+	case err := <-updated:
+		assert.Nil(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("updatePluginConfig is blocked")
+	}
+
+	pluginConfigLock.RLock()
+	pluginDataConfig := pluginConfig[apiId]
+	pluginConfigLock.RUnlock()
+	assert.NotNil(t, pluginDataConfig)
+	assert.Equal(t, "/issues-api/", pluginDataConfig.ListenPath)
+	_, err = agentBridgeStore.GetKey(apiId)
+	assert.Nil(t, err)
+
+	// A wrong configuration is rejected, the gateway goes on with the previous one
+	apiDef.GetTykExtension().Middleware.Global.PluginConfig.Data.Value["llmProvider"] = "unknown"
+	assert.NotNil(t, updatePluginConfig(apiId, r))
+	pluginConfigLock.RLock()
+	assert.Same(t, pluginDataConfig, pluginConfig[apiId])
+	pluginConfigLock.RUnlock()
+}
+
+func TestGetConfigValue(t *testing.T) {
+	tests := []struct {
+		description string
+		configData  map[string]any
+		envValue    string
+		expected    string
+	}{
+		{"Missing value", map[string]any{}, "", "default"},
+		{"String value", map[string]any{"provider": "azure"}, "", "azure"},
+		{"Invalid value", map[string]any{"provider": 42.0}, "", "default"},
+		{"Null value", map[string]any{"provider": nil}, "", "default"},
+		{"Environment", map[string]any{"provider": 42.0}, "openai", "openai"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			t.Setenv("TEST_CONFIG_VALUE", tt.envValue)
+			assert.Equal(t, tt.expected, getConfigValue("default", tt.configData, "provider", "TEST_CONFIG_VALUE"))
+		})
+	}
+}
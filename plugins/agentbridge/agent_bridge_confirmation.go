@@ -0,0 +1,361 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+	"github.com/TykTechnologies/kin-openapi/routers"
+	"github.com/TykTechnologies/tyk/ctx"
+	"github.com/TykTechnologies/tyk/user"
+	"github.com/gorilla/mux"
+)
+
+const (
+	SPEC_EXT_REQUIRES_CONFIRMATION = "x-nl-requires-confirmation"
+	PENDING_ACTION_KEY_PREFIX      = "pending:"
+	DEFAULT_CONFIRMATION_TTL       = 300 // In seconds
+)
+
+// ConfirmationConfig lists the operations that must be confirmed by the user
+// before being sent upstream.
+type ConfirmationConfig struct {
+	Methods      []string `json:"methods"`      // e.g. DELETE, applies to every operation using the method
+	OperationIDs []string `json:"operationIds"` // Specific operations
+	TTL          int64    `json:"ttl"`          // How long a pending action can be confirmed, in seconds
+}
+
+// pendingAction is a translated request waiting for a confirmation. It is
+// saved in the store so any gateway node can execute it.
+type pendingAction struct {
+	Token       string              `json:"token"`
+	APIID       string              `json:"api_id"`
+	Target      string              `json:"target"` // tyk://<api id><listen path>
+	OperationID string              `json:"operation_id"`
+	Method      string              `json:"method"`
+	Path        string              `json:"path"`
+	RawQuery    string              `json:"query"`
+	Headers     map[string][]string `json:"headers"`
+	Cookies     map[string]string   `json:"cookies,omitempty"` // The cookie parameters, without the credentials
+	// The names of the credentials, taken from the confirmation request
+	SecretHeaders     []string `json:"secret_headers,omitempty"`
+	SecretQueryParams []string `json:"secret_query_params,omitempty"`
+	Body              string   `json:"body"`
+	NLQuery           string   `json:"nl_query"`
+	ResponseType      string   `json:"response_type"`
+}
+
+// pendingActionResponse is sent back instead of executing the request.
+type pendingActionResponse struct {
+	Message   string         `json:"message"`
+	Token     string         `json:"token"`
+	ExpiresIn int64          `json:"expires_in"`
+	Request   dryRunResponse `json:"request"`
+}
+
+func parseConfirmationConfig(configData map[string]any) ConfirmationConfig {
+	confirmationConfig := ConfirmationConfig{
+		Methods:      []string{},
+		OperationIDs: []string{},
+		TTL:          DEFAULT_CONFIRMATION_TTL,
+	}
+
+	v, exists := configData["requireConfirmation"]
+	if !exists {
+		return confirmationConfig
+	}
+	confirmationData, ok := v.(map[string]any)
+	if !ok {
+		logger.Warningf("[+] Invalid type for requireConfirmation: %T; ignoring", v)
+		return confirmationConfig
+	}
+
+	for _, method := range getConfigStrings(confirmationData, "methods") {
+		confirmationConfig.Methods = append(confirmationConfig.Methods, strings.ToUpper(method))
+	}
+	confirmationConfig.OperationIDs = getConfigStrings(confirmationData, "operationIds")
+	if ttl, exists := confirmationData["ttl"]; exists {
+		if f, ok := ttl.(float64); ok && f > 0 {
+			confirmationConfig.TTL = int64(f)
+		} else {
+			logger.Warningf("[+] Invalid value for requireConfirmation.ttl: %v; using default %d", ttl, confirmationConfig.TTL)
+		}
+	}
+
+	return confirmationConfig
+}
+
+func getConfigStrings(configData map[string]any, key string) []string {
+	values := []string{}
+	v, exists := configData[key]
+	if !exists {
+		return values
+	}
+	items, ok := v.([]any)
+	if !ok {
+		logger.Warningf("[+] Invalid type for %s: %T; ignoring", key, v)
+		return values
+	}
+	for _, item := range items {
+		value, ok := item.(string)
+		if !ok {
+			logger.Warningf("[+] Invalid value in %s: %v; ignoring", key, item)
+			continue
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+// requiresConfirmation tells if the operation must be confirmed, either from
+// the plugin configuration or from the OpenAPI extension.
+func requiresConfirmation(config *PluginDataConfig, route *routers.Route) bool {
+	if slices.Contains(config.RequireConfirmation.Methods, strings.ToUpper(route.Method)) {
+		return true
+	}
+	if slices.Contains(config.RequireConfirmation.OperationIDs, route.Operation.OperationID) {
+		return true
+	}
+	if ext, exists := route.Operation.Extensions[SPEC_EXT_REQUIRES_CONFIRMATION]; exists {
+		if required, ok := ext.(bool); ok {
+			return required
+		}
+		logger.Warningf("[+] Invalid value for %s in operation %s: %v", SPEC_EXT_REQUIRES_CONFIRMATION, route.Operation.OperationID, ext)
+	}
+	return false
+}
+
+func newPendingAction(r *http.Request, config *PluginDataConfig, route *routers.Route) (*pendingAction, error) {
+	token, err := newRandomToken()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create the token: %w", err)
+	}
+
+	body := ""
+	if r.Body != nil {
+		bodyBytes, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the body: %w", err)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+		body = string(bodyBytes)
+	}
+
+	// Credentials are not stored, they are taken from the confirmation request
+	secretHeaders, secretQueryParams := getSecretParams(route)
+	headers := map[string][]string{}
+	for name, values := range r.Header {
+		if !slices.Contains(secretHeaders, http.CanonicalHeaderKey(name)) {
+			headers[name] = values
+		}
+	}
+	query := r.URL.Query()
+	for _, name := range secretQueryParams {
+		query.Del(name)
+	}
+
+	action := &pendingAction{
+		Token:       token,
+		APIID:       config.APIID,
+		Target:      fmt.Sprintf("tyk://%s%s", config.APIID, strings.TrimSuffix(config.ListenPath, "/")),
+		OperationID: route.Operation.OperationID,
+		Method:      r.Method,
+		Path:        stripListenPath(config.ListenPath, r.URL.Path),
+		RawQuery:    query.Encode(),
+		Headers:     headers,
+		Cookies:     getCookieParams(r, route),
+		Body:        body,
+
+		SecretHeaders:     secretHeaders,
+		SecretQueryParams: secretQueryParams,
+	}
+	if session := ctx.GetSession(r); session != nil {
+		action.NLQuery, _ = session.MetaData[METADATA_NLQ].(string)
+		action.ResponseType, _ = session.MetaData[METADATA_RESPONSE_TYPE].(string)
+	}
+	return action, nil
+}
+
+// getCookieParams returns the cookies of the request that are parameters of
+// the operation, except the ones holding credentials.
+func getCookieParams(r *http.Request, route *routers.Route) map[string]string {
+	parameters := append(openapi3.Parameters{}, route.Operation.Parameters...)
+	if route.PathItem != nil {
+		parameters = append(parameters, route.PathItem.Parameters...)
+	}
+	noOverrideCookies := getNoOverrideCookies(route)
+
+	cookies := map[string]string{}
+	for _, parameterRef := range parameters {
+		parameter := parameterRef.Value
+		if parameter == nil || parameter.In != openapi3.ParameterInCookie || slices.Contains(noOverrideCookies, parameter.Name) {
+			continue
+		}
+		if cookie, err := r.Cookie(parameter.Name); err == nil {
+			cookies[parameter.Name] = cookie.Value
+		}
+	}
+	return cookies
+}
+
+func savePendingAction(action *pendingAction, ttl int64) error {
+	if agentBridgeStore == nil {
+		return fmt.Errorf("storage is not configured")
+	}
+
+	jsonAction, err := json.Marshal(action)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the pending action: %w", err)
+	}
+
+	logger.Debugf("[+] Save pending action: %s", action.Token)
+	return agentBridgeStore.SetKey(PENDING_ACTION_KEY_PREFIX+action.Token, string(jsonAction), ttl)
+}
+
+func loadPendingAction(token string) (*pendingAction, error) {
+	if agentBridgeStore == nil {
+		return nil, fmt.Errorf("storage is not configured")
+	}
+
+	value, err := agentBridgeStore.GetKey(PENDING_ACTION_KEY_PREFIX + token)
+	if err != nil {
+		return nil, fmt.Errorf("unknown or expired pending action %s: %w", token, err)
+	}
+
+	action := &pendingAction{}
+	if err := json.Unmarshal([]byte(value), action); err != nil {
+		return nil, fmt.Errorf("conversion error for the pending action: %w", err)
+	}
+	return action, nil
+}
+
+// deletePendingAction returns false if the action doesn't exist anymore,
+// which guarantees that an action is executed only once.
+func deletePendingAction(token string) bool {
+	if agentBridgeStore == nil {
+		return false
+	}
+	logger.Debugf("[+] Delete pending action: %s", token)
+	return agentBridgeStore.DeleteKey(PENDING_ACTION_KEY_PREFIX + token)
+}
+
+// writePendingActionResponse saves the rewritten request and asks the user
+// to confirm it instead of sending it upstream.
+func writePendingActionResponse(rw http.ResponseWriter, r *http.Request, config *PluginDataConfig, route *routers.Route, selection *OperationSelection) {
+	action, err := newPendingAction(r, config, route)
+	if err != nil {
+		logger.Errorf("[+] Error creating the pending action: %s", err)
+		http.Error(rw, INTERNAL_ERROR_MSG, http.StatusInternalServerError)
+		return
+	}
+	if err := savePendingAction(action, config.RequireConfirmation.TTL); err != nil {
+		logger.Errorf("[+] Error saving the pending action: %s", err)
+		http.Error(rw, INTERNAL_ERROR_MSG, http.StatusInternalServerError)
+		return
+	}
+
+	response := pendingActionResponse{
+		Message:   fmt.Sprintf("this query will call %s %s, please confirm the action", route.Method, route.Path),
+		Token:     action.Token,
+		ExpiresIn: config.RequireConfirmation.TTL,
+		Request:   newDryRunResponse(r, route, selection),
+	}
+	jsonResponse, err := json.Marshal(response)
+	if err != nil {
+		logger.Errorf("[+] Error while marshalling the pending action: %s", err)
+		http.Error(rw, INTERNAL_ERROR_MSG, http.StatusInternalServerError)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusAccepted)
+	_, _ = rw.Write(jsonResponse)
+}
+
+// processConfirmAction executes a pending action by redirecting the request
+// to the API it was translated for.
+func processConfirmAction(rw http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+
+	action, err := loadPendingAction(token)
+	if err != nil {
+		logger.Errorf("[+] Error loading the pending action: %s", err)
+		http.Error(rw, "Unknown or expired action", http.StatusNotFound)
+		return
+	}
+	if !deletePendingAction(token) {
+		// Confirmed in the meantime by another request
+		http.Error(rw, "Unknown or expired action", http.StatusNotFound)
+		return
+	}
+
+	u, err := url.Parse(action.Target + action.Path)
+	if err != nil {
+		logger.Errorf("[+] Error while parsing the action URL (%v): %s", action.Target, err)
+		http.Error(rw, INTERNAL_ERROR_MSG, http.StatusInternalServerError)
+		return
+	}
+	logger.Debugf("[+] Confirmed action %s, redirect query to: %v ", token, u)
+
+	// Keep the credentials of the confirmation request
+	secretHeaders := action.SecretHeaders
+	if len(secretHeaders) == 0 {
+		secretHeaders = sensitiveHeaders
+	}
+	for name := range r.Header {
+		if !slices.Contains(secretHeaders, http.CanonicalHeaderKey(name)) {
+			r.Header.Del(name)
+		}
+	}
+	query, err := url.ParseQuery(action.RawQuery)
+	if err != nil {
+		logger.Errorf("[+] Error while parsing the action query (%v): %s", action.RawQuery, err)
+		http.Error(rw, INTERNAL_ERROR_MSG, http.StatusInternalServerError)
+		return
+	}
+	for _, name := range action.SecretQueryParams {
+		if values, present := r.URL.Query()[name]; present {
+			query[name] = values
+		}
+	}
+	u.RawQuery = query.Encode()
+	for name, values := range action.Headers {
+		r.Header[name] = values
+	}
+	if len(action.Cookies) > 0 {
+		setCookieParams(r, action.Cookies, nil)
+	}
+	r.Method = action.Method
+	r.Body = io.NopCloser(strings.NewReader(action.Body))
+	r.ContentLength = int64(len(action.Body))
+
+	session := &user.SessionState{
+		MetaData: map[string]any{
+			METADATA_NLQ:           action.NLQuery,
+			METADATA_RESPONSE_TYPE: action.ResponseType,
+		},
+	}
+	ctx.SetSession(r, session, true)
+	rctx := r.Context()
+	rctx = context.WithValue(rctx, ctx.UrlRewriteTarget, u)
+	SetContext(r, rctx)
+}
+
+// processCancelAction drops a pending action.
+func processCancelAction(rw http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+	if !deletePendingAction(token) {
+		http.Error(rw, "Unknown or expired action", http.StatusNotFound)
+		return
+	}
+	rw.WriteHeader(http.StatusNoContent)
+}
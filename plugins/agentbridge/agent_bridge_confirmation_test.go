@@ -0,0 +1,147 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+	"github.com/TykTechnologies/kin-openapi/routers"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/ctx"
+	"github.com/TykTechnologies/tyk/user"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+const confirmationTestSpec = `{
+	"openapi": "3.0.0",
+	"info": {"title": "Releases", "version": "1.0.0"},
+	"paths": {
+		"/releases": {
+			"get": {"operationId": "listReleases", "responses": {"200": {"description": "OK"}}},
+			"post": {"operationId": "createRelease", "x-nl-requires-confirmation": true, "responses": {"201": {"description": "Created"}}}
+		},
+		"/releases/{id}": {
+			"put": {"operationId": "updateRelease", "responses": {"200": {"description": "OK"}}},
+			"delete": {
+				"operationId": "deleteRelease",
+				"parameters": [{"name": "lang", "in": "cookie", "schema": {"type": "string"}}],
+				"responses": {"204": {"description": "Deleted"}}
+			}
+		}
+	},
+	"components": {
+		"securitySchemes": {
+			"session": {"type": "apiKey", "in": "cookie", "name": "session"},
+			"token": {"type": "apiKey", "in": "header", "name": "X-Release-Token"},
+			"key": {"type": "apiKey", "in": "query", "name": "api_key"}
+		}
+	}
+}`
+
+func TestRequiresConfirmation(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(confirmationTestSpec))
+	assert.Nil(t, err)
+
+	config := &PluginDataConfig{
+		RequireConfirmation: ConfirmationConfig{Methods: []string{http.MethodDelete}, OperationIDs: []string{"updateRelease"}},
+	}
+	tests := []struct {
+		path     string
+		method   string
+		expected bool
+	}{
+		{"/releases", http.MethodGet, false},
+		{"/releases", http.MethodPost, true},
+		{"/releases/{id}", http.MethodPut, true},
+		{"/releases/{id}", http.MethodDelete, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			route := &routers.Route{
+				Path:      tt.path,
+				Method:    tt.method,
+				Operation: doc.Paths[tt.path].GetOperation(tt.method),
+			}
+			assert.Equal(t, tt.expected, requiresConfirmation(config, route))
+		})
+	}
+}
+
+func TestConfirmPendingAction(t *testing.T) {
+	// Sessions need the gateway configuration
+	globalConfig := config.Global
+	config.Global = func() config.Config { return config.Config{} }
+	defer func() { config.Global = globalConfig }()
+
+	route := getTestRoute(t, confirmationTestSpec, "/releases/{id}", http.MethodDelete)
+	apiConfig := &PluginDataConfig{
+		APIID:               "releases",
+		ListenPath:          "/releases-api/",
+		RequireConfirmation: ConfirmationConfig{Methods: []string{http.MethodDelete}, TTL: DEFAULT_CONFIRMATION_TTL},
+	}
+
+	// The translated request is saved instead of being executed
+	r := httptest.NewRequest(http.MethodDelete, "/releases-api/releases/250?force=true&api_key=secret", strings.NewReader(`{"reason": "broken"}`))
+	r.Header.Set("Authorization", "Bearer secret")
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Cookie", "session=secret; lang=fr")
+	r.Header.Set("X-Release-Token", "secret")
+	ctx.SetSession(r, &user.SessionState{MetaData: map[string]any{METADATA_NLQ: "delete the release 250", METADATA_RESPONSE_TYPE: RESPONSE_TYPE_NL}}, true)
+
+	rw := httptest.NewRecorder()
+	writePendingActionResponse(rw, r, apiConfig, route, nil)
+	assert.Equal(t, http.StatusAccepted, rw.Code)
+
+	response := pendingActionResponse{}
+	assert.Nil(t, json.Unmarshal(rw.Body.Bytes(), &response))
+	assert.NotEmpty(t, response.Token)
+	assert.Equal(t, int64(DEFAULT_CONFIRMATION_TTL), response.ExpiresIn)
+	assert.Equal(t, "deleteRelease", response.Request.OperationID)
+	assert.Equal(t, []string{MASKED_VALUE}, response.Request.Headers["Authorization"])
+
+	action, err := loadPendingAction(response.Token)
+	assert.Nil(t, err)
+	assert.NotContains(t, action.Headers, "Authorization")
+	assert.NotContains(t, action.Headers, "X-Release-Token")
+	assert.Equal(t, "force=true", action.RawQuery)
+	assert.Equal(t, map[string]string{"lang": "fr"}, action.Cookies)
+
+	// The confirmation redirects the stored request to the API
+	confirm := httptest.NewRequest(http.MethodPost, "/api-bridge-agent/confirm/"+response.Token+"?api_key=confirm", nil)
+	confirm.Header.Set("Authorization", "Bearer confirm")
+	confirm.Header.Set("Content-Type", CONTENT_TYPE_NLQ)
+	confirm.Header.Set("Cookie", "session=confirm")
+	confirm.Header.Set("X-Release-Token", "confirm")
+	confirm = mux.SetURLVars(confirm, map[string]string{"token": response.Token})
+
+	rw = httptest.NewRecorder()
+	processConfirmAction(rw, confirm)
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, http.MethodDelete, confirm.Method)
+	assert.Equal(t, "Bearer confirm", confirm.Header.Get("Authorization"))
+	assert.Equal(t, "confirm", confirm.Header.Get("X-Release-Token"))
+	assert.Equal(t, "application/json", confirm.Header.Get("Content-Type"))
+	assert.Equal(t, "session=confirm; lang=fr", confirm.Header.Get("Cookie"))
+	body, _ := io.ReadAll(confirm.Body)
+	assert.Equal(t, `{"reason": "broken"}`, string(body))
+
+	target, ok := confirm.Context().Value(ctx.UrlRewriteTarget).(*url.URL)
+	assert.True(t, ok)
+	assert.Equal(t, "tyk://releases/releases-api/releases/250?api_key=confirm&force=true", target.String())
+	assert.Equal(t, "delete the release 250", ctx.GetSession(confirm).MetaData[METADATA_NLQ])
+
+	// An action can only be confirmed once
+	rw = httptest.NewRecorder()
+	processConfirmAction(rw, confirm)
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+}
@@ -1,7 +1,7 @@
 // Copyright AGNTCY Contributors (https://github.com/agntcy)
 // SPDX-License-Identifier: Apache-2.0
 
-package main
+package agentbridge
 
 import (
 	"context"
@@ -10,7 +10,6 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"path/filepath"
 
 	"github.com/TykTechnologies/tyk/ctx"
 	"github.com/TykTechnologies/tyk/user"
@@ -29,8 +28,7 @@ type apiServicePluginApiConfig struct {
 
 type apiServicePluginData struct {
 	PluginServices   map[string]apiServicePluginApiConfig
-	ModelPath        string
-	ModelEmbedder    *search.Vectorizer
+	ModelEmbedder    Embedder
 	ModelIndex       *search.Index[string]
 	StoreVersion     int64
 	MaxRequestLength int64 `json:"maxRequestLength"` // MaxRequestSize is the maximum size of the request in characters; default is -1 (no limit)
@@ -81,7 +79,7 @@ func processSelectService(rw http.ResponseWriter, r *http.Request, mcpFallback b
 	}
 	nlq := string(nlqBytes)
 
-	service, err := findServiceFromQuery(nlq)
+	service, err := findServiceFromQuery(r.Context(), nlq)
 	if err != nil {
 		logger.Errorf("[+] Error while trying to find a matching service: %s", err)
 		http.Error(rw, INTERNAL_ERROR_MSG, http.StatusInternalServerError)
@@ -153,6 +151,9 @@ func initServicePluginApiConfig() error {
 	}
 	// Refresh config
 	for key, value := range apiKeysValues {
+		if isReservedStoreKey(key) {
+			continue
+		}
 		logger.Debugf("[+] Found key: '%s', with value: '%s'", key, value)
 		apiConfig := apiServicePluginApiConfig{}
 		err := json.Unmarshal([]byte(value), &apiConfig)
@@ -165,32 +166,34 @@ func initServicePluginApiConfig() error {
 	return nil
 }
 
-func findServiceFromQuery(query string) (string, error) {
+func findServiceFromQuery(ctx context.Context, query string) (string, error) {
 	if servicePluginData.ModelEmbedder == nil {
+		// The services of every API are compared, so they share the embedder configured in the environment
 		var err error
-		servicePluginData.ModelPath = filepath.Join(DEFAULT_MODEL_EMBEDDINGS_PATH, DEFAULT_MODEL_EMBEDDINGS_MODEL)
-		servicePluginData.ModelEmbedder, err = search.NewVectorizer(servicePluginData.ModelPath, 1)
+		servicePluginData.ModelEmbedder, err = newEmbedder(parseEmbeddingConfig(map[string]any{}), DEFAULT_MODEL_EMBEDDINGS_PATH, DEFAULT_MODEL_EMBEDDINGS_MODEL)
 		if err != nil {
-			return "", fmt.Errorf("unable to find embedding model %s: %s", servicePluginData.ModelPath, err)
+			return "", fmt.Errorf("unable to load embedding model: %s", err)
 		}
 		servicePluginData.ModelIndex = search.NewIndex[string]()
 		for _, service := range servicePluginData.PluginServices {
 			for _, utterance := range service.Utterances {
-				embedding, err := servicePluginData.ModelEmbedder.EmbedText(utterance)
+				// The index is kept for the next queries
+				embedding, err := embedTextWithCache(context.Background(), servicePluginData.ModelEmbedder, utterance)
 				if err != nil {
-					return "", fmt.Errorf("embedding model %s failed for text '%s': %s", servicePluginData.ModelPath, utterance, err)
+					return "", fmt.Errorf("embedding model %s failed for text '%s': %s", servicePluginData.ModelEmbedder.Name(), utterance, err)
 				}
 				servicePluginData.ModelIndex.Add(embedding, service.Target)
 			}
 		}
+		getEmbeddingCache().Prune()
 	}
 	if servicePluginData.ModelEmbedder == nil || servicePluginData.ModelIndex == nil {
 		return "", fmt.Errorf("ModelEmbedder or ModelIndex is nil")
 	}
 
-	embedding, err := servicePluginData.ModelEmbedder.EmbedText(query)
+	embedding, err := servicePluginData.ModelEmbedder.EmbedText(ctx, query)
 	if err != nil {
-		return "", fmt.Errorf("embedding model %s failed for query '%s': %s", servicePluginData.ModelPath, query, err)
+		return "", fmt.Errorf("embedding model %s failed for query '%s': %s", servicePluginData.ModelEmbedder.Name(), query, err)
 	}
 	results := servicePluginData.ModelIndex.Search(embedding, NBRESULT)
 	if len(results) == 0 {
@@ -206,12 +209,3 @@ func findServiceFromQuery(query string) (string, error) {
 
 	return results[0].Value, nil
 }
-
-func init() {
-	logger.Infof("[+] Initializing API Bridge Agent plugin ...")
-
-	// Init Redis store, if needed
-	if agentBridgeStore == nil {
-		agentBridgeStore = getStorageForPlugin(context.TODO())
-	}
-}
@@ -0,0 +1,142 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/TykTechnologies/kin-openapi/routers"
+)
+
+const MASKED_VALUE = "********"
+
+// Headers always masked in the dry-run response
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key"}
+
+// dryRunResponse describes the request that would have been sent upstream.
+type dryRunResponse struct {
+	OperationID string              `json:"operationId"`
+	Score       *float64            `json:"score,omitempty"`      // Only when the operation was selected from the query
+	Confidence  float64             `json:"confidence,omitempty"` // Only when the LLM re-ranked the candidates
+	Method      string              `json:"method"`
+	URL         string              `json:"url"`
+	Headers     map[string][]string `json:"headers"`
+	Body        string              `json:"body"`
+}
+
+func isDryRun(r *http.Request) bool {
+	return isEnabled(r.Header.Get(HEADER_X_NL_DRY_RUN))
+}
+
+// writeDryRunResponse sends the description of the rewritten request back
+// to the client. The upstream is not called.
+func writeDryRunResponse(rw http.ResponseWriter, r *http.Request, route *routers.Route, selection *OperationSelection) {
+	jsonResponse, err := json.Marshal(newDryRunResponse(r, route, selection))
+	if err != nil {
+		logger.Errorf("[+] Error while marshalling the dry-run response: %s", err)
+		http.Error(rw, INTERNAL_ERROR_MSG, http.StatusInternalServerError)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	_, _ = rw.Write(jsonResponse)
+}
+
+// newDryRunResponse describes the rewritten request, with the secrets masked.
+// The selection is nil when the operation was not selected from the query.
+func newDryRunResponse(r *http.Request, route *routers.Route, selection *OperationSelection) dryRunResponse {
+	body := ""
+	if r.Body != nil {
+		bodyBytes, err := io.ReadAll(r.Body)
+		if err != nil {
+			logger.Errorf("[+] Error while reading the body: %s", err)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+		body = string(bodyBytes)
+	}
+
+	secretHeaders, secretQueryParams := getSecretParams(route)
+	headers := map[string][]string{}
+	for name, values := range r.Header {
+		if slices.Contains(secretHeaders, http.CanonicalHeaderKey(name)) {
+			headers[name] = []string{MASKED_VALUE}
+			continue
+		}
+		headers[name] = values
+	}
+
+	response := dryRunResponse{
+		OperationID: route.Operation.OperationID,
+		Method:      r.Method,
+		URL:         getUpstreamURL(r, secretQueryParams),
+		Headers:     headers,
+		Body:        body,
+	}
+	if selection != nil {
+		response.Score = &selection.Relevance
+		response.Confidence = selection.Confidence
+	}
+	return response
+}
+
+// getSecretParams returns the headers and query parameters carrying
+// credentials, either well known or declared as API keys in the OpenAPI spec.
+func getSecretParams(route *routers.Route) ([]string, []string) {
+	headers := slices.Clone(sensitiveHeaders)
+	queryParams := []string{}
+	if route.Spec == nil || route.Spec.Components == nil {
+		return headers, queryParams
+	}
+
+	for _, securityScheme := range route.Spec.Components.SecuritySchemes {
+		if securityScheme == nil || securityScheme.Value == nil || securityScheme.Value.Type != "apiKey" {
+			continue
+		}
+		switch securityScheme.Value.In {
+		case "header":
+			headers = append(headers, http.CanonicalHeaderKey(securityScheme.Value.Name))
+		case "query":
+			queryParams = append(queryParams, securityScheme.Value.Name)
+		}
+	}
+	return headers, queryParams
+}
+
+// getUpstreamURL returns the URL the request would be proxied to.
+func getUpstreamURL(r *http.Request, secretQueryParams []string) string {
+	upstreamURL := maskQueryParams(r.URL, secretQueryParams)
+
+	oasDef := getOASDefinition(r)
+	if oasDef == nil || oasDef.GetTykExtension() == nil {
+		return upstreamURL.String()
+	}
+	target, err := url.Parse(oasDef.GetTykExtension().Upstream.URL)
+	if err != nil || target.Host == "" {
+		return upstreamURL.String()
+	}
+
+	path := stripListenPath(oasDef.GetTykExtension().Server.ListenPath.Value, upstreamURL.Path)
+	target.Path = strings.TrimSuffix(target.Path, "/") + path
+	target.RawQuery = upstreamURL.RawQuery
+	return target.String()
+}
+
+// maskQueryParams returns a copy of the URL with the secret query parameters masked.
+func maskQueryParams(u *url.URL, secretQueryParams []string) *url.URL {
+	masked := *u
+	query := masked.Query()
+	for _, name := range secretQueryParams {
+		if query.Has(name) {
+			query.Set(name, MASKED_VALUE)
+		}
+	}
+	masked.RawQuery = query.Encode()
+	return &masked
+}
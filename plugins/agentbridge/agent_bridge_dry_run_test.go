@@ -0,0 +1,78 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TykTechnologies/tyk/apidef/oas"
+	"github.com/TykTechnologies/tyk/ctx"
+	"github.com/stretchr/testify/assert"
+)
+
+const dryRunTestSpec = `{
+	"openapi": "3.0.0",
+	"info": {"title": "Releases", "version": "1.0.0"},
+	"components": {
+		"securitySchemes": {
+			"token": {"type": "apiKey", "in": "header", "name": "X-Release-Token"},
+			"key": {"type": "apiKey", "in": "query", "name": "api_key"}
+		}
+	},
+	"paths": {
+		"/releases/{id}": {
+			"delete": {
+				"operationId": "deleteRelease",
+				"responses": {"204": {"description": "Deleted"}}
+			}
+		}
+	}
+}`
+
+func TestWriteDryRunResponse(t *testing.T) {
+	route := getTestRoute(t, dryRunTestSpec, "/releases/{id}", http.MethodDelete)
+
+	oasDef := &oas.OAS{T: *route.Spec}
+	oasDef.SetTykExtension(&oas.XTykAPIGateway{
+		Upstream: oas.Upstream{URL: "https://releases.example.com/v2/"},
+		Server:   oas.Server{ListenPath: oas.ListenPath{Value: "/releases-api/"}},
+	})
+
+	r := httptest.NewRequest(http.MethodDelete, "/releases-api/releases/250?api_key=secret&force=true", strings.NewReader(`{"reason": "broken"}`))
+	r = r.WithContext(context.WithValue(r.Context(), ctx.OASDefinition, oasDef))
+	r.Header.Set("Authorization", "Bearer secret")
+	r.Header.Set("X-Release-Token", "secret")
+	r.Header.Set("Accept", "application/json")
+
+	rw := httptest.NewRecorder()
+	writeDryRunResponse(rw, r, route, &OperationSelection{OperationID: "deleteRelease", Relevance: 0.87, Confidence: 0.9})
+
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.JSONEq(t, `{
+		"operationId": "deleteRelease",
+		"score": 0.87,
+		"confidence": 0.9,
+		"method": "DELETE",
+		"url": "https://releases.example.com/v2/releases/250?api_key=%2A%2A%2A%2A%2A%2A%2A%2A&force=true",
+		"headers": {
+			"Accept": ["application/json"],
+			"Authorization": ["********"],
+			"X-Release-Token": ["********"]
+		},
+		"body": "{\"reason\": \"broken\"}"
+	}`, rw.Body.String())
+	assert.NotContains(t, rw.Body.String(), "secret")
+}
+
+func TestIsDryRun(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	assert.False(t, isDryRun(r))
+
+	r.Header.Set(HEADER_X_NL_DRY_RUN, "true")
+	assert.True(t, isDryRun(r))
+}
@@ -0,0 +1,262 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kelindar/search"
+)
+
+const (
+	EMBEDDING_PROVIDER_LOCAL             = "local"             // GGUF model run in the gateway
+	EMBEDDING_PROVIDER_OPENAI_COMPATIBLE = "openai-compatible" // Any server exposing the OpenAI /embeddings endpoint
+	DEFAULT_EMBEDDING_PROVIDER           = EMBEDDING_PROVIDER_LOCAL
+
+	DEFAULT_OPENAI_EMBEDDING_MODEL = "text-embedding-3-small"
+	EMBEDDING_HTTP_TIMEOUT         = 30 * time.Second
+)
+
+// Embedder computes the embeddings of the examples and of the queries.
+type Embedder interface {
+	// Name identifies the model, two embedders with the same name give the
+	// same embeddings
+	Name() string
+	EmbedText(ctx context.Context, text string) ([]float32, error)
+}
+
+type EmbeddingConfig struct {
+	// Provider is "local" (selectModelEmbedding in selectModelsPath) or "openai-compatible"; default is "local"
+	Provider string            `json:"provider"`
+	Endpoint string            `json:"endpoint"`
+	APIKey   string            `json:"apiKey"`
+	Model    string            `json:"model"`
+	NoAuth   bool              `json:"noAuth"`  // Don't send any credential, for local servers
+	Headers  map[string]string `json:"headers"` // Extra headers sent with every embedding request
+}
+
+func parseEmbeddingConfig(configData map[string]any) EmbeddingConfig {
+	embeddingConfigData, exists := configData["embeddingConfig"].(map[string]any)
+	if !exists {
+		embeddingConfigData = map[string]any{}
+	}
+
+	embeddingConfig := EmbeddingConfig{
+		Provider: getConfigValue(DEFAULT_EMBEDDING_PROVIDER, embeddingConfigData, "provider", "EMBEDDING_PROVIDER"),
+		Endpoint: getConfigValue(DEFAULT_OPENAI_ENDPOINT, embeddingConfigData, "endpoint", "EMBEDDING_ENDPOINT"),
+		APIKey:   getConfigValue("", embeddingConfigData, "apiKey", "EMBEDDING_API_KEY"),
+		Model:    getConfigValue(DEFAULT_OPENAI_EMBEDDING_MODEL, embeddingConfigData, "model", "EMBEDDING_MODEL"),
+		Headers:  parseConfigHeaders(embeddingConfigData, "embeddingConfig"),
+	}
+
+	if v, exists := embeddingConfigData["noAuth"]; exists {
+		if noAuth, ok := v.(bool); ok {
+			embeddingConfig.NoAuth = noAuth
+		} else {
+			logger.Warningf("[+] Invalid type for embeddingConfig.noAuth: %T; using default %t", v, embeddingConfig.NoAuth)
+		}
+	}
+
+	return embeddingConfig
+}
+
+// getEmbedderName is the name of the embedder of the API, known before the
+// embedder is created. The embeddings are cached by this name.
+func getEmbedderName(config EmbeddingConfig, localModel string) string {
+	if config.Provider == EMBEDDING_PROVIDER_OPENAI_COMPATIBLE {
+		return config.Model + "@" + config.Endpoint
+	}
+	return localModel
+}
+
+// getEmbedderKey identifies the embedder shared by the APIs with the same
+// configuration: the path of a local model, or the name of a remote one with
+// a hash of its credentials and headers.
+func getEmbedderKey(config EmbeddingConfig, modelsPath string, localModel string) string {
+	if config.Provider != EMBEDDING_PROVIDER_OPENAI_COMPATIBLE {
+		return filepath.Join(modelsPath, localModel)
+	}
+	jsonConfig, err := json.Marshal(config)
+	if err != nil {
+		return getEmbedderName(config, localModel)
+	}
+	configHash := sha256.Sum256(jsonConfig)
+	return getEmbedderName(config, localModel) + "#" + hex.EncodeToString(configHash[:8])
+}
+
+func newEmbedder(config EmbeddingConfig, modelsPath string, localModel string) (Embedder, error) {
+	switch config.Provider {
+	case EMBEDDING_PROVIDER_LOCAL:
+		vectorizer, err := search.NewVectorizer(filepath.Join(modelsPath, localModel), VECTORIZER_GPU_LAYERS)
+		if err != nil {
+			return nil, err
+		}
+		return &localEmbedder{name: localModel, vectorizer: vectorizer}, nil
+	case EMBEDDING_PROVIDER_OPENAI_COMPATIBLE:
+		return newOpenAIEmbedder(config)
+	default:
+		return nil, fmt.Errorf("unknown embeddingConfig.provider '%s', must be one of %s or %s",
+			config.Provider, EMBEDDING_PROVIDER_LOCAL, EMBEDDING_PROVIDER_OPENAI_COMPATIBLE)
+	}
+}
+
+// getEmbedder returns the embedder of the API, when it was loaded.
+func getEmbedder(pluginDataConfig *PluginDataConfig) (Embedder, bool) {
+	embeddingModelsLock.RLock()
+	defer embeddingModelsLock.RUnlock()
+
+	embedder, present := embeddingModels[getEmbedderKey(pluginDataConfig.EmbeddingConfig, pluginDataConfig.SelectModelsPath, pluginDataConfig.SelectModelEmbedding)]
+	return embedder, present
+}
+
+// loadEmbedder creates the embedder of the API, unless another API already
+// uses the same one.
+func loadEmbedder(apiId string, pluginDataConfig *PluginDataConfig) error {
+	name := getEmbedderName(pluginDataConfig.EmbeddingConfig, pluginDataConfig.SelectModelEmbedding)
+	key := getEmbedderKey(pluginDataConfig.EmbeddingConfig, pluginDataConfig.SelectModelsPath, pluginDataConfig.SelectModelEmbedding)
+	logger.Debugf("[+] Loading embedding model %s for api id: %s", name, apiId)
+	if _, present := getEmbedder(pluginDataConfig); present {
+		logger.Debugf("[+] embedding model %s cached for api id: %s", name, apiId)
+		return nil
+	}
+
+	embeddingModelsLock.Lock()
+	defer embeddingModelsLock.Unlock()
+	if _, present := embeddingModels[key]; present {
+		return nil
+	}
+	embedder, err := newEmbedder(pluginDataConfig.EmbeddingConfig, pluginDataConfig.SelectModelsPath, pluginDataConfig.SelectModelEmbedding)
+	if err != nil {
+		return fmt.Errorf("unable to load embedding model %s: %w", name, err)
+	}
+	embeddingModels[key] = embedder
+	logger.Debugf("[+] Added embedding model %s for api id: %s", name, apiId)
+	return nil
+}
+
+// localEmbedder runs a GGUF model with llama.cpp.
+type localEmbedder struct {
+	name       string
+	vectorizer *search.Vectorizer
+}
+
+func (e *localEmbedder) Name() string {
+	return e.name
+}
+
+// EmbedText runs llama.cpp, which can't be interrupted, so the context is
+// only checked before.
+func (e *localEmbedder) EmbedText(ctx context.Context, text string) ([]float32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return e.vectorizer.EmbedText(text)
+}
+
+// openAIEmbedder calls the /embeddings endpoint of OpenAI, or of a
+// self-hosted server with the same API (Ollama, llama.cpp server, vLLM, ...).
+type openAIEmbedder struct {
+	config     EmbeddingConfig
+	httpClient *http.Client
+}
+
+type openAIEmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type openAIEmbeddingResponse struct {
+	Data []struct {
+		Embedding []float32 `json:"embedding"`
+		Index     int       `json:"index"`
+	} `json:"data"`
+}
+
+func newOpenAIEmbedder(config EmbeddingConfig) (Embedder, error) {
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("missing required config for embeddingConfig.endpoint")
+	}
+	if !config.NoAuth && config.APIKey == "" {
+		return nil, fmt.Errorf("missing required config for embeddingConfig.apiKey")
+	}
+
+	return &openAIEmbedder{
+		config:     config,
+		httpClient: &http.Client{Timeout: EMBEDDING_HTTP_TIMEOUT},
+	}, nil
+}
+
+func (e *openAIEmbedder) Name() string {
+	return getEmbedderName(e.config, "")
+}
+
+func (e *openAIEmbedder) EmbedText(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := e.embedTexts(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+func (e *openAIEmbedder) embedTexts(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(openAIEmbeddingRequest{Model: e.config.Model, Input: texts})
+	if err != nil {
+		return nil, err
+	}
+	url := strings.TrimSuffix(e.config.Endpoint, "/") + "/embeddings"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if !e.config.NoAuth {
+		req.Header.Set("Authorization", "Bearer "+e.config.APIKey)
+	}
+	for name, value := range e.config.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embedding request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the embedding response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embedding request failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	embeddingResponse := openAIEmbeddingResponse{}
+	if err := json.Unmarshal(respBody, &embeddingResponse); err != nil {
+		return nil, fmt.Errorf("invalid embedding response: %w", err)
+	}
+	if len(embeddingResponse.Data) != len(texts) {
+		return nil, fmt.Errorf("invalid embedding response: %d embeddings for %d texts", len(embeddingResponse.Data), len(texts))
+	}
+	sort.Slice(embeddingResponse.Data, func(i, j int) bool {
+		return embeddingResponse.Data[i].Index < embeddingResponse.Data[j].Index
+	})
+	embeddings := make([][]float32, len(texts))
+	for i, data := range embeddingResponse.Data {
+		if len(data.Embedding) == 0 {
+			return nil, fmt.Errorf("invalid embedding response: empty embedding")
+		}
+		embeddings[i] = data.Embedding
+	}
+	return embeddings, nil
+}
@@ -0,0 +1,112 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenAIEmbedder(t *testing.T) {
+	var request openAIEmbeddingRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		assert.Equal(t, "Bearer xxx", r.Header.Get("Authorization"))
+		assert.Equal(t, "agntcy", r.Header.Get("X-Tenant"))
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&request))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"object": "list", "data": [{"object": "embedding", "index": 0, "embedding": [0.5, -0.25, 1]}]}`))
+	}))
+	defer server.Close()
+
+	embedder, err := newEmbedder(EmbeddingConfig{
+		Provider: EMBEDDING_PROVIDER_OPENAI_COMPATIBLE,
+		Endpoint: server.URL + "/v1/",
+		APIKey:   "xxx",
+		Model:    "nomic-embed-text",
+		Headers:  map[string]string{"X-Tenant": "agntcy"},
+	}, DEFAULT_MODEL_EMBEDDINGS_PATH, DEFAULT_MODEL_EMBEDDINGS_MODEL)
+	assert.Nil(t, err)
+	assert.Equal(t, "nomic-embed-text@"+server.URL+"/v1/", embedder.Name())
+
+	embedding, err := embedder.EmbedText(context.TODO(), "list my gists")
+	assert.Nil(t, err)
+	assert.Equal(t, []float32{0.5, -0.25, 1}, embedding)
+
+	// The request is canceled with the context of the query
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = embedder.EmbedText(ctx, "list my gists")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, openAIEmbeddingRequest{Model: "nomic-embed-text", Input: []string{"list my gists"}}, request)
+}
+
+func TestOpenAIEmbedderErrors(t *testing.T) {
+	tests := []struct {
+		description string
+		status      int
+		body        string
+	}{
+		{"Server error", http.StatusInternalServerError, `{"error": "model not found"}`},
+		{"Invalid JSON", http.StatusOK, `not json`},
+		{"No embedding", http.StatusOK, `{"data": []}`},
+		{"Empty embedding", http.StatusOK, `{"data": [{"index": 0, "embedding": []}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// No credential is sent to a server without authentication
+				assert.Empty(t, r.Header.Get("Authorization"))
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			embedder, err := newOpenAIEmbedder(EmbeddingConfig{Endpoint: server.URL, Model: "nomic-embed-text", NoAuth: true})
+			assert.Nil(t, err)
+			_, err = embedder.EmbedText(context.TODO(), "list my gists")
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestNewEmbedderInvalidConfig(t *testing.T) {
+	_, err := newEmbedder(EmbeddingConfig{Provider: "unknown"}, DEFAULT_MODEL_EMBEDDINGS_PATH, DEFAULT_MODEL_EMBEDDINGS_MODEL)
+	assert.ErrorContains(t, err, "unknown embeddingConfig.provider")
+
+	_, err = newEmbedder(EmbeddingConfig{Provider: EMBEDDING_PROVIDER_OPENAI_COMPATIBLE, Endpoint: DEFAULT_OPENAI_ENDPOINT}, DEFAULT_MODEL_EMBEDDINGS_PATH, DEFAULT_MODEL_EMBEDDINGS_MODEL)
+	assert.ErrorContains(t, err, "embeddingConfig.apiKey")
+}
+
+func TestGetEmbedderName(t *testing.T) {
+	assert.Equal(t, DEFAULT_MODEL_EMBEDDINGS_MODEL, getEmbedderName(EmbeddingConfig{Provider: EMBEDDING_PROVIDER_LOCAL}, DEFAULT_MODEL_EMBEDDINGS_MODEL))
+	assert.Equal(t, "nomic-embed-text@http://localhost:11434/v1", getEmbedderName(EmbeddingConfig{
+		Provider: EMBEDDING_PROVIDER_OPENAI_COMPATIBLE,
+		Endpoint: "http://localhost:11434/v1",
+		Model:    "nomic-embed-text",
+	}, DEFAULT_MODEL_EMBEDDINGS_MODEL))
+}
+
+func TestGetEmbedderKey(t *testing.T) {
+	local := EmbeddingConfig{Provider: EMBEDDING_PROVIDER_LOCAL}
+	assert.NotEqual(t, getEmbedderKey(local, "models", DEFAULT_MODEL_EMBEDDINGS_MODEL), getEmbedderKey(local, "other-models", DEFAULT_MODEL_EMBEDDINGS_MODEL))
+
+	// The APIs with other credentials don't share the embedder, but the cache
+	first := EmbeddingConfig{Provider: EMBEDDING_PROVIDER_OPENAI_COMPATIBLE, Endpoint: DEFAULT_OPENAI_ENDPOINT, Model: "text-embedding-3-small", APIKey: "first-key"}
+	second := first
+	second.APIKey = "second-key"
+	third := first
+	third.Headers = map[string]string{"OpenAI-Organization": "org"}
+	assert.NotEqual(t, getEmbedderKey(first, "", ""), getEmbedderKey(second, "", ""))
+	assert.NotEqual(t, getEmbedderKey(first, "", ""), getEmbedderKey(third, "", ""))
+	assert.Equal(t, getEmbedderKey(first, "", ""), getEmbedderKey(first, "models", DEFAULT_MODEL_EMBEDDINGS_MODEL))
+	assert.Equal(t, getEmbedderName(first, ""), getEmbedderName(second, ""))
+	assert.NotContains(t, getEmbedderKey(first, "", ""), "first-key")
+}
@@ -0,0 +1,310 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/TykTechnologies/tyk/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	EMBEDDING_CACHE_STORE = "store" // Shared by the gateway nodes through Redis
+	EMBEDDING_CACHE_DISK  = "disk"
+	EMBEDDING_CACHE_NONE  = "none"
+
+	DEFAULT_EMBEDDING_CACHE             = EMBEDDING_CACHE_STORE
+	DEFAULT_EMBEDDING_CACHE_MAX_ENTRIES = 50000
+
+	EMBEDDING_CACHE_KEY_PREFIX = "agent_bridge_embedding:" // Apart from the keys of agentBridgeStore
+	EMBEDDING_CACHE_LRU_KEY    = "lru"                     // Sorted set of the keys, by last use
+	EMBEDDING_CACHE_LRU_BATCH  = 1000                      // Uses of the entries sent at once to the sorted set
+)
+
+// embeddingCache keeps the embeddings of the examples across restarts. The
+// entries are keyed by the model name and the hash of the text.
+type embeddingCache interface {
+	Get(model string, text string) ([]float32, bool)
+	Set(model string, text string, embedding []float32)
+	// Prune evicts the least recently used entries above the size limit
+	Prune()
+}
+
+var embeddingsCache embeddingCache
+var embeddingsCacheOnce sync.Once
+
+// getEmbeddingCache creates the cache configured with the EMBEDDING_CACHE,
+// EMBEDDING_CACHE_PATH and EMBEDDING_CACHE_MAX_ENTRIES environment variables.
+func getEmbeddingCache() embeddingCache {
+	embeddingsCacheOnce.Do(func() {
+		maxEntries := getEnvAsInt("EMBEDDING_CACHE_MAX_ENTRIES", DEFAULT_EMBEDDING_CACHE_MAX_ENTRIES)
+		switch cacheType := getEnvOrDefault("", "EMBEDDING_CACHE", DEFAULT_EMBEDDING_CACHE); cacheType {
+		case EMBEDDING_CACHE_STORE:
+			if agentBridgeStore == nil {
+				// The command line tools run without the store of the gateway
+				logger.Warningf("[+] No store for EMBEDDING_CACHE=%s; the embeddings are not cached", cacheType)
+				embeddingsCache = noEmbeddingCache{}
+				break
+			}
+			embeddingsCache = newStoreEmbeddingCache(maxEntries)
+		case EMBEDDING_CACHE_DISK:
+			path := getEnvOrDefault("", "EMBEDDING_CACHE_PATH", filepath.Join(DEFAULT_MODEL_EMBEDDINGS_PATH, "cache"))
+			embeddingsCache = &diskEmbeddingCache{path: path, maxEntries: maxEntries}
+		case EMBEDDING_CACHE_NONE:
+			embeddingsCache = noEmbeddingCache{}
+		default:
+			logger.Warningf("[+] Invalid value for EMBEDDING_CACHE: %s; the embeddings are not cached", cacheType)
+			embeddingsCache = noEmbeddingCache{}
+		}
+	})
+	return embeddingsCache
+}
+
+// embedTextWithCache returns the cached embedding of the text, or computes
+// and caches it.
+func embedTextWithCache(ctx context.Context, modelEmbedder Embedder, text string) ([]float32, error) {
+	cache := getEmbeddingCache()
+	model := modelEmbedder.Name()
+	if embedding, found := cache.Get(model, text); found {
+		return embedding, nil
+	}
+	embedding, err := modelEmbedder.EmbedText(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	cache.Set(model, text, embedding)
+	return embedding, nil
+}
+
+func getEmbeddingHash(model string, text string) string {
+	textHash := sha256.Sum256([]byte(text))
+	return model + ":" + hex.EncodeToString(textHash[:])
+}
+
+func encodeEmbedding(embedding []float32) []byte {
+	data := make([]byte, 4*len(embedding))
+	for i, value := range embedding {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(value))
+	}
+	return data
+}
+
+func decodeEmbedding(data []byte) ([]float32, error) {
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("invalid embedding size: %d", len(data))
+	}
+	embedding := make([]float32, len(data)/4)
+	for i := range embedding {
+		embedding[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return embedding, nil
+}
+
+type noEmbeddingCache struct{}
+
+func (noEmbeddingCache) Get(model string, text string) ([]float32, bool)    { return nil, false }
+func (noEmbeddingCache) Set(model string, text string, embedding []float32) {}
+func (noEmbeddingCache) Prune()                                             {}
+
+// storeEmbeddingCache saves the embeddings in Redis, with a sorted set of the
+// keys by last use for the eviction. Its keys have their own prefix, so the
+// API configurations of agentBridgeStore are listed without the embeddings.
+// The uses of the entries are sent in batches, at the latest by Prune.
+type storeEmbeddingCache struct {
+	store      *storage.RedisCluster
+	maxEntries int
+	lock       sync.Mutex
+	lastUses   map[string]float64 // Not sent yet, by key
+}
+
+func newStoreEmbeddingCache(maxEntries int) *storeEmbeddingCache {
+	return &storeEmbeddingCache{
+		store:      &storage.RedisCluster{KeyPrefix: EMBEDDING_CACHE_KEY_PREFIX, ConnectionHandler: agentBridgeStore.ConnectionHandler},
+		maxEntries: maxEntries,
+		lastUses:   map[string]float64{},
+	}
+}
+
+func (c *storeEmbeddingCache) Get(model string, text string) ([]float32, bool) {
+	key := getEmbeddingHash(model, text)
+	value, err := c.store.GetKey(key)
+	if err != nil {
+		return nil, false
+	}
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		logger.Warningf("[+] Invalid cached embedding %s: %s", key, err)
+		return nil, false
+	}
+	embedding, err := decodeEmbedding(data)
+	if err != nil {
+		logger.Warningf("[+] Invalid cached embedding %s: %s", key, err)
+		return nil, false
+	}
+	c.setLastUse(key)
+	return embedding, true
+}
+
+func (c *storeEmbeddingCache) Set(model string, text string, embedding []float32) {
+	key := getEmbeddingHash(model, text)
+	value := base64.StdEncoding.EncodeToString(encodeEmbedding(embedding))
+	if err := c.store.SetKey(key, value, AGENT_BRIDGE_DEFAULT_TTL); err != nil {
+		logger.Warningf("[+] Unable to cache the embedding %s: %s", key, err)
+		return
+	}
+	c.setLastUse(key)
+}
+
+func (c *storeEmbeddingCache) setLastUse(key string) {
+	c.lock.Lock()
+	c.lastUses[key] = float64(time.Now().UnixMilli())
+	full := len(c.lastUses) >= EMBEDDING_CACHE_LRU_BATCH
+	c.lock.Unlock()
+	if full {
+		c.sendLastUses()
+	}
+}
+
+// sendLastUses adds the last uses to the sorted set, in one command.
+func (c *storeEmbeddingCache) sendLastUses() {
+	c.lock.Lock()
+	lastUses := c.lastUses
+	c.lastUses = map[string]float64{}
+	c.lock.Unlock()
+	if len(lastUses) == 0 {
+		return
+	}
+
+	client, err := c.store.Client()
+	if err != nil {
+		logger.Warningf("[+] Unable to save the uses of the cached embeddings: %s", err)
+		return
+	}
+	members := make([]redis.Z, 0, len(lastUses))
+	for key, lastUse := range lastUses {
+		members = append(members, redis.Z{Score: lastUse, Member: key})
+	}
+	if err := client.ZAdd(context.Background(), c.store.KeyPrefix+EMBEDDING_CACHE_LRU_KEY, members...).Err(); err != nil {
+		logger.Warningf("[+] Unable to save the uses of the cached embeddings: %s", err)
+	}
+}
+
+func (c *storeEmbeddingCache) Prune() {
+	c.sendLastUses()
+	client, err := c.store.Client()
+	if err != nil {
+		logger.Warningf("[+] Unable to prune the embedding cache: %s", err)
+		return
+	}
+	ctx := context.Background()
+	lruKey := c.store.KeyPrefix + EMBEDDING_CACHE_LRU_KEY
+	count, err := client.ZCard(ctx, lruKey).Result()
+	if err != nil || count <= int64(c.maxEntries) {
+		return
+	}
+
+	// The least recently used entries come first in the sorted set
+	evictedCount := count - int64(c.maxEntries)
+	evicted, err := client.ZRange(ctx, lruKey, 0, evictedCount-1).Result()
+	if err != nil {
+		logger.Warningf("[+] Unable to prune the embedding cache: %s", err)
+		return
+	}
+	logger.Debugf("[+] Evict %d embeddings from the cache", len(evicted))
+	if err := client.ZRemRangeByRank(ctx, lruKey, 0, evictedCount-1).Err(); err != nil {
+		logger.Warningf("[+] Unable to prune the embedding cache: %s", err)
+		return
+	}
+	c.store.DeleteKeys(evicted)
+}
+
+// diskEmbeddingCache saves one file per embedding, in a directory per model
+// named by the hash of the model name, as remote models are named after their
+// endpoint. The modification time of the files is their last use.
+type diskEmbeddingCache struct {
+	path       string
+	maxEntries int
+	lock       sync.Mutex
+}
+
+func (c *diskEmbeddingCache) getFilename(model string, text string) string {
+	modelHash := sha256.Sum256([]byte(model))
+	textHash := sha256.Sum256([]byte(text))
+	return filepath.Join(c.path, hex.EncodeToString(modelHash[:]), hex.EncodeToString(textHash[:]))
+}
+
+func (c *diskEmbeddingCache) Get(model string, text string) ([]float32, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	filename := c.getFilename(model, text)
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, false
+	}
+	embedding, err := decodeEmbedding(data)
+	if err != nil {
+		logger.Warningf("[+] Invalid cached embedding %s: %s", filename, err)
+		return nil, false
+	}
+	now := time.Now()
+	_ = os.Chtimes(filename, now, now)
+	return embedding, true
+}
+
+func (c *diskEmbeddingCache) Set(model string, text string, embedding []float32) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	filename := c.getFilename(model, text)
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		logger.Warningf("[+] Unable to create the embedding cache directory: %s", err)
+		return
+	}
+	if err := os.WriteFile(filename, encodeEmbedding(embedding), 0o644); err != nil {
+		logger.Warningf("[+] Unable to cache the embedding %s: %s", filename, err)
+	}
+}
+
+func (c *diskEmbeddingCache) Prune() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	type cacheFile struct {
+		path    string
+		modTime time.Time
+	}
+	files := []cacheFile{}
+	_ = filepath.WalkDir(c.path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil {
+			files = append(files, cacheFile{path: path, modTime: info.ModTime()})
+		}
+		return nil
+	})
+	if len(files) <= c.maxEntries {
+		return
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	logger.Debugf("[+] Evict %d embeddings from the cache", len(files)-c.maxEntries)
+	for _, file := range files[:len(files)-c.maxEntries] {
+		_ = os.Remove(file.path)
+	}
+}
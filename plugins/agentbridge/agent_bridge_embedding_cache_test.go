@@ -0,0 +1,116 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeEmbedding(t *testing.T) {
+	embedding := []float32{0.25, -1.5, 3.1415927, 0}
+	decoded, err := decodeEmbedding(encodeEmbedding(embedding))
+	assert.Nil(t, err)
+	assert.Equal(t, embedding, decoded)
+
+	_, err = decodeEmbedding([]byte{1, 2, 3})
+	assert.NotNil(t, err)
+}
+
+func TestDiskEmbeddingCache(t *testing.T) {
+	cache := &diskEmbeddingCache{path: t.TempDir(), maxEntries: 2}
+
+	_, found := cache.Get("model.gguf", "list my gists")
+	assert.False(t, found)
+
+	texts := []string{"list my gists", "create a gist", "delete a gist"}
+	for i, text := range texts {
+		cache.Set("model.gguf", text, []float32{float32(i), 1})
+		// The oldest file is evicted first
+		past := time.Now().Add(time.Duration(i-len(texts)) * time.Minute)
+		assert.Nil(t, os.Chtimes(cache.getFilename("model.gguf", text), past, past))
+	}
+
+	// The same text embedded by another model is another entry
+	_, found = cache.Get("other.gguf", "list my gists")
+	assert.False(t, found)
+
+	// Reading an entry makes it the most recently used
+	embedding, found := cache.Get("model.gguf", "list my gists")
+	assert.True(t, found)
+	assert.Equal(t, []float32{0, 1}, embedding)
+
+	cache.Prune()
+	_, found = cache.Get("model.gguf", "list my gists")
+	assert.True(t, found)
+	_, found = cache.Get("model.gguf", "create a gist")
+	assert.False(t, found)
+	_, found = cache.Get("model.gguf", "delete a gist")
+	assert.True(t, found)
+}
+
+func TestDiskEmbeddingCacheRemoteModels(t *testing.T) {
+	cache := &diskEmbeddingCache{path: t.TempDir(), maxEntries: 10}
+
+	// Both models end with the same path segment
+	first := getEmbedderName(EmbeddingConfig{Provider: EMBEDDING_PROVIDER_OPENAI_COMPATIBLE, Model: "text-embedding-3-small", Endpoint: "https://first.example.com/v1"}, "")
+	second := getEmbedderName(EmbeddingConfig{Provider: EMBEDDING_PROVIDER_OPENAI_COMPATIBLE, Model: "text-embedding-3-small", Endpoint: "https://second.example.com/v1"}, "")
+	cache.Set(first, "list my gists", []float32{1, 0})
+	cache.Set(second, "list my gists", []float32{0, 1})
+
+	embedding, found := cache.Get(first, "list my gists")
+	assert.True(t, found)
+	assert.Equal(t, []float32{1, 0}, embedding)
+	embedding, found = cache.Get(second, "list my gists")
+	assert.True(t, found)
+	assert.Equal(t, []float32{0, 1}, embedding)
+	assert.NotEqual(t, filepath.Dir(cache.getFilename(first, "list my gists")), filepath.Dir(cache.getFilename(second, "list my gists")))
+
+	// The model names can't escape the cache directory
+	assert.Equal(t, cache.path, filepath.Dir(filepath.Dir(cache.getFilename("../..", "list my gists"))))
+}
+
+func TestStoreEmbeddingCache(t *testing.T) {
+	cache := newStoreEmbeddingCache(2)
+	model := "test-store-cache-" + time.Now().Format(time.RFC3339Nano)
+
+	texts := []string{"list my gists", "create a gist", "delete a gist"}
+	for i, text := range texts {
+		cache.Set(model, text, []float32{float32(i), 1})
+		time.Sleep(5 * time.Millisecond)
+	}
+	embedding, found := cache.Get(model, "list my gists")
+	assert.True(t, found)
+	assert.Equal(t, []float32{0, 1}, embedding)
+
+	cache.Prune()
+	_, found = cache.Get(model, "list my gists")
+	assert.True(t, found)
+	_, found = cache.Get(model, "create a gist")
+	assert.False(t, found)
+	_, found = cache.Get(model, "delete a gist")
+	assert.True(t, found)
+	// Apart from the API configurations
+	_, err := agentBridgeStore.GetKey(getEmbeddingHash(model, "delete a gist"))
+	assert.NotNil(t, err)
+
+	// The entries used at the same time are evicted one by one
+	lastUse := float64(time.Now().UnixMilli())
+	for _, text := range texts {
+		cache.Set(model, text, []float32{1, 1})
+		cache.lastUses[getEmbeddingHash(model, text)] = lastUse
+	}
+	cache.Prune()
+	cached := 0
+	for _, text := range texts {
+		if _, found := cache.Get(model, text); found {
+			cached++
+		}
+	}
+	assert.Equal(t, 2, cached)
+}
@@ -0,0 +1,109 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/kelindar/search"
+)
+
+const (
+	NBRESULT                  = 1
+	MAX_RESULTS_PER_OPERATION = 10 // Examples of the same operation returned when looking for candidates
+)
+
+func findSelectOperation(ctx context.Context, apiId string, input string) (*string, float64, error) {
+	results, err := FindSelectOperationCandidates(ctx, apiId, input, NBRESULT)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(results) < 1 {
+		return nil, 0, nil
+	} else {
+		if NBRESULT > 1 {
+			for index, result := range results {
+				logger.Debugf("Result %d: %v / %v\n", index, result.OperationID, result.Relevance)
+			}
+		}
+		return &results[0].OperationID, results[0].Relevance, nil
+	}
+}
+
+// OperationCandidate is an operation matching the input, with the relevance
+// of its closest example and the score the candidates are ranked on: the
+// relevance, fused with the lexical score when there is a lexicalWeight.
+type OperationCandidate struct {
+	OperationID string
+	Relevance   float64
+	Score       float64
+}
+
+// FindSelectOperationCandidates returns up to maxResults distinct operations
+// matching the input, the best match first. The operations whose negative
+// examples are closer to the input are penalized. With a lexicalWeight, the
+// candidates are ranked on the relevance fused with the BM25 score of the
+// operations.
+func FindSelectOperationCandidates(ctx context.Context, apiId string, input string, maxResults int) ([]OperationCandidate, error) {
+	apiSpecIndicesLock.RLock()
+	apiSpecIndex, present := apiSpecIndices[apiId]
+	lexicalIndex := apiLexicalIndices[apiId]
+	negativeIndex := apiNegativeIndices[apiId]
+	apiSpecIndicesLock.RUnlock()
+	if !present {
+		// This API has no x-nl-input-examples
+		return nil, fmt.Errorf("no x-nl-input-examples found for api id: %s", apiId)
+	}
+	pluginConfigLock.RLock()
+	pluginDataConfig, present := pluginConfig[apiId]
+	pluginConfigLock.RUnlock()
+	if !present {
+		return nil, fmt.Errorf("no plugin config found for api: %s", apiId)
+	}
+	modelEmbedder, present := getEmbedder(pluginDataConfig)
+	if !present {
+		return nil, fmt.Errorf("no embedding model found for api id: %s", apiId)
+	}
+	embedding, err := modelEmbedder.EmbedText(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	// The learned examples are added to the index under the write lock
+	apiSpecIndicesLock.RLock()
+	results := []search.Result[string]{}
+	if pluginDataConfig.NegativeWeight > 0 && negativeIndex != nil && negativeIndex.Len() > 0 {
+		// All the examples are scored, so the best operation after the penalty
+		// doesn't depend on the number of results
+		results = apiSpecIndex.Search(embedding, apiSpecIndex.Len())
+		negatives := negativeIndex.Search(embedding, negativeIndex.Len())
+		results = penalizeNegativeExamples(results, negatives, pluginDataConfig.NegativeWeight)
+	} else {
+		results = apiSpecIndex.Search(embedding, maxResults*MAX_RESULTS_PER_OPERATION)
+	}
+	apiSpecIndicesLock.RUnlock()
+
+	// The index contains one entry per example, so an operation can be found several times
+	candidates := []OperationCandidate{}
+	for _, result := range results {
+		found := slices.ContainsFunc(candidates, func(candidate OperationCandidate) bool {
+			return candidate.OperationID == result.Value
+		})
+		if !found {
+			candidates = append(candidates, OperationCandidate{OperationID: result.Value, Relevance: result.Relevance, Score: result.Relevance})
+		}
+	}
+
+	if pluginDataConfig.LexicalWeight > 0 && lexicalIndex != nil {
+		lexical := lexicalIndex.Search(input, maxResults*MAX_RESULTS_PER_OPERATION)
+		candidates = fuseOperationScores(candidates, lexical, pluginDataConfig.LexicalWeight)
+	}
+
+	if len(candidates) > maxResults {
+		candidates = candidates[:maxResults]
+	}
+	return candidates, nil
+}
@@ -1,9 +1,10 @@
 // Copyright AGNTCY Contributors (https://github.com/agntcy)
 // SPDX-License-Identifier: Apache-2.0
 
-package main
+package agentbridge
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,25 +13,42 @@ import (
 	"slices"
 	"testing"
 
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+	"github.com/TykTechnologies/tyk/apidef/oas"
 	"github.com/kelindar/search"
 	"github.com/stretchr/testify/assert"
 )
 
+var SpecToTests = []struct {
+	apiId        string
+	specFilename string
+}{
+	{"tyk-gmail-id", "../../configs/gmail.googleapis.com.oas.json"},
+	{"tyk-jira-id", "../../configs/your-domain.atlassian.net.oas.json"},
+	{"tyk-github-id", "../../configs/api.github.com.gist.deref.oas.json"},
+	{"tyk-sendgrid-id", "../../configs/api.sendgrid.com.oas.json"},
+}
+
 type EndpointSelectionTestingRequests struct {
 	TargetApiID       string `json:"api_id"`
 	Query             string `json:"query"`
-	ExpectedOperation string `json:"expected"`
+	ExpectedOperation string `json:"expected"` // Empty when no operation should be selected
 	ReachThreshold    bool   `json:"reach_threshold"`
 }
 
-var SpecToTests = []struct {
-	apiId        string
-	specFilename string
-}{
-	{"tyk-gmail-id", "../configs/gmail.googleapis.com.oas.json"},
-	{"tyk-jira-id", "../configs/your-domain.atlassian.net.oas.json"},
-	{"tyk-github-id", "../configs/api.github.com.gist.deref.oas.json"},
-	{"tyk-sendgrid-id", "../configs/api.sendgrid.com.oas.json"},
+func loadRequestToTest(filename string) ([]EndpointSelectionTestingRequests, error) {
+	var tests []EndpointSelectionTestingRequests
+	file, err := os.Open(filename)
+	if err != nil {
+		return tests, fmt.Errorf("can't open file %s: %s", filename, err)
+	}
+	defer file.Close()
+	byteValue, _ := io.ReadAll(file)
+	err = json.Unmarshal(byteValue, &tests)
+	if err != nil {
+		return tests, fmt.Errorf("can't unmarshal file %s: %s", filename, err)
+	}
+	return tests, nil
 }
 
 func loadApiSpecsForTests(apiId string, specFilename string) (*PluginDataConfig, error) {
@@ -49,7 +67,7 @@ func loadApiSpecsForTests(apiId string, specFilename string) (*PluginDataConfig,
 		},
 		SelectOperations:     map[string]*AIExtensionConfig{},
 		SelectModelEmbedding: DEFAULT_MODEL_EMBEDDINGS_MODEL,
-		SelectModelsPath:     "../tyk-release-v5.8.1/models",
+		SelectModelsPath:     "../../tyk-release-v5.8.1/models",
 
 		APIID: apiId,
 	}
@@ -110,28 +128,28 @@ func initForTests() error {
 		if err != nil {
 			return fmt.Errorf("Unable to find embedding model %s: %s", pluginDataConfig.SelectModelEmbedding, err)
 		}
-		embeddingModels[pluginDataConfig.SelectModelEmbedding] = modelEmbedder
+		embeddingModels[getEmbedderKey(pluginDataConfig.EmbeddingConfig, pluginDataConfig.SelectModelsPath, pluginDataConfig.SelectModelEmbedding)] = &localEmbedder{name: pluginDataConfig.SelectModelEmbedding, vectorizer: modelEmbedder}
 
-		if err := initSelectOperations(apiID, pluginDataConfig); err != nil {
+		if err := initSelectOperations(apiID, pluginDataConfig, loadApiDefinitionForTests(apiID)); err != nil {
 			return fmt.Errorf("can't init operations for testing: %s", err)
 		}
 	}
 	return nil
 }
 
-func loadRequestToTest(filename string) ([]EndpointSelectionTestingRequests, error) {
-	var tests []EndpointSelectionTestingRequests
-	file, err := os.Open(filename)
-	if err != nil {
-		return tests, fmt.Errorf("can't open file %s: %s", filename, err)
-	}
-	defer file.Close()
-	byteValue, _ := io.ReadAll(file)
-	err = json.Unmarshal(byteValue, &tests)
-	if err != nil {
-		return tests, fmt.Errorf("can't unmarshal file %s: %s", filename, err)
+// loadApiDefinitionForTests gives the paths, tags and summaries to the lexical index
+func loadApiDefinitionForTests(apiId string) *oas.OAS {
+	for _, specData := range SpecToTests {
+		if specData.apiId != apiId {
+			continue
+		}
+		doc, err := openapi3.NewLoader().LoadFromFile(specData.specFilename)
+		if err != nil {
+			return nil
+		}
+		return &oas.OAS{T: *doc}
 	}
-	return tests, nil
+	return nil
 }
 
 func TestEndpointSelection(t *testing.T) {
@@ -154,7 +172,7 @@ func TestEndpointSelection(t *testing.T) {
 			}
 			_, ok := pluginConfig[tt.TargetApiID]
 			if ok {
-				matchingOperation, matchingScore, err := findSelectOperation(tt.TargetApiID, tt.Query)
+				matchingOperation, matchingScore, err := findSelectOperation(context.TODO(), tt.TargetApiID, tt.Query)
 
 				assert.Nil(t, err)
 				assert.Equal(t, tt.ExpectedOperation, *matchingOperation)
@@ -167,8 +185,50 @@ func TestEndpointSelection(t *testing.T) {
 
 }
 
+func TestEndpointSelectionHybrid(t *testing.T) {
+	err := initConfigFromApiSpecsForTests()
+	assert.Nil(t, err)
+	err = initForTests()
+	assert.Nil(t, err)
+	tests, err := loadRequestToTest("./testdata/endpoint_selection_requests_to_test.json")
+	assert.Nil(t, err)
+
+	lexicalWeights := map[string]float64{}
+	for apiId, pluginDataConfig := range pluginConfig {
+		lexicalWeights[apiId] = pluginDataConfig.LexicalWeight
+	}
+	t.Cleanup(func() {
+		for apiId, lexicalWeight := range lexicalWeights {
+			pluginConfig[apiId].LexicalWeight = lexicalWeight
+		}
+	})
+
+	hitRate := func(lexicalWeight float64) float64 {
+		hits, total := 0, 0
+		for _, tt := range tests {
+			pluginDataConfig, ok := pluginConfig[tt.TargetApiID]
+			if len(tt.ExpectedOperation) == 0 || !ok {
+				continue
+			}
+			pluginDataConfig.LexicalWeight = lexicalWeight
+			matchingOperation, _, err := findSelectOperation(context.TODO(), tt.TargetApiID, tt.Query)
+			assert.Nil(t, err)
+			total++
+			if matchingOperation != nil && *matchingOperation == tt.ExpectedOperation {
+				hits++
+			}
+		}
+		return float64(hits) / float64(total)
+	}
+
+	semantic := hitRate(0)
+	hybrid := hitRate(DEFAULT_LEXICAL_WEIGHT)
+	t.Logf("Hit rate: embeddings %.3f, hybrid %.3f", semantic, hybrid)
+	assert.Greater(t, hybrid, semantic)
+}
+
 func TestFixForUBatchBug(t *testing.T) {
-	pluginDataConfig, err := loadApiSpecsForTests("tyk-github-id", "../configs/api.github.com.gist.deref.oas.json")
+	pluginDataConfig, err := loadApiSpecsForTests("tyk-github-id", "../../configs/api.github.com.gist.deref.oas.json")
 	assert.Nil(t, err)
 
 	modelPath := filepath.Join(pluginDataConfig.SelectModelsPath, DEFAULT_MODEL_EMBEDDINGS_MODEL)
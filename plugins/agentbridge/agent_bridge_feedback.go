@@ -0,0 +1,313 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/tyk/ctx"
+	"github.com/gorilla/mux"
+	"github.com/kelindar/search"
+)
+
+const (
+	HEADER_X_NL_REQUEST_ID = "X-Nl-Request-Id"
+	METADATA_REQUEST_ID    = "RequestID"
+
+	SELECTION_KEY_PREFIX       = "selection:" // + request id
+	LEARNED_EXAMPLE_KEY_PREFIX = "learned:"   // + apiId:example id
+	DEFAULT_SELECTION_TTL      = 86400        // How long the feedback on a request is accepted, in seconds
+)
+
+// operationSelection is the operation selected for a query, kept in the store
+// until the client gives its feedback.
+type operationSelection struct {
+	RequestID   string  `json:"request_id"`
+	APIID       string  `json:"api_id"`
+	Query       string  `json:"query"`
+	OperationID string  `json:"operation_id"`
+	Score       float64 `json:"score"`
+}
+
+// feedbackRequest tells which operation should have been selected for the
+// query of a request.
+type feedbackRequest struct {
+	RequestID   string `json:"request_id"`
+	Query       string `json:"query"`
+	OperationID string `json:"operation_id"`
+}
+
+// learnedExample is a query confirmed by the feedback of a client, added to
+// the examples of the operation.
+type learnedExample struct {
+	ID                  string `json:"id"`
+	APIID               string `json:"api_id"`
+	Query               string `json:"query"`
+	OperationID         string `json:"operation_id"`
+	SelectedOperationID string `json:"selected_operation_id"`
+	CreatedAt           int64  `json:"created_at"` // Unix time, in seconds
+}
+
+type feedbackResponse struct {
+	Hit     bool           `json:"hit"` // The selected operation was the correct one
+	Example learnedExample `json:"example"`
+}
+
+// saveOperationSelection returns the request id given to the client to send
+// its feedback.
+func saveOperationSelection(apiId string, query string, operationID string, score float64) (string, error) {
+	if agentBridgeStore == nil {
+		return "", fmt.Errorf("storage is not configured")
+	}
+	requestID, err := newRandomToken()
+	if err != nil {
+		return "", fmt.Errorf("unable to create the request id: %w", err)
+	}
+
+	selection := operationSelection{RequestID: requestID, APIID: apiId, Query: query, OperationID: operationID, Score: score}
+	jsonSelection, err := json.Marshal(selection)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal the selection: %w", err)
+	}
+	if err := agentBridgeStore.SetKey(SELECTION_KEY_PREFIX+requestID, string(jsonSelection), DEFAULT_SELECTION_TTL); err != nil {
+		return "", fmt.Errorf("failed to save the selection: %w", err)
+	}
+	return requestID, nil
+}
+
+func loadOperationSelection(requestID string) (*operationSelection, error) {
+	if agentBridgeStore == nil {
+		return nil, fmt.Errorf("storage is not configured")
+	}
+	value, err := agentBridgeStore.GetKey(SELECTION_KEY_PREFIX + requestID)
+	if err != nil {
+		return nil, fmt.Errorf("unknown or expired request %s: %w", requestID, err)
+	}
+	selection := &operationSelection{}
+	if err := json.Unmarshal([]byte(value), selection); err != nil {
+		return nil, fmt.Errorf("conversion error for the selection: %w", err)
+	}
+	return selection, nil
+}
+
+// setRequestIDHeader gives the request id of the selection to the client, on
+// the upstream response.
+func setRequestIDHeader(res *http.Response, req *http.Request) {
+	session := ctx.GetSession(req)
+	if session == nil {
+		return
+	}
+	if requestID, _ := session.MetaData[METADATA_REQUEST_ID].(string); requestID != "" {
+		res.Header.Set(HEADER_X_NL_REQUEST_ID, requestID)
+	}
+}
+
+func saveLearnedExample(example learnedExample) error {
+	if agentBridgeStore == nil {
+		return fmt.Errorf("storage is not configured")
+	}
+	jsonExample, err := json.Marshal(example)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the learned example: %w", err)
+	}
+	return agentBridgeStore.SetKey(getLearnedExampleKey(example.APIID, example.ID), string(jsonExample), AGENT_BRIDGE_DEFAULT_TTL)
+}
+
+func getLearnedExampleKey(apiId string, id string) string {
+	return LEARNED_EXAMPLE_KEY_PREFIX + apiId + ":" + id
+}
+
+// getLearnedExamples returns the learned examples of the API, the oldest first.
+func getLearnedExamples(apiId string) []learnedExample {
+	examples := []learnedExample{}
+	if agentBridgeStore == nil || apiId == "" {
+		return examples
+	}
+	for key, value := range agentBridgeStore.GetKeysAndValuesWithFilter(getLearnedExampleKey(apiId, "*")) {
+		example := learnedExample{}
+		if err := json.Unmarshal([]byte(value), &example); err != nil {
+			logger.Warningf("[+] Invalid learned example %s: %s; ignoring", key, err)
+			continue
+		}
+		examples = append(examples, example)
+	}
+	sort.Slice(examples, func(i, j int) bool {
+		if examples[i].CreatedAt == examples[j].CreatedAt {
+			return examples[i].ID < examples[j].ID
+		}
+		return examples[i].CreatedAt < examples[j].CreatedAt
+	})
+	return examples
+}
+
+// addLearnedExamples adds the learned examples of the enabled operations to
+// the index of the API.
+func addLearnedExamples(ctx context.Context, apiSpecIndex *search.Index[string], modelEmbedder Embedder, pluginDataConfig *PluginDataConfig, examples []learnedExample) {
+	for _, example := range examples {
+		if _, present := pluginDataConfig.SelectOperations[example.OperationID]; !present {
+			continue
+		}
+		embedding, err := embedTextWithCache(ctx, modelEmbedder, example.Query)
+		if err != nil {
+			logger.Warningf("[+] embedding model %s failed for text \"%s\": %s", modelEmbedder.Name(), example.Query, err)
+			continue
+		}
+		apiSpecIndex.Add(embedding, example.OperationID)
+	}
+}
+
+// addLearnedExample adds a new learned example to the index of the API loaded
+// on this gateway node. The other nodes get it when the API is reloaded.
+func addLearnedExample(example learnedExample) error {
+	pluginConfigLock.RLock()
+	pluginDataConfig, present := pluginConfig[example.APIID]
+	pluginConfigLock.RUnlock()
+	if !present {
+		return nil
+	}
+	modelEmbedder, present := getEmbedder(pluginDataConfig)
+	if !present {
+		return fmt.Errorf("no embedding model found for api id: %s", example.APIID)
+	}
+	embedding, err := embedTextWithCache(context.Background(), modelEmbedder, example.Query)
+	if err != nil {
+		return fmt.Errorf("embedding model %s failed for text \"%s\": %w", modelEmbedder.Name(), example.Query, err)
+	}
+
+	// The index is searched under the read lock
+	apiSpecIndicesLock.Lock()
+	if apiSpecIndex, present := apiSpecIndices[example.APIID]; present {
+		apiSpecIndex.Add(embedding, example.OperationID)
+	}
+	apiSpecIndicesLock.Unlock()
+	return nil
+}
+
+// reloadLearnedExamples rebuilds the index of the API loaded on this gateway
+// node, with its current learned examples, when one of them is deleted. The
+// other nodes get them when the API is reloaded.
+func reloadLearnedExamples(apiId string) error {
+	pluginConfigLock.RLock()
+	pluginDataConfig, present := pluginConfig[apiId]
+	pluginConfigLock.RUnlock()
+	if !present || len(pluginDataConfig.SelectOperations) == 0 {
+		return nil
+	}
+	modelEmbedder, present := getEmbedder(pluginDataConfig)
+	if !present {
+		return fmt.Errorf("no embedding model found for api id: %s", apiId)
+	}
+
+	ctx := context.Background()
+	apiSpecIndex := newOperationsIndex(ctx, modelEmbedder, pluginDataConfig.SelectOperations)
+	addLearnedExamples(ctx, apiSpecIndex, modelEmbedder, pluginDataConfig, getLearnedExamples(apiId))
+
+	apiSpecIndicesLock.Lock()
+	apiSpecIndices[apiId] = apiSpecIndex
+	apiSpecIndicesLock.Unlock()
+	return nil
+}
+
+// processFeedback learns the query of a request as an example of the correct
+// operation, whether the selected operation was the right one or not.
+func processFeedback(rw http.ResponseWriter, r *http.Request) {
+	feedback := feedbackRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&feedback); err != nil {
+		http.Error(rw, "Invalid feedback", http.StatusBadRequest)
+		return
+	}
+	feedback.Query = strings.TrimSpace(feedback.Query)
+	if feedback.RequestID == "" || feedback.Query == "" || feedback.OperationID == "" {
+		http.Error(rw, "request_id, query and operation_id are required", http.StatusBadRequest)
+		return
+	}
+	if len(feedback.Query) > MAX_UTERANCE_LENGTH {
+		http.Error(rw, "Query is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	selection, err := loadOperationSelection(feedback.RequestID)
+	if err != nil {
+		logger.Debugf("[+] Error loading the selection: %s", err)
+		http.Error(rw, "Unknown or expired request", http.StatusNotFound)
+		return
+	}
+	if selection.Query != feedback.Query {
+		http.Error(rw, "The query doesn't match the request", http.StatusBadRequest)
+		return
+	}
+	pluginConfigLock.RLock()
+	pluginDataConfig, present := pluginConfig[selection.APIID]
+	pluginConfigLock.RUnlock()
+	if !present {
+		// The operation can't be checked
+		http.Error(rw, "Unknown API", http.StatusNotFound)
+		return
+	}
+	if _, enabled := pluginDataConfig.SelectOperations[feedback.OperationID]; !enabled {
+		http.Error(rw, "Unknown or disabled operation", http.StatusBadRequest)
+		return
+	}
+	if !agentBridgeStore.DeleteKey(SELECTION_KEY_PREFIX + feedback.RequestID) {
+		// Only one feedback per request
+		http.Error(rw, "Unknown or expired request", http.StatusNotFound)
+		return
+	}
+
+	example := learnedExample{
+		ID:                  feedback.RequestID,
+		APIID:               selection.APIID,
+		Query:               selection.Query,
+		OperationID:         feedback.OperationID,
+		SelectedOperationID: selection.OperationID,
+		CreatedAt:           time.Now().Unix(),
+	}
+	if err := saveLearnedExample(example); err != nil {
+		logger.Errorf("[+] Error saving the learned example: %s", err)
+		http.Error(rw, INTERNAL_ERROR_MSG, http.StatusInternalServerError)
+		return
+	}
+	logger.Debugf("[+] Learned example for %s: %s (selected %s)", example.OperationID, example.Query, example.SelectedOperationID)
+	if err := addLearnedExample(example); err != nil {
+		logger.Errorf("[+] Error adding the learned example to the index of api id %s: %s", example.APIID, err)
+	}
+
+	writeJSONResponse(rw, http.StatusCreated, feedbackResponse{Hit: example.OperationID == example.SelectedOperationID, Example: example})
+}
+
+// processListLearnedExamples is the admin view of the learned examples of an API.
+func processListLearnedExamples(rw http.ResponseWriter, r *http.Request) {
+	writeJSONResponse(rw, http.StatusOK, getLearnedExamples(mux.Vars(r)["apiId"]))
+}
+
+// processDeleteLearnedExample prunes a wrong learned example.
+func processDeleteLearnedExample(rw http.ResponseWriter, r *http.Request) {
+	apiId, id := mux.Vars(r)["apiId"], mux.Vars(r)["id"]
+	if agentBridgeStore == nil || !agentBridgeStore.DeleteKey(getLearnedExampleKey(apiId, id)) {
+		http.Error(rw, "Unknown learned example", http.StatusNotFound)
+		return
+	}
+	if err := reloadLearnedExamples(apiId); err != nil {
+		logger.Errorf("[+] Error reloading the learned examples of api id %s: %s", apiId, err)
+	}
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSONResponse(rw http.ResponseWriter, statusCode int, response any) {
+	jsonResponse, err := json.Marshal(response)
+	if err != nil {
+		logger.Errorf("[+] Error while marshalling the response: %s", err)
+		http.Error(rw, INTERNAL_ERROR_MSG, http.StatusInternalServerError)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(statusCode)
+	_, _ = rw.Write(jsonResponse)
+}
@@ -0,0 +1,135 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// keywordEmbedder embeds the texts on one dimension per keyword.
+type keywordEmbedder struct {
+	keywords []string
+}
+
+func (e *keywordEmbedder) Name() string {
+	return "keywords:" + strings.Join(e.keywords, ",")
+}
+
+func (e *keywordEmbedder) EmbedText(ctx context.Context, text string) ([]float32, error) {
+	embedding := make([]float32, len(e.keywords)+1)
+	embedding[len(e.keywords)] = 0.1
+	for i, keyword := range e.keywords {
+		if strings.Contains(text, keyword) {
+			embedding[i] = 1
+		}
+	}
+	return embedding, nil
+}
+
+func TestFeedbackLearnedExamples(t *testing.T) {
+	const apiId = "feedback-test"
+	embedder := &keywordEmbedder{keywords: []string{"release", "tag"}}
+	pluginDataConfig := &PluginDataConfig{
+		APIID:                apiId,
+		SelectModelEmbedding: embedder.Name(),
+		SelectOperations: map[string]*AIExtensionConfig{
+			"getRelease": {InputExamples: []string{"show me the release"}},
+			"getTag":     {InputExamples: []string{"show me the tag"}},
+		},
+	}
+	embeddingModelsLock.Lock()
+	embeddingModels[embedder.Name()] = embedder
+	embeddingModelsLock.Unlock()
+	pluginConfigLock.Lock()
+	pluginConfig[apiId] = pluginDataConfig
+	pluginConfigLock.Unlock()
+	t.Cleanup(func() {
+		for _, example := range getLearnedExamples(apiId) {
+			agentBridgeStore.DeleteKey(getLearnedExampleKey(apiId, example.ID))
+		}
+		deletePluginConfig(apiId)
+		embeddingModelsLock.Lock()
+		delete(embeddingModels, embedder.Name())
+		embeddingModelsLock.Unlock()
+	})
+
+	assert.Nil(t, reloadLearnedExamples(apiId))
+	apiSpecIndicesLock.RLock()
+	apiSpecIndex := apiSpecIndices[apiId]
+	apiSpecIndicesLock.RUnlock()
+
+	requestID, err := saveOperationSelection(apiId, "show me v1.2", "getRelease", 0.6)
+	assert.Nil(t, err)
+
+	sendFeedback := func(feedback string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		processFeedback(rw, httptest.NewRequest(http.MethodPost, "/api-bridge-agent/feedback", strings.NewReader(feedback)))
+		return rw
+	}
+	assert.Equal(t, http.StatusBadRequest, sendFeedback(`{"request_id": "`+requestID+`"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, sendFeedback(`{"request_id": "`+requestID+`", "query": "another query", "operation_id": "getTag"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, sendFeedback(`{"request_id": "`+requestID+`", "query": "show me v1.2", "operation_id": "deleteTag"}`).Code)
+	assert.Equal(t, http.StatusNotFound, sendFeedback(`{"request_id": "unknown", "query": "show me v1.2", "operation_id": "getTag"}`).Code)
+
+	// The selected operation was the wrong one
+	rw := sendFeedback(`{"request_id": "` + requestID + `", "query": "show me v1.2", "operation_id": "getTag"}`)
+	assert.Equal(t, http.StatusCreated, rw.Code)
+	response := feedbackResponse{}
+	assert.Nil(t, json.Unmarshal(rw.Body.Bytes(), &response))
+	assert.False(t, response.Hit)
+	assert.Equal(t, "getTag", response.Example.OperationID)
+	assert.Equal(t, "getRelease", response.Example.SelectedOperationID)
+
+	// Only one feedback per request
+	rw = sendFeedback(`{"request_id": "` + requestID + `", "query": "show me v1.2", "operation_id": "getTag"}`)
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+
+	// The learned example is added to the index of the API
+	apiSpecIndicesLock.RLock()
+	assert.Same(t, apiSpecIndex, apiSpecIndices[apiId])
+	apiSpecIndicesLock.RUnlock()
+	assert.Equal(t, 3, apiSpecIndex.Len())
+	query, _ := embedder.EmbedText(context.TODO(), "show me v1.2")
+	results := apiSpecIndex.Search(query, 1)
+	assert.Equal(t, "getTag", results[0].Value)
+	assert.InDelta(t, 1.0, results[0].Relevance, 1e-6)
+
+	// The admin view lists and prunes the learned examples
+	list := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api-bridge-agent/feedback/"+apiId, nil), map[string]string{"apiId": apiId})
+	rw = httptest.NewRecorder()
+	processListLearnedExamples(rw, list)
+	assert.Equal(t, http.StatusOK, rw.Code)
+	examples := []learnedExample{}
+	assert.Nil(t, json.Unmarshal(rw.Body.Bytes(), &examples))
+	assert.Equal(t, []learnedExample{response.Example}, examples)
+
+	remove := mux.SetURLVars(httptest.NewRequest(http.MethodDelete, "/api-bridge-agent/feedback/"+apiId+"/"+requestID, nil), map[string]string{"apiId": apiId, "id": requestID})
+	rw = httptest.NewRecorder()
+	processDeleteLearnedExample(rw, remove)
+	assert.Equal(t, http.StatusNoContent, rw.Code)
+	assert.Empty(t, getLearnedExamples(apiId))
+
+	apiSpecIndicesLock.RLock()
+	assert.Equal(t, 2, apiSpecIndices[apiId].Len())
+	apiSpecIndicesLock.RUnlock()
+
+	rw = httptest.NewRecorder()
+	processDeleteLearnedExample(rw, remove)
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+
+	// The operation of an API not loaded on this node can't be checked
+	requestID, err = saveOperationSelection("feedback-unloaded", "show me v1.2", "getRelease", 0.6)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, sendFeedback(`{"request_id": "`+requestID+`", "query": "show me v1.2", "operation_id": "anything"}`).Code)
+	assert.Empty(t, getLearnedExamples("feedback-unloaded"))
+	agentBridgeStore.DeleteKey(SELECTION_KEY_PREFIX + requestID)
+}
@@ -0,0 +1,221 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/TykTechnologies/tyk/apidef/oas"
+)
+
+const (
+	DEFAULT_LEXICAL_WEIGHT = 0.3
+
+	BM25_K1 = 1.2
+	BM25_B  = 0.75
+
+	LEXICAL_SCORE_SATURATION = 4.0 // BM25 score counting for half of the lexical weight
+)
+
+// stopwords are not indexed, they match almost every example and query
+var stopwords = map[string]bool{
+	"a": true, "about": true, "all": true, "an": true, "and": true, "any": true, "are": true, "as": true,
+	"at": true, "be": true, "by": true, "can": true, "could": true, "do": true, "does": true, "for": true,
+	"from": true, "have": true, "how": true, "i": true, "in": true, "is": true, "it": true, "its": true,
+	"me": true, "my": true, "of": true, "on": true, "or": true, "our": true, "please": true, "that": true,
+	"the": true, "their": true, "them": true, "these": true, "this": true, "those": true, "to": true,
+	"us": true, "was": true, "we": true, "what": true, "which": true, "with": true, "would": true,
+	"you": true, "your": true,
+}
+
+// lexicalIndex is a BM25 index with one document per operation. It finds the
+// exact identifiers (operationIds, path segments, tags) that the embeddings miss.
+type lexicalIndex struct {
+	documents       map[string]map[string]int // operationId -> term -> frequency
+	lengths         map[string]int            // operationId -> number of terms
+	documentFreq    map[string]int            // term -> number of operations using it
+	totalTermsCount int
+}
+
+type lexicalResult struct {
+	Value string
+	Score float64
+}
+
+func newLexicalIndex() *lexicalIndex {
+	return &lexicalIndex{
+		documents:    map[string]map[string]int{},
+		lengths:      map[string]int{},
+		documentFreq: map[string]int{},
+	}
+}
+
+// newOperationsLexicalIndex indexes the operationId and the examples of every
+// operation, and its path, tags and summary when the API definition is known.
+// The operations without examples, only negative ones, are not indexed.
+func newOperationsLexicalIndex(apiDef *oas.OAS, selectOperations map[string]*AIExtensionConfig) *lexicalIndex {
+	index := newLexicalIndex()
+	for operationID, aiExtension := range selectOperations {
+		if len(aiExtension.InputExamples) == 0 {
+			continue
+		}
+		index.Add(operationID, operationID)
+		for _, example := range aiExtension.InputExamples {
+			index.Add(operationID, example)
+		}
+	}
+	if apiDef == nil {
+		return index
+	}
+
+	for path, pathItem := range apiDef.Paths {
+		for _, operation := range pathItem.Operations() {
+			if aiExtension, present := selectOperations[operation.OperationID]; !present || len(aiExtension.InputExamples) == 0 {
+				continue
+			}
+			summary, description := getOperationDescriptions(operation)
+			if summary == "" {
+				summary = description
+			}
+			index.Add(operation.OperationID, path)
+			index.Add(operation.OperationID, summary)
+			for _, tag := range operation.Tags {
+				index.Add(operation.OperationID, tag)
+			}
+		}
+	}
+	return index
+}
+
+// Add appends the text to the document of the operation.
+func (index *lexicalIndex) Add(operationID string, text string) {
+	document, present := index.documents[operationID]
+	if !present {
+		document = map[string]int{}
+		index.documents[operationID] = document
+	}
+	for _, term := range tokenize(text) {
+		if document[term] == 0 {
+			index.documentFreq[term]++
+		}
+		document[term]++
+		index.lengths[operationID]++
+		index.totalTermsCount++
+	}
+}
+
+// Search returns the operations sharing at least one term with the query, the
+// best BM25 score first.
+func (index *lexicalIndex) Search(query string, maxResults int) []lexicalResult {
+	if len(index.documents) == 0 {
+		return []lexicalResult{}
+	}
+
+	documentsCount := float64(len(index.documents))
+	averageLength := float64(index.totalTermsCount) / documentsCount
+	results := []lexicalResult{}
+	terms := tokenize(query)
+	for operationID, document := range index.documents {
+		score := 0.0
+		for _, term := range terms {
+			frequency := float64(document[term])
+			if frequency == 0 {
+				continue
+			}
+			documentFreq := float64(index.documentFreq[term])
+			idf := math.Log(1 + (documentsCount-documentFreq+0.5)/(documentFreq+0.5))
+			length := float64(index.lengths[operationID])
+			score += idf * frequency * (BM25_K1 + 1) / (frequency + BM25_K1*(1-BM25_B+BM25_B*length/averageLength))
+		}
+		if score > 0 {
+			results = append(results, lexicalResult{Value: operationID, Score: score})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score == results[j].Score {
+			return results[i].Value < results[j].Value
+		}
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > maxResults {
+		results = results[:maxResults]
+	}
+	return results
+}
+
+// tokenize splits the text on anything but letters and digits, without the
+// stopwords. camelCase words are also split, so "listReleases" matches "list
+// the releases".
+func tokenize(text string) []string {
+	terms := []string{}
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		parts := splitCamelCase(word)
+		if len(parts) > 1 {
+			terms = append(terms, strings.ToLower(word))
+		}
+		for _, part := range parts {
+			term := strings.ToLower(part)
+			if !stopwords[term] {
+				terms = append(terms, term)
+			}
+		}
+	}
+	return terms
+}
+
+func splitCamelCase(word string) []string {
+	parts := []string{}
+	runes := []rune(word)
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		if unicode.IsUpper(runes[i]) && unicode.IsLower(runes[i-1]) {
+			parts = append(parts, string(runes[start:i]))
+			start = i
+		}
+	}
+	return append(parts, string(runes[start:]))
+}
+
+// fuseOperationScores ranks the candidates on the relevance of the embeddings
+// combined with the lexical score, saturated so a weak match stays weak even
+// when it is the best one: (1 - lexicalWeight) * relevance + lexicalWeight * bm25 / (bm25 + saturation)
+// The relevance is kept for the RelevanceThreshold; it is 0 for the operations
+// found by the lexical index only.
+func fuseOperationScores(semantic []OperationCandidate, lexical []lexicalResult, lexicalWeight float64) []OperationCandidate {
+	candidates := map[string]*OperationCandidate{}
+	for _, candidate := range semantic {
+		candidates[candidate.OperationID] = &OperationCandidate{
+			OperationID: candidate.OperationID,
+			Relevance:   candidate.Relevance,
+			Score:       (1 - lexicalWeight) * candidate.Relevance,
+		}
+	}
+	for _, result := range lexical {
+		candidate, present := candidates[result.Value]
+		if !present {
+			candidate = &OperationCandidate{OperationID: result.Value}
+			candidates[result.Value] = candidate
+		}
+		candidate.Score += lexicalWeight * result.Score / (result.Score + LEXICAL_SCORE_SATURATION)
+	}
+
+	fused := make([]OperationCandidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		fused = append(fused, *candidate)
+	}
+	sort.Slice(fused, func(i, j int) bool {
+		if fused[i].Score == fused[j].Score {
+			return fused[i].OperationID < fused[j].OperationID
+		}
+		return fused[i].Score > fused[j].Score
+	})
+	return fused
+}
@@ -0,0 +1,101 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"testing"
+
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+	"github.com/TykTechnologies/tyk/apidef/oas"
+	"github.com/stretchr/testify/assert"
+)
+
+const lexicalIndexTestSpec = `{
+	"openapi": "3.0.0",
+	"info": {"title": "Gists", "version": "1.0.0"},
+	"paths": {
+		"/gists": {
+			"get": {"operationId": "gists/list", "summary": "List gists for the authenticated user", "tags": ["gists"], "responses": {"200": {"description": "OK"}}}
+		},
+		"/repos/{owner}/{repo}/releases": {
+			"get": {"operationId": "listReleases", "summary": "List releases", "tags": ["repos"], "responses": {"200": {"description": "OK"}}}
+		},
+		"/repos/{owner}/{repo}/tags": {
+			"get": {"operationId": "repos/list-tags", "summary": "List repository tags", "tags": ["repos"], "responses": {"200": {"description": "OK"}}}
+		},
+		"/repos/{owner}/{repo}/forks": {
+			"post": {"operationId": "createFork", "summary": "Create a fork", "tags": ["repos"], "responses": {"202": {"description": "Accepted"}}}
+		}
+	}
+}`
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"listreleases", "list", "releases"}, tokenize("listReleases"))
+	assert.Equal(t, []string{"repos", "owner", "repo", "tags"}, tokenize("/repos/{owner}/{repo}/tags"))
+	assert.Equal(t, []string{"show", "gists", "octocat"}, tokenize("Show the /gists of 'octocat'"))
+	assert.Equal(t, []string{"releases"}, tokenize("What are the releases of this"))
+}
+
+func TestLexicalIndexSearch(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(lexicalIndexTestSpec))
+	assert.Nil(t, err)
+	selectOperations := map[string]*AIExtensionConfig{
+		"gists/list":      {InputExamples: []string{"Show my snippets"}},
+		"listReleases":    {InputExamples: []string{"What are the versions published for this project"}},
+		"repos/list-tags": {InputExamples: []string{"Give me the tags of this project"}},
+		"createFork":      {NegativeExamples: []string{"list the forks"}}, // Not indexed
+	}
+	index := newOperationsLexicalIndex(&oas.OAS{T: *doc}, selectOperations)
+
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"call /gists", "gists/list"},                               // Path segment
+		{"listReleases for agntcy/api-bridge-agnt", "listReleases"}, // operationId
+		{"the repos tags", "repos/list-tags"},                       // Tag and summary
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			results := index.Search(tt.query, 3)
+			assert.NotEmpty(t, results)
+			assert.Equal(t, tt.expected, results[0].Value)
+		})
+	}
+
+	assert.Empty(t, index.Search("weather forecast", 3))
+	assert.Empty(t, index.Search("createFork forks", 3))
+	assert.Len(t, index.Search("list", 1), 1)
+}
+
+func TestFuseOperationScores(t *testing.T) {
+	semantic := []OperationCandidate{
+		{OperationID: "listReleases", Relevance: 0.62},
+		{OperationID: "repos/list-tags", Relevance: 0.60},
+	}
+	lexical := []lexicalResult{
+		{Value: "repos/list-tags", Score: 4.0},
+		{Value: "gists/list", Score: 1.0},
+	}
+
+	fused := fuseOperationScores(semantic, lexical, 0.3)
+	assert.Equal(t, []string{"repos/list-tags", "listReleases", "gists/list"}, []string{fused[0].OperationID, fused[1].OperationID, fused[2].OperationID})
+	assert.InDelta(t, 0.7*0.60+0.3*0.5, fused[0].Score, 1e-9)
+	assert.InDelta(t, 0.7*0.62, fused[1].Score, 1e-9)
+	assert.InDelta(t, 0.3*0.2, fused[2].Score, 1e-9)
+	// The relevance of the embeddings is kept for the threshold
+	assert.InDelta(t, 0.60, fused[0].Relevance, 1e-9)
+	assert.InDelta(t, 0.62, fused[1].Relevance, 1e-9)
+	assert.Zero(t, fused[2].Relevance)
+
+	// A weak lexical match doesn't get the full weight for being the best one
+	fused = fuseOperationScores(semantic, []lexicalResult{{Value: "listReleases", Score: 0.5}, {Value: "repos/list-tags", Score: 0.4}}, 0.3)
+	assert.Equal(t, "listReleases", fused[0].OperationID)
+	assert.InDelta(t, 0.7*0.62+0.3*0.5/4.5, fused[0].Score, 1e-9)
+
+	// Without lexical weight, the relevance of the embeddings is kept
+	fused = fuseOperationScores(semantic, lexical, 0)
+	assert.Equal(t, "listReleases", fused[0].OperationID)
+	assert.InDelta(t, 0.62, fused[0].Score, 1e-9)
+}
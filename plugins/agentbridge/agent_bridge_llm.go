@@ -0,0 +1,130 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	LLM_PROVIDER_OPENAI  = "openai" // OpenAI or Azure OpenAI, configured with azureConfig
+	DEFAULT_LLM_PROVIDER = LLM_PROVIDER_OPENAI
+)
+
+const (
+	LLM_ROLE_SYSTEM    = "system"
+	LLM_ROLE_USER      = "user"
+	LLM_ROLE_ASSISTANT = "assistant"
+	LLM_ROLE_TOOL      = "tool"
+)
+
+type JsonSchemaResponse struct {
+	Name        string
+	Description string
+	Schema      []byte
+}
+
+// LLMToolCall is a tool invocation requested by the model.
+type LLMToolCall struct {
+	ID        string
+	Name      string
+	Arguments string // JSON encoded arguments
+}
+
+// LLMMessage is a provider agnostic chat message.
+type LLMMessage struct {
+	Role       string
+	Content    string
+	ToolCalls  []LLMToolCall // Only for assistant messages
+	ToolCallID string        // Only for tool messages
+}
+
+// LLMTool describes a tool the model is allowed to call.
+type LLMTool struct {
+	Name        string
+	Description string
+	Parameters  []byte // JSON schema of the arguments
+}
+
+// LLMToolResponse is the outcome of one tool calling round.
+type LLMToolResponse struct {
+	Content   string
+	ToolCalls []LLMToolCall
+	Done      bool // The model stopped, Content is the final answer
+}
+
+// LLMProvider is implemented by every LLM backend used by the plugin.
+type LLMProvider interface {
+	// ChatCompletion sends a system and a user prompt. When schemaResponse is
+	// not nil, the answer must be a JSON document following the schema.
+	ChatCompletion(ctx context.Context, systemPrompt string, userPrompt string, schemaResponse *JsonSchemaResponse) (string, error)
+
+	// ChatWithTools runs one round of a conversation where the model can
+	// either answer or ask for some tools to be called.
+	ChatWithTools(ctx context.Context, messages []LLMMessage, tools []LLMTool) (*LLMToolResponse, error)
+}
+
+// LLMProviderFactory creates a provider from the pluginConfig.data of an API.
+type LLMProviderFactory func(configData map[string]any) (LLMProvider, error)
+
+var llmProviders = map[string]LLMProviderFactory{
+	LLM_PROVIDER_OPENAI:    newOpenAIProviderFromConfigData,
+	LLM_PROVIDER_ANTHROPIC: newAnthropicProviderFromConfigData,
+}
+var llmProvidersLock = &sync.RWMutex{}
+
+// RegisterLLMProvider makes a provider available to the "llmProvider"
+// configuration. Registering an existing name replaces it.
+func RegisterLLMProvider(name string, factory LLMProviderFactory) {
+	llmProvidersLock.Lock()
+	defer llmProvidersLock.Unlock()
+
+	llmProviders[name] = factory
+}
+
+func getLLMProviderNames() []string {
+	llmProvidersLock.RLock()
+	defer llmProvidersLock.RUnlock()
+
+	names := make([]string, 0, len(llmProviders))
+	for name := range llmProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newLLMProvider(name string, configData map[string]any) (LLMProvider, error) {
+	if name == "" {
+		name = DEFAULT_LLM_PROVIDER
+	}
+
+	llmProvidersLock.RLock()
+	factory, exists := llmProviders[name]
+	llmProvidersLock.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("unknown LLM provider '%s', available providers are %v", name, getLLMProviderNames())
+	}
+
+	return factory(configData)
+}
+
+func mcpToolToLLMTool(tool mcp.Tool) (LLMTool, error) {
+	jsonBytes, err := json.Marshal(tool.InputSchema)
+	if err != nil {
+		return LLMTool{}, fmt.Errorf("failed to marshal parameters of tool (%s): %w", tool.Name, err)
+	}
+
+	return LLMTool{
+		Name:        tool.Name,
+		Description: tool.Description,
+		Parameters:  jsonBytes,
+	}, nil
+}
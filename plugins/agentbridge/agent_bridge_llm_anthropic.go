@@ -0,0 +1,289 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	LLM_PROVIDER_ANTHROPIC = "anthropic"
+
+	DEFAULT_ANTHROPIC_ENDPOINT = "https://api.anthropic.com"
+	DEFAULT_ANTHROPIC_MODEL    = "claude-3-5-haiku-latest"
+	ANTHROPIC_API_VERSION      = "2023-06-01"
+	ANTHROPIC_HTTP_TIMEOUT     = 120 * time.Second
+
+	ANTHROPIC_STOP_REASON_TOOL_USE = "tool_use"
+)
+
+type AnthropicConfig struct {
+	APIKey   string `json:"apiKey"`
+	Endpoint string `json:"endpoint"`
+	Model    string `json:"model"`
+}
+
+// anthropicProvider talks to the Anthropic Messages API.
+type anthropicProvider struct {
+	config     AnthropicConfig
+	httpClient *http.Client
+}
+
+// Messages API payloads, only the fields used by the plugin are declared.
+type anthropicContentBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string                  `json:"role"`
+	Content []anthropicContentBlock `json:"content"`
+}
+
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+type anthropicRequest struct {
+	Model       string               `json:"model"`
+	MaxTokens   int                  `json:"max_tokens"`
+	System      string               `json:"system,omitempty"`
+	Messages    []anthropicMessage   `json:"messages"`
+	Tools       []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice  *anthropicToolChoice `json:"tool_choice,omitempty"`
+	Temperature float64              `json:"temperature"`
+}
+
+type anthropicResponse struct {
+	Content    []anthropicContentBlock `json:"content"`
+	StopReason string                  `json:"stop_reason"`
+}
+
+type anthropicError struct {
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func parseAnthropicConfig(configData map[string]any) AnthropicConfig {
+	anthropicConfigData, exists := configData["anthropicConfig"].(map[string]any)
+	if !exists {
+		anthropicConfigData = map[string]any{}
+	}
+
+	return AnthropicConfig{
+		APIKey:   getConfigValue("", anthropicConfigData, "apiKey", "ANTHROPIC_API_KEY"),
+		Endpoint: getConfigValue(DEFAULT_ANTHROPIC_ENDPOINT, anthropicConfigData, "endpoint", "ANTHROPIC_ENDPOINT"),
+		Model:    getConfigValue(DEFAULT_ANTHROPIC_MODEL, anthropicConfigData, "model", "ANTHROPIC_MODEL"),
+	}
+}
+
+func newAnthropicProviderFromConfigData(configData map[string]any) (LLMProvider, error) {
+	return newAnthropicProvider(parseAnthropicConfig(configData))
+}
+
+func newAnthropicProvider(config AnthropicConfig) (LLMProvider, error) {
+	if config.APIKey == "" {
+		return nil, fmt.Errorf("missing required config for anthropicConfig.apiKey")
+	}
+
+	return &anthropicProvider{
+		config:     config,
+		httpClient: &http.Client{Timeout: ANTHROPIC_HTTP_TIMEOUT},
+	}, nil
+}
+
+// ChatCompletion uses a forced tool call to obtain structured output: the
+// schema becomes the input schema of a single tool the model must call.
+func (p *anthropicProvider) ChatCompletion(ctx context.Context, systemPrompt string, userPrompt string, schemaResponse *JsonSchemaResponse) (string, error) {
+	request := anthropicRequest{
+		Model:     p.config.Model,
+		MaxTokens: DEFAULT_LLM_MAX_TOKENS,
+		System:    systemPrompt,
+		Messages: []anthropicMessage{
+			{Role: LLM_ROLE_USER, Content: []anthropicContentBlock{{Type: "text", Text: userPrompt}}},
+		},
+		Temperature: DEFAULT_LLM_TEMPERATURE,
+	}
+
+	if schemaResponse != nil {
+		request.Tools = []anthropicTool{{
+			Name:        schemaResponse.Name,
+			Description: schemaResponse.Description,
+			InputSchema: schemaResponse.Schema,
+		}}
+		request.ToolChoice = &anthropicToolChoice{Type: "tool", Name: schemaResponse.Name}
+	}
+
+	resp, err := p.sendMessages(ctx, request)
+	if err != nil {
+		return "", err
+	}
+
+	if schemaResponse != nil {
+		for _, block := range resp.Content {
+			if block.Type == "tool_use" && block.Name == schemaResponse.Name {
+				return string(block.Input), nil
+			}
+		}
+		return "", fmt.Errorf("unable to get a structured response from the LLM")
+	}
+
+	text := anthropicText(resp.Content)
+	if text == "" {
+		return "", fmt.Errorf("unable to get a response from the LLM")
+	}
+	return text, nil
+}
+
+func (p *anthropicProvider) ChatWithTools(ctx context.Context, messages []LLMMessage, tools []LLMTool) (*LLMToolResponse, error) {
+	request := anthropicRequest{
+		Model:       p.config.Model,
+		MaxTokens:   DEFAULT_LLM_MAX_TOKENS,
+		Temperature: DEFAULT_LLM_TEMPERATURE,
+	}
+
+	for _, message := range messages {
+		switch message.Role {
+		case LLM_ROLE_SYSTEM:
+			request.System = strings.TrimSpace(request.System + "\n" + message.Content)
+		case LLM_ROLE_USER:
+			request.Messages = appendAnthropicBlock(request.Messages, LLM_ROLE_USER, anthropicContentBlock{Type: "text", Text: message.Content})
+		case LLM_ROLE_ASSISTANT:
+			if message.Content != "" {
+				request.Messages = appendAnthropicBlock(request.Messages, LLM_ROLE_ASSISTANT, anthropicContentBlock{Type: "text", Text: message.Content})
+			}
+			for _, toolCall := range message.ToolCalls {
+				input := json.RawMessage(toolCall.Arguments)
+				if !json.Valid(input) {
+					input = json.RawMessage("{}")
+				}
+				request.Messages = appendAnthropicBlock(request.Messages, LLM_ROLE_ASSISTANT, anthropicContentBlock{
+					Type:  "tool_use",
+					ID:    toolCall.ID,
+					Name:  toolCall.Name,
+					Input: input,
+				})
+			}
+		case LLM_ROLE_TOOL:
+			// Tool results are sent back as user content blocks
+			request.Messages = appendAnthropicBlock(request.Messages, LLM_ROLE_USER, anthropicContentBlock{
+				Type:      "tool_result",
+				ToolUseID: message.ToolCallID,
+				Content:   message.Content,
+			})
+		default:
+			return nil, fmt.Errorf("unknown message role '%s'", message.Role)
+		}
+	}
+
+	for _, tool := range tools {
+		request.Tools = append(request.Tools, anthropicTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: tool.Parameters,
+		})
+	}
+
+	resp, err := p.sendMessages(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &LLMToolResponse{Content: anthropicText(resp.Content)}
+	for _, block := range resp.Content {
+		if block.Type == "tool_use" {
+			response.ToolCalls = append(response.ToolCalls, LLMToolCall{
+				ID:        block.ID,
+				Name:      block.Name,
+				Arguments: string(block.Input),
+			})
+		}
+	}
+	response.Done = resp.StopReason != ANTHROPIC_STOP_REASON_TOOL_USE
+
+	return response, nil
+}
+
+func (p *anthropicProvider) sendMessages(ctx context.Context, request anthropicRequest) (*anthropicResponse, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal the Anthropic request: %w", err)
+	}
+
+	endpoint := strings.TrimSuffix(p.config.Endpoint, "/") + "/v1/messages"
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpRequest.Header.Set("X-Api-Key", p.config.APIKey)
+	httpRequest.Header.Set("Anthropic-Version", ANTHROPIC_API_VERSION)
+
+	httpResponse, err := p.httpClient.Do(httpRequest)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+
+	responseBody, err := io.ReadAll(httpResponse.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if httpResponse.StatusCode != http.StatusOK {
+		apiError := anthropicError{}
+		if err := json.Unmarshal(responseBody, &apiError); err == nil && apiError.Error.Message != "" {
+			return nil, fmt.Errorf("anthropic API error (%d): %s: %s", httpResponse.StatusCode, apiError.Error.Type, apiError.Error.Message)
+		}
+		return nil, fmt.Errorf("anthropic API error (%d)", httpResponse.StatusCode)
+	}
+
+	response := &anthropicResponse{}
+	if err := json.Unmarshal(responseBody, response); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal the Anthropic response: %w", err)
+	}
+
+	return response, nil
+}
+
+// appendAnthropicBlock adds a block to the last message when it has the same
+// role, the Messages API expects user and assistant turns to alternate.
+func appendAnthropicBlock(messages []anthropicMessage, role string, block anthropicContentBlock) []anthropicMessage {
+	if len(messages) > 0 && messages[len(messages)-1].Role == role {
+		messages[len(messages)-1].Content = append(messages[len(messages)-1].Content, block)
+		return messages
+	}
+	return append(messages, anthropicMessage{Role: role, Content: []anthropicContentBlock{block}})
+}
+
+func anthropicText(blocks []anthropicContentBlock) string {
+	var sb strings.Builder
+	for _, block := range blocks {
+		if block.Type == "text" {
+			sb.WriteString(block.Text)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,140 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+// newAnthropicStub starts a fake Messages API replaying the given responses
+// and recording the requests it received.
+func newAnthropicStub(t *testing.T, responses []string, requests *[]anthropicRequest) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/messages", r.URL.Path)
+		assert.Equal(t, "test-key", r.Header.Get("X-Api-Key"))
+		assert.Equal(t, ANTHROPIC_API_VERSION, r.Header.Get("Anthropic-Version"))
+
+		body, err := io.ReadAll(r.Body)
+		assert.Nil(t, err)
+		request := anthropicRequest{}
+		assert.Nil(t, json.Unmarshal(body, &request))
+		*requests = append(*requests, request)
+
+		if len(responses) == 0 {
+			http.Error(rw, `{"error":{"type":"invalid_request_error","message":"no more responses"}}`, http.StatusBadRequest)
+			return
+		}
+		_, _ = rw.Write([]byte(responses[0]))
+		responses = responses[1:]
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAnthropicChatCompletionStructured(t *testing.T) {
+	requests := []anthropicRequest{}
+	server := newAnthropicStub(t, []string{`{
+		"content": [{"type": "tool_use", "id": "toolu_1", "name": "convert_to_openapi", "input": {"in_path_params": {"id": "250"}}}],
+		"stop_reason": "tool_use"
+	}`}, &requests)
+
+	provider, err := newAnthropicProvider(AnthropicConfig{APIKey: "test-key", Endpoint: server.URL, Model: "claude-test"})
+	assert.Nil(t, err)
+
+	structuredResponse, err := buildStructuredOASResponse(&openapi3.Operation{})
+	assert.Nil(t, err)
+	schema := JsonSchemaResponse{Name: "convert_to_openapi", Schema: structuredResponse}
+	resp, err := provider.ChatCompletion(context.TODO(), "system prompt", "user prompt", &schema)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"in_path_params": {"id": "250"}}`, resp)
+
+	assert.Len(t, requests, 1)
+	assert.Equal(t, "claude-test", requests[0].Model)
+	assert.Equal(t, "system prompt", requests[0].System)
+	assert.Equal(t, &anthropicToolChoice{Type: "tool", Name: "convert_to_openapi"}, requests[0].ToolChoice)
+	assert.Len(t, requests[0].Tools, 1)
+	assert.JSONEq(t, string(structuredResponse), string(requests[0].Tools[0].InputSchema))
+}
+
+func TestAnthropicChatCompletionText(t *testing.T) {
+	requests := []anthropicRequest{}
+	server := newAnthropicStub(t, []string{`{
+		"content": [{"type": "text", "text": "The release was deleted."}],
+		"stop_reason": "end_turn"
+	}`}, &requests)
+
+	provider, err := newAnthropicProvider(AnthropicConfig{APIKey: "test-key", Endpoint: server.URL + "/", Model: "claude-test"})
+	assert.Nil(t, err)
+
+	resp, err := provider.ChatCompletion(context.TODO(), "system prompt", "user prompt", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "The release was deleted.", resp)
+	assert.Nil(t, requests[0].ToolChoice)
+	assert.Empty(t, requests[0].Tools)
+}
+
+func TestAnthropicChatWithTools(t *testing.T) {
+	requests := []anthropicRequest{}
+	server := newAnthropicStub(t, []string{`{
+		"content": [
+			{"type": "text", "text": "Let me search."},
+			{"type": "tool_use", "id": "toolu_1", "name": "search", "input": {"query": "agntcy"}}
+		],
+		"stop_reason": "tool_use"
+	}`, `{
+		"content": [{"type": "text", "text": "AGNTCY is an open source collective."}],
+		"stop_reason": "end_turn"
+	}`}, &requests)
+
+	provider, err := newAnthropicProvider(AnthropicConfig{APIKey: "test-key", Endpoint: server.URL, Model: "claude-test"})
+	assert.Nil(t, err)
+
+	tools := []LLMTool{{Name: "search", Description: "Search the web", Parameters: []byte(`{"type":"object","properties":{"query":{"type":"string"}}}`)}}
+	messages := []LLMMessage{{Role: LLM_ROLE_USER, Content: "What is agntcy?"}}
+
+	resp, err := provider.ChatWithTools(context.TODO(), messages, tools)
+	assert.Nil(t, err)
+	assert.False(t, resp.Done)
+	assert.Equal(t, []LLMToolCall{{ID: "toolu_1", Name: "search", Arguments: `{"query": "agntcy"}`}}, resp.ToolCalls)
+
+	messages = append(messages,
+		LLMMessage{Role: LLM_ROLE_ASSISTANT, ToolCalls: resp.ToolCalls},
+		LLMMessage{Role: LLM_ROLE_TOOL, Content: "agntcy.org", ToolCallID: "toolu_1"},
+	)
+	resp, err = provider.ChatWithTools(context.TODO(), messages, tools)
+	assert.Nil(t, err)
+	assert.True(t, resp.Done)
+	assert.Equal(t, "AGNTCY is an open source collective.", resp.Content)
+
+	// The tool result must be sent back in a user turn referencing the tool use
+	assert.Len(t, requests, 2)
+	assert.Len(t, requests[1].Messages, 3)
+	assert.Equal(t, LLM_ROLE_ASSISTANT, requests[1].Messages[1].Role)
+	assert.Equal(t, "tool_use", requests[1].Messages[1].Content[0].Type)
+	assert.Equal(t, LLM_ROLE_USER, requests[1].Messages[2].Role)
+	assert.Equal(t, anthropicContentBlock{Type: "tool_result", ToolUseID: "toolu_1", Content: "agntcy.org"}, requests[1].Messages[2].Content[0])
+	assert.Equal(t, "search", requests[1].Tools[0].Name)
+}
+
+func TestAnthropicAPIError(t *testing.T) {
+	requests := []anthropicRequest{}
+	server := newAnthropicStub(t, []string{}, &requests)
+
+	provider, err := newAnthropicProvider(AnthropicConfig{APIKey: "test-key", Endpoint: server.URL, Model: "claude-test"})
+	assert.Nil(t, err)
+
+	_, err = provider.ChatCompletion(context.TODO(), "system prompt", "user prompt", nil)
+	assert.ErrorContains(t, err, "no more responses")
+
+	_, err = newAnthropicProvider(AnthropicConfig{Endpoint: server.URL})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,240 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/ai/azopenai"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+)
+
+const (
+	DEFAULT_LLM_MAX_TOKENS = 2048
+)
+
+const (
+	OPENAI_PROVIDER_OPENAI            = "openai"            // api.openai.com
+	OPENAI_PROVIDER_AZURE             = "azure"             // Azure OpenAI deployment
+	OPENAI_PROVIDER_OPENAI_COMPATIBLE = "openai-compatible" // Ollama, llama.cpp server, vLLM, ...
+)
+
+// openAIProvider talks to OpenAI or Azure OpenAI through the azopenai client.
+type openAIProvider struct {
+	config AzureConfig
+	client *azopenai.Client
+}
+
+func newOpenAIProviderFromConfigData(configData map[string]any) (LLMProvider, error) {
+	return newOpenAIProvider(parseAzureConfig(configData))
+}
+
+func newOpenAIProvider(config AzureConfig) (LLMProvider, error) {
+	if config.Provider == "" {
+		config.Provider = getDefaultOpenAIProvider(config.OpenAIEndpoint)
+	}
+
+	// A nil credential means that no Authorization header is sent at all
+	var keyCredential *azcore.KeyCredential
+	if !config.NoAuth {
+		if config.OpenAIKey == "" {
+			return nil, fmt.Errorf("missing required config for azureConfig.openAIKey")
+		}
+		keyCredential = azcore.NewKeyCredential(config.OpenAIKey)
+	}
+
+	options := &azopenai.ClientOptions{}
+	if len(config.Headers) > 0 {
+		options.PerCallPolicies = append(options.PerCallPolicies, &customHeadersPolicy{headers: config.Headers})
+	}
+
+	// Note: eventually cache these by hash of config?
+	var client *azopenai.Client
+	var err error
+	switch config.Provider {
+	case OPENAI_PROVIDER_OPENAI:
+		client, err = azopenai.NewClientForOpenAI(config.OpenAIEndpoint, keyCredential, options)
+	case OPENAI_PROVIDER_AZURE:
+		client, err = azopenai.NewClientWithKeyCredential(config.OpenAIEndpoint, keyCredential, options)
+	case OPENAI_PROVIDER_OPENAI_COMPATIBLE:
+		// On-prem servers are commonly exposed over plain HTTP
+		options.InsecureAllowCredentialWithHTTP = strings.HasPrefix(strings.ToLower(config.OpenAIEndpoint), "http://")
+		client, err = azopenai.NewClientForOpenAI(config.OpenAIEndpoint, keyCredential, options)
+	default:
+		return nil, fmt.Errorf("unknown azureConfig.provider '%s', must be one of %s, %s or %s",
+			config.Provider, OPENAI_PROVIDER_OPENAI, OPENAI_PROVIDER_AZURE, OPENAI_PROVIDER_OPENAI_COMPATIBLE)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to create OpenAI client: %w", err)
+	}
+
+	return &openAIProvider{
+		config: config,
+		client: client,
+	}, nil
+}
+
+func (p *openAIProvider) ChatCompletion(ctx context.Context, systemPrompt string, userPrompt string, schemaResponse *JsonSchemaResponse) (string, error) {
+	chatCompletions := azopenai.ChatCompletionsOptions{
+		Messages: []azopenai.ChatRequestMessageClassification{
+			&azopenai.ChatRequestSystemMessage{
+				Content: azopenai.NewChatRequestSystemMessageContent(systemPrompt),
+			},
+			&azopenai.ChatRequestUserMessage{
+				Content: azopenai.NewChatRequestUserMessageContent(userPrompt),
+			},
+		},
+		MaxTokens:      to.Ptr(int32(DEFAULT_LLM_MAX_TOKENS)),
+		Temperature:    to.Ptr(float32(DEFAULT_LLM_TEMPERATURE)),
+		Seed:           to.Ptr(int64(DEFAULT_LLM_SEED)),
+		DeploymentName: &p.config.ModelDeployment,
+	}
+
+	if schemaResponse != nil {
+		chatCompletions.ResponseFormat = &azopenai.ChatCompletionsJSONSchemaResponseFormat{
+			JSONSchema: &azopenai.ChatCompletionsJSONSchemaResponseFormatJSONSchema{
+				Name:        &schemaResponse.Name,
+				Description: &schemaResponse.Description,
+				Schema:      schemaResponse.Schema,
+				Strict:      to.Ptr(false),
+			},
+		}
+	}
+	resp, err := p.client.GetChatCompletions(ctx, chatCompletions, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if len(resp.Choices) > 0 && resp.Choices[0].Message != nil && resp.Choices[0].Message.Content != nil {
+		return *resp.Choices[0].Message.Content, nil
+	}
+
+	return "", fmt.Errorf("unable to get a response from the LLM")
+}
+
+func (p *openAIProvider) ChatWithTools(ctx context.Context, messages []LLMMessage, tools []LLMTool) (*LLMToolResponse, error) {
+	azMessages := []azopenai.ChatRequestMessageClassification{}
+	for _, message := range messages {
+		azMessage, err := toOpenAIMessage(message)
+		if err != nil {
+			return nil, err
+		}
+		azMessages = append(azMessages, azMessage)
+	}
+
+	azTools := []azopenai.ChatCompletionsToolDefinitionClassification{}
+	for _, tool := range tools {
+		azTools = append(azTools, &azopenai.ChatCompletionsFunctionToolDefinition{
+			Function: &azopenai.ChatCompletionsFunctionToolDefinitionFunction{
+				Name:        to.Ptr(tool.Name),
+				Description: to.Ptr(tool.Description),
+				Parameters:  tool.Parameters,
+			},
+		})
+	}
+
+	resp, err := p.client.GetChatCompletions(ctx, azopenai.ChatCompletionsOptions{
+		DeploymentName: &p.config.ModelDeployment,
+		Messages:       azMessages,
+		Tools:          azTools,
+		Temperature:    to.Ptr[float32](DEFAULT_LLM_TEMPERATURE),
+		Seed:           to.Ptr[int64](DEFAULT_LLM_SEED),
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no choices in LLM response")
+	}
+
+	choice := resp.Choices[0]
+	if choice.FinishReason == nil {
+		return nil, fmt.Errorf("LLM returned a choice with no finish reason")
+	}
+
+	response := &LLMToolResponse{}
+	if choice.Message != nil {
+		if choice.Message.Content != nil {
+			response.Content = *choice.Message.Content
+		}
+		for _, toolCall := range choice.Message.ToolCalls {
+			functionToolCall, ok := toolCall.(*azopenai.ChatCompletionsFunctionToolCall)
+			if !ok || functionToolCall.Function == nil || functionToolCall.Function.Name == nil {
+				logger.Errorf("[+] Unexpected error, something is wrong in the azure-sdk-for-go library, ignoring ...")
+				continue
+			}
+			llmToolCall := LLMToolCall{Name: *functionToolCall.Function.Name}
+			if functionToolCall.ID != nil {
+				llmToolCall.ID = *functionToolCall.ID
+			}
+			if functionToolCall.Function.Arguments != nil {
+				llmToolCall.Arguments = *functionToolCall.Function.Arguments
+			}
+			response.ToolCalls = append(response.ToolCalls, llmToolCall)
+		}
+	}
+
+	if *choice.FinishReason == azopenai.CompletionsFinishReasonStopped {
+		if choice.Message == nil || choice.Message.Content == nil {
+			return nil, fmt.Errorf("no content in the response")
+		}
+		response.Done = true
+	}
+
+	return response, nil
+}
+
+func toOpenAIMessage(message LLMMessage) (azopenai.ChatRequestMessageClassification, error) {
+	switch message.Role {
+	case LLM_ROLE_SYSTEM:
+		return &azopenai.ChatRequestSystemMessage{
+			Content: azopenai.NewChatRequestSystemMessageContent(message.Content),
+		}, nil
+	case LLM_ROLE_USER:
+		return &azopenai.ChatRequestUserMessage{
+			Content: azopenai.NewChatRequestUserMessageContent(message.Content),
+		}, nil
+	case LLM_ROLE_ASSISTANT:
+		assistantMessage := &azopenai.ChatRequestAssistantMessage{}
+		if message.Content != "" {
+			assistantMessage.Content = azopenai.NewChatRequestAssistantMessageContent(message.Content)
+		}
+		for _, toolCall := range message.ToolCalls {
+			assistantMessage.ToolCalls = append(assistantMessage.ToolCalls, &azopenai.ChatCompletionsFunctionToolCall{
+				ID:   to.Ptr(toolCall.ID),
+				Type: to.Ptr("function"),
+				Function: &azopenai.FunctionCall{
+					Name:      to.Ptr(toolCall.Name),
+					Arguments: to.Ptr(toolCall.Arguments),
+				},
+			})
+		}
+		return assistantMessage, nil
+	case LLM_ROLE_TOOL:
+		return &azopenai.ChatRequestToolMessage{
+			Content:    azopenai.NewChatRequestToolMessageContent(message.Content),
+			ToolCallID: to.Ptr(message.ToolCallID),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown message role '%s'", message.Role)
+}
+
+// customHeadersPolicy adds the configured headers to every request.
+type customHeadersPolicy struct {
+	headers map[string]string
+}
+
+func (p *customHeadersPolicy) Do(req *policy.Request) (*http.Response, error) {
+	for name, value := range p.headers {
+		req.Raw().Header.Set(name, value)
+	}
+	return req.Next()
+}
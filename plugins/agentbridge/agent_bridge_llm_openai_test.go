@@ -0,0 +1,103 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newOpenAICompatibleStub starts a fake /chat/completions server, like the
+// ones exposed by Ollama, llama.cpp server or vLLM.
+func newOpenAICompatibleStub(t *testing.T, requests *[]*http.Request, bodies *[]map[string]any) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.Nil(t, err)
+		payload := map[string]any{}
+		assert.Nil(t, json.Unmarshal(body, &payload))
+		*requests = append(*requests, r)
+		*bodies = append(*bodies, payload)
+
+		rw.Header().Set("Content-Type", "application/json")
+		_, _ = rw.Write([]byte(`{
+			"id": "chatcmpl-1",
+			"object": "chat.completion",
+			"created": 1700000000,
+			"model": "llama3.1:8b",
+			"choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": "Hello from a local model"}}]
+		}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOpenAICompatibleProvider(t *testing.T) {
+	tests := []struct {
+		description           string
+		config                AzureConfig
+		expectedAuthorization string
+	}{
+		{
+			"No authentication",
+			AzureConfig{
+				Provider:        OPENAI_PROVIDER_OPENAI_COMPATIBLE,
+				ModelDeployment: "llama3.1:8b",
+				NoAuth:          true,
+				Headers:         map[string]string{"X-Tenant": "agntcy"},
+			},
+			"",
+		},
+		{
+			"API key over plain HTTP",
+			AzureConfig{
+				Provider:        OPENAI_PROVIDER_OPENAI_COMPATIBLE,
+				ModelDeployment: "llama3.1:8b",
+				OpenAIKey:       "local-key",
+				Headers:         map[string]string{"X-Tenant": "agntcy"},
+			},
+			"Bearer local-key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			requests := []*http.Request{}
+			bodies := []map[string]any{}
+			server := newOpenAICompatibleStub(t, &requests, &bodies)
+
+			tt.config.OpenAIEndpoint = server.URL + "/v1"
+			provider, err := newOpenAIProvider(tt.config)
+			assert.Nil(t, err)
+
+			resp, err := provider.ChatCompletion(context.TODO(), "system prompt", "user prompt", nil)
+			assert.Nil(t, err)
+			assert.Equal(t, "Hello from a local model", resp)
+
+			assert.Len(t, requests, 1)
+			assert.Equal(t, "/v1/chat/completions", requests[0].URL.Path)
+			assert.Equal(t, tt.expectedAuthorization, requests[0].Header.Get("Authorization"))
+			assert.Equal(t, "agntcy", requests[0].Header.Get("X-Tenant"))
+			assert.Equal(t, "llama3.1:8b", bodies[0]["model"])
+		})
+	}
+}
+
+func TestOpenAIProviderConfigErrors(t *testing.T) {
+	_, err := newOpenAIProvider(AzureConfig{Provider: OPENAI_PROVIDER_OPENAI_COMPATIBLE, OpenAIEndpoint: "http://localhost:8000/v1"})
+	assert.ErrorContains(t, err, "openAIKey")
+
+	_, err = newOpenAIProvider(AzureConfig{Provider: "unknown", OpenAIKey: "xx", OpenAIEndpoint: "http://localhost:8000/v1"})
+	assert.ErrorContains(t, err, "unknown azureConfig.provider")
+}
+
+func TestGetDefaultOpenAIProvider(t *testing.T) {
+	assert.Equal(t, OPENAI_PROVIDER_OPENAI, getDefaultOpenAIProvider(DEFAULT_OPENAI_ENDPOINT))
+	assert.Equal(t, OPENAI_PROVIDER_AZURE, getDefaultOpenAIProvider("https://tests-agents.openai.azure.com"))
+}
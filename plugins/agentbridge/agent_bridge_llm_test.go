@@ -0,0 +1,112 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeLLMProvider is a deterministic LLMProvider replaying canned answers.
+type fakeLLMProvider struct {
+	completions   []string
+	toolResponses []*LLMToolResponse
+
+	systemPrompts []string
+	userPrompts   []string
+	schemas       []*JsonSchemaResponse
+	messages      [][]LLMMessage
+}
+
+func (p *fakeLLMProvider) ChatCompletion(ctx context.Context, systemPrompt string, userPrompt string, schemaResponse *JsonSchemaResponse) (string, error) {
+	p.systemPrompts = append(p.systemPrompts, systemPrompt)
+	p.userPrompts = append(p.userPrompts, userPrompt)
+	p.schemas = append(p.schemas, schemaResponse)
+	if len(p.completions) == 0 {
+		return "", fmt.Errorf("no more completions")
+	}
+	completion := p.completions[0]
+	p.completions = p.completions[1:]
+	return completion, nil
+}
+
+func (p *fakeLLMProvider) ChatWithTools(ctx context.Context, messages []LLMMessage, tools []LLMTool) (*LLMToolResponse, error) {
+	p.messages = append(p.messages, append([]LLMMessage{}, messages...))
+	if len(p.toolResponses) == 0 {
+		return nil, fmt.Errorf("no more tool responses")
+	}
+	response := p.toolResponses[0]
+	p.toolResponses = p.toolResponses[1:]
+	return response, nil
+}
+
+func TestNewLLMProvider(t *testing.T) {
+	fake := &fakeLLMProvider{}
+	RegisterLLMProvider("fake", func(configData map[string]any) (LLMProvider, error) {
+		return fake, nil
+	})
+	defer func() {
+		llmProvidersLock.Lock()
+		delete(llmProviders, "fake")
+		llmProvidersLock.Unlock()
+	}()
+
+	provider, err := newLLMProvider("fake", map[string]any{})
+	assert.Nil(t, err)
+	assert.Equal(t, fake, provider)
+
+	_, err = newLLMProvider("unknown", map[string]any{})
+	assert.NotNil(t, err)
+
+	t.Setenv("OPENAI_API_KEY", "")
+	_, err = newLLMProvider("", map[string]any{})
+	assert.ErrorContains(t, err, "openAIKey")
+}
+
+func TestLlmNlToOpenAPIRequestWithFakeProvider(t *testing.T) {
+	fake := &fakeLLMProvider{
+		completions: []string{`{
+			"in_path_params": {"owner": "agntcy"},
+			"in_query_params": {"state": ["open"]},
+			"in_header_params": {"Accept": ["application/json"]}
+		}`},
+	}
+	operation := &openapi3.Operation{Summary: "List issues"}
+
+	params := llmNlToOpenAPIRequest(context.TODO(), operation, "list open issues of agntcy", &NLAPIConfig{provider: fake})
+	assert.NotNil(t, params)
+	assert.Equal(t, map[string]string{"owner": "agntcy"}, params.InPathParams)
+	assert.Equal(t, url.Values{"state": []string{"open"}}, params.InQueryParams)
+	assert.Equal(t, http.Header{"Accept": []string{"application/json"}}, params.InHeaderParams)
+
+	assert.Len(t, fake.schemas, 1)
+	assert.Equal(t, "convert_to_openapi", fake.schemas[0].Name)
+	assert.Contains(t, fake.userPrompts[0], "list open issues of agntcy")
+	assert.Contains(t, fake.systemPrompts[0], "List issues")
+}
+
+func TestLlmCallWithoutProvider(t *testing.T) {
+	_, err := llmCall(context.TODO(), "system", "user", nil, &NLAPIConfig{})
+	assert.NotNil(t, err)
+}
+
+func TestMcpToolToLLMTool(t *testing.T) {
+	tool := mcp.NewTool("search",
+		mcp.WithDescription("Search the web"),
+		mcp.WithString("query", mcp.Required()),
+	)
+
+	llmTool, err := mcpToolToLLMTool(tool)
+	assert.Nil(t, err)
+	assert.Equal(t, "search", llmTool.Name)
+	assert.Equal(t, "Search the web", llmTool.Description)
+	assert.JSONEq(t, `{"type":"object","properties":{"query":{"type":"string"}},"required":["query"]}`, string(llmTool.Parameters))
+}
@@ -1,7 +1,7 @@
 // Copyright AGNTCY Contributors (https://github.com/agntcy)
 // SPDX-License-Identifier: Apache-2.0
 
-package main
+package agentbridge
 
 import (
 	"context"
@@ -12,10 +12,6 @@ import (
 	"os"
 	"strings"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/ai/azopenai"
-	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
-	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
-
 	"github.com/mark3labs/mcp-go/client"
 	"github.com/mark3labs/mcp-go/mcp"
 )
@@ -29,6 +25,7 @@ type MCPServers map[string]*MCPServerConfig
 type TykMCPConfig struct {
 	MCPServers   MCPServers      `json:"mcpServers"`
 	MCPLLMConfig MCPOpenAIConfig `json:"openai,omitempty"`
+	LLMProvider  string          `json:"llmProvider,omitempty"` // default is "openai"
 }
 
 type MCPServerConfig struct {
@@ -45,14 +42,17 @@ type MCPServerConfig struct {
 var mcpConfig MCPServers = MCPServers{}
 
 type MCPOpenAIConfig struct {
-	OpenAIKey       string `json:"openAIKey"`
-	OpenAIEndpoint  string `json:"openAIEndpoint"`
-	ModelDeployment string `json:"modelDeployment"`
+	OpenAIKey       string            `json:"openAIKey"`
+	OpenAIEndpoint  string            `json:"openAIEndpoint"`
+	ModelDeployment string            `json:"modelDeployment"`
+	Provider        string            `json:"provider"`
+	NoAuth          bool              `json:"noAuth"`
+	Headers         map[string]string `json:"headers"`
 }
 
 type MCPLLMConfig struct {
 	openAIConfig MCPOpenAIConfig
-	azureClient  *azopenai.Client
+	provider     LLMProvider
 }
 
 var llmConfig = MCPLLMConfig{}
@@ -167,86 +167,60 @@ func processQueryWithMCP(nlq string) (string, error) {
 		logger.Errorf("[+] processQueryWithMCP('%s') no available tools", nlq)
 		return "", fmt.Errorf("no available tools")
 	}
-	if llmConfig.azureClient == nil {
+	if llmConfig.provider == nil {
 		logger.Error("[+] No LLM configured in MCP configuration")
 		return "", fmt.Errorf("no LLM configured in MCP configuration")
 	}
 
 	// Ask to the LLM
-	messages := []azopenai.ChatRequestMessageClassification{
-		&azopenai.ChatRequestUserMessage{
-			Content: azopenai.NewChatRequestUserMessageContent(nlq),
-		},
+	messages := []LLMMessage{
+		{Role: LLM_ROLE_USER, Content: nlq},
 	}
-	llmTools := []azopenai.ChatCompletionsToolDefinitionClassification{}
+	llmTools := []LLMTool{}
 	for _, tool := range availableTools {
-		functionDefinition, err := getChatCompletionFunctionDefinition(tool)
+		llmTool, err := mcpToolToLLMTool(tool)
 		if err != nil {
 			logger.Errorf("[+] processQueryWithMCP('%s') failed to get function definition for tool (%s): %v", nlq, tool.Name, err)
 			continue
 		}
-		llmTools = append(llmTools, &azopenai.ChatCompletionsFunctionToolDefinition{
-			Function: functionDefinition,
-		},
-		)
+		llmTools = append(llmTools, llmTool)
 	}
 
 	round := 0
 	for round < DEFAULT_MAX_LLM_ITERATIONS {
 		round++
 
-		resp, err := llmConfig.azureClient.GetChatCompletions(context.TODO(), azopenai.ChatCompletionsOptions{
-			DeploymentName: &llmConfig.openAIConfig.ModelDeployment,
-			Messages:       messages,
-			Tools:          llmTools,
-			Temperature:    to.Ptr[float32](DEFAULT_LLM_TEMPERATURE),
-			Seed:           to.Ptr[int64](DEFAULT_LLM_SEED),
-		}, nil)
+		resp, err := llmConfig.provider.ChatWithTools(context.TODO(), messages, llmTools)
 		if err != nil {
 			logger.Errorf("[+] Failed to query LLM: %s", err)
 			return "", err
 		}
 
-		if len(resp.Choices) == 0 {
-			return "", fmt.Errorf("no choices in LLM response")
-		}
-
-		choice := resp.Choices[0]
-		if choice.FinishReason == nil {
-			return "", fmt.Errorf("LLM returned a choice with no finish reason")
-		}
-
-		messages = append(messages, &azopenai.ChatRequestAssistantMessage{
-			ToolCalls: choice.Message.ToolCalls,
+		messages = append(messages, LLMMessage{
+			Role:      LLM_ROLE_ASSISTANT,
+			ToolCalls: resp.ToolCalls,
 		})
 
-		if *choice.FinishReason == azopenai.CompletionsFinishReasonStopped {
-			if choice.Message == nil || choice.Message.Content == nil {
-				logger.Errorf("[+] ERROR: no content in the response")
-				return "", fmt.Errorf("no content in the response")
-			}
-			logger.Debugf("[+] Stop is detected, final response is (%v) in %v round", *choice.Message.Content, round)
-			return *choice.Message.Content, nil
+		if resp.Done {
+			logger.Debugf("[+] Stop is detected, final response is (%v) in %v round", resp.Content, round)
+			return resp.Content, nil
 		}
-		for _, toolCall := range choice.Message.ToolCalls {
-			functionToolCall, ok := toolCall.(*azopenai.ChatCompletionsFunctionToolCall)
-			if !ok {
-				logger.Errorf("[+] Unexpected error, something is wrong in the azure-sdk-for-go library, ignoring ...")
-				continue
-			}
-			result, err := callMCPTool(*functionToolCall.Function.Name, functionToolCall.Function.Arguments)
+		for _, toolCall := range resp.ToolCalls {
+			result, err := callMCPTool(toolCall.Name, &toolCall.Arguments)
 			if err != nil {
-				logger.Errorf("[+] Failed to call tool (%s): %v", *functionToolCall.Function.Name, err)
-				messages = append(messages, &azopenai.ChatRequestToolMessage{
-					Content:    azopenai.NewChatRequestToolMessageContent("An error occurred while calling the tool"),
-					ToolCallID: functionToolCall.ID,
+				logger.Errorf("[+] Failed to call tool (%s): %v", toolCall.Name, err)
+				messages = append(messages, LLMMessage{
+					Role:       LLM_ROLE_TOOL,
+					Content:    "An error occurred while calling the tool",
+					ToolCallID: toolCall.ID,
 				})
 				continue
 			}
 
-			messages = append(messages, &azopenai.ChatRequestToolMessage{
-				Content:    azopenai.NewChatRequestToolMessageContent(result),
-				ToolCallID: functionToolCall.ID,
+			messages = append(messages, LLMMessage{
+				Role:       LLM_ROLE_TOOL,
+				Content:    result,
+				ToolCallID: toolCall.ID,
 			})
 		}
 	}
@@ -255,21 +229,6 @@ func processQueryWithMCP(nlq string) (string, error) {
 	return "", fmt.Errorf("reached the limit of rounds")
 }
 
-func getChatCompletionFunctionDefinition(tool mcp.Tool) (*azopenai.ChatCompletionsFunctionToolDefinitionFunction, error) {
-	jsonBytes, err := json.Marshal(tool.InputSchema)
-	if err != nil {
-		logger.Fatalf("[+] Failed to marshal parameters of tool (%v): err=%v", tool, err)
-	}
-
-	// Create the function definition
-	functionDefinition := &azopenai.ChatCompletionsFunctionToolDefinitionFunction{
-		Name:        &tool.Name,
-		Description: &tool.Description,
-		Parameters:  jsonBytes,
-	}
-	return functionDefinition, nil
-}
-
 func deinitMCPClient() {
 	for _, config := range mcpConfig {
 		if config.Client != nil {
@@ -404,24 +363,28 @@ func loadMCPPluginConfig(r *http.Request) error {
 	}
 	mcpConfig = mcpTykConfig.MCPServers
 
+	if mcpTykConfig.LLMProvider != "" && mcpTykConfig.LLMProvider != LLM_PROVIDER_OPENAI {
+		llmConfig.provider, err = newLLMProvider(mcpTykConfig.LLMProvider, globalPluginConfig.Data.Value)
+		if err != nil {
+			logger.Errorf("[+] Unable to create LLM provider %s: %s", mcpTykConfig.LLMProvider, err)
+			return err
+		}
+		return nil
+	}
+
 	llmConfig.openAIConfig = mcpTykConfig.MCPLLMConfig
 	llmConfig.openAIConfig.OpenAIEndpoint = getEnvOrDefault(llmConfig.openAIConfig.OpenAIEndpoint, "OPENAI_ENDPOINT", DEFAULT_OPENAI_ENDPOINT)
 	llmConfig.openAIConfig.OpenAIKey = getEnvOrDefault(llmConfig.openAIConfig.OpenAIKey, "OPENAI_API_KEY", "")
 	llmConfig.openAIConfig.ModelDeployment = getEnvOrDefault(llmConfig.openAIConfig.ModelDeployment, "OPENAI_MODEL", DEFAULT_OPENAI_MODEL)
+	llmConfig.openAIConfig.Provider = getEnvOrDefault(llmConfig.openAIConfig.Provider, "OPENAI_PROVIDER", getDefaultOpenAIProvider(llmConfig.openAIConfig.OpenAIEndpoint))
 
-	if llmConfig.openAIConfig.OpenAIKey == "" {
+	if llmConfig.openAIConfig.OpenAIKey == "" && !llmConfig.openAIConfig.NoAuth {
 		err := fmt.Errorf("missing required OpenAI Key. Either set OPENAI_API_KEY environement variable or set the 'openai.openAIKey' configuration")
 		logger.Errorf("[+] Error initializing plugin: %s", err)
 		return err
 	}
 
-	// Note: eventually cache these by hash of config?
-	keyCredential := azcore.NewKeyCredential(llmConfig.openAIConfig.OpenAIKey)
-	if llmConfig.openAIConfig.OpenAIEndpoint == DEFAULT_OPENAI_ENDPOINT {
-		llmConfig.azureClient, err = azopenai.NewClientForOpenAI(llmConfig.openAIConfig.OpenAIEndpoint, keyCredential, nil)
-	} else {
-		llmConfig.azureClient, err = azopenai.NewClientWithKeyCredential(llmConfig.openAIConfig.OpenAIEndpoint, keyCredential, nil)
-	}
+	llmConfig.provider, err = newOpenAIProvider(AzureConfig(llmConfig.openAIConfig))
 	if err != nil {
 		logger.Errorf("[+] Unable to create OpenAI client: %s", err)
 		return err
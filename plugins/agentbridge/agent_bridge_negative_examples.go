@@ -0,0 +1,63 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"context"
+	"sort"
+
+	"github.com/kelindar/search"
+)
+
+const (
+	SPEC_EXT_AI_NEGATIVE_EXAMPLES = "x-nl-negative-examples"
+
+	DEFAULT_NEGATIVE_WEIGHT = 1.0
+)
+
+// newNegativeExamplesIndex embeds the negative examples of the operations.
+// Operations without negative examples are not in the index.
+func newNegativeExamplesIndex(ctx context.Context, modelEmbedder Embedder, selectOperations map[string]*AIExtensionConfig) *search.Index[string] {
+	index := search.NewIndex[string]()
+	for apiOperation, aiExtension := range selectOperations {
+		for _, example := range aiExtension.NegativeExamples {
+			if len(example) > MAX_UTERANCE_LENGTH {
+				logger.Warningf("[+] negative example too long: %s", example)
+				continue
+			}
+			embedding, err := embedTextWithCache(ctx, modelEmbedder, example)
+			if err != nil {
+				logger.Warningf("[+] embedding model %s failed for text \"%s\": %s", modelEmbedder.Name(), example, err)
+			} else {
+				index.Add(embedding, apiOperation)
+			}
+		}
+	}
+	return index
+}
+
+// penalizeNegativeExamples lowers the relevance of the examples of the
+// operations whose negative examples are closer to the input:
+// relevance - negativeWeight * (negative relevance - relevance)
+// The results are sorted again, the best match first.
+func penalizeNegativeExamples(results []search.Result[string], negatives []search.Result[string], negativeWeight float64) []search.Result[string] {
+	nearestNegatives := map[string]float64{}
+	for _, negative := range negatives {
+		if relevance, present := nearestNegatives[negative.Value]; !present || negative.Relevance > relevance {
+			nearestNegatives[negative.Value] = negative.Relevance
+		}
+	}
+
+	penalized := make([]search.Result[string], 0, len(results))
+	for _, result := range results {
+		if relevance, present := nearestNegatives[result.Value]; present && relevance > result.Relevance {
+			result.Relevance -= negativeWeight * (relevance - result.Relevance)
+		}
+		penalized = append(penalized, result)
+	}
+	sort.SliceStable(penalized, func(i, j int) bool {
+		return penalized[i].Relevance > penalized[j].Relevance
+	})
+	return penalized
+}
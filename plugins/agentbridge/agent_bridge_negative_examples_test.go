@@ -0,0 +1,56 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"testing"
+
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+	"github.com/kelindar/search"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPenalizeNegativeExamples(t *testing.T) {
+	results := []search.Result[string]{
+		{Value: "updateRelease", Relevance: 0.82},
+		{Value: "getRelease", Relevance: 0.80},
+		{Value: "updateRelease", Relevance: 0.70},
+		{Value: "listReleases", Relevance: 0.60},
+	}
+	negatives := []search.Result[string]{
+		{Value: "updateRelease", Relevance: 0.90},
+		{Value: "updateRelease", Relevance: 0.85},
+		// Farther than the examples of the operation: no penalty
+		{Value: "listReleases", Relevance: 0.55},
+	}
+
+	penalized := penalizeNegativeExamples(results, negatives, 1)
+	assert.Len(t, penalized, 4)
+	assert.Equal(t, "getRelease", penalized[0].Value)
+	assert.InDelta(t, 0.80, penalized[0].Relevance, 1e-9)
+	assert.Equal(t, "updateRelease", penalized[1].Value)
+	assert.InDelta(t, 0.74, penalized[1].Relevance, 1e-9)
+	assert.Equal(t, "listReleases", penalized[2].Value)
+	assert.InDelta(t, 0.60, penalized[2].Relevance, 1e-9)
+	// Every example of the operation is penalized
+	assert.Equal(t, "updateRelease", penalized[3].Value)
+	assert.InDelta(t, 0.50, penalized[3].Relevance, 1e-9)
+
+	// A lower weight keeps the order
+	penalized = penalizeNegativeExamples(results, negatives, 0.1)
+	assert.Equal(t, "updateRelease", penalized[0].Value)
+	assert.InDelta(t, 0.812, penalized[0].Relevance, 1e-9)
+
+	// The results are not modified
+	assert.Equal(t, 0.82, results[0].Relevance)
+}
+
+func TestGetNegativeExamples(t *testing.T) {
+	operation := &openapi3.Operation{
+		OperationID: "getRelease",
+		Extensions:  map[string]any{SPEC_EXT_AI_NEGATIVE_EXAMPLES: []any{"update the release", 3}},
+	}
+	assert.Equal(t, []string{"update the release"}, getNegativeExamples(operation))
+	assert.Equal(t, []string{}, getNegativeExamples(&openapi3.Operation{OperationID: "listReleases"}))
+}
@@ -0,0 +1,94 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+)
+
+const SPEC_EXT_NATURAL_LANGUAGE = "x-tyk-natural-language"
+
+// naturalLanguageExtension is the x-tyk-natural-language extension of an
+// operation. The description replaces the summary and the description of the
+// operation for the LLM, the utterances are examples like x-nl-input-examples.
+type naturalLanguageExtension struct {
+	Description string
+	Utterances  []string
+}
+
+// getNaturalLanguageExtension returns nil when the operation has no valid
+// x-tyk-natural-language extension. Invalid values are ignored with a warning.
+func getNaturalLanguageExtension(operation *openapi3.Operation) *naturalLanguageExtension {
+	ext, exists := operation.Extensions[SPEC_EXT_NATURAL_LANGUAGE]
+	if !exists {
+		return nil
+	}
+	data, ok := ext.(map[string]any)
+	if !ok {
+		logger.Warningf("[+] Invalid type for %s in operation %s: %T; ignoring", SPEC_EXT_NATURAL_LANGUAGE, operation.OperationID, ext)
+		return nil
+	}
+
+	nlExtension := &naturalLanguageExtension{Utterances: []string{}}
+	if v, exists := data["description"]; exists {
+		if description, ok := v.(string); ok {
+			nlExtension.Description = description
+		} else {
+			logger.Warningf("[+] Invalid type for %s.description in operation %s: %T; ignoring", SPEC_EXT_NATURAL_LANGUAGE, operation.OperationID, v)
+		}
+	}
+	if v, exists := data["utterances"]; exists {
+		nlExtension.Utterances = getExtensionStrings(operation, SPEC_EXT_NATURAL_LANGUAGE+".utterances", v)
+	}
+	return nlExtension
+}
+
+// getInputExamples returns the examples of the operation, from both
+// x-nl-input-examples and the utterances of x-tyk-natural-language.
+func getInputExamples(operation *openapi3.Operation) []string {
+	examples := []string{}
+	if v, exists := operation.Extensions[SPEC_EXT_AI_INPUT_EXAMPLES]; exists {
+		examples = append(examples, getExtensionStrings(operation, SPEC_EXT_AI_INPUT_EXAMPLES, v)...)
+	}
+	if nlExtension := getNaturalLanguageExtension(operation); nlExtension != nil {
+		examples = append(examples, nlExtension.Utterances...)
+	}
+	return examples
+}
+
+// getNegativeExamples returns the queries that must not select the operation,
+// from the x-nl-negative-examples extension.
+func getNegativeExamples(operation *openapi3.Operation) []string {
+	if v, exists := operation.Extensions[SPEC_EXT_AI_NEGATIVE_EXAMPLES]; exists {
+		return getExtensionStrings(operation, SPEC_EXT_AI_NEGATIVE_EXAMPLES, v)
+	}
+	return []string{}
+}
+
+// getOperationDescriptions returns the summary and the description of the
+// operation, overridden by the x-tyk-natural-language description.
+func getOperationDescriptions(operation *openapi3.Operation) (string, string) {
+	if nlExtension := getNaturalLanguageExtension(operation); nlExtension != nil && nlExtension.Description != "" {
+		return "", nlExtension.Description
+	}
+	return operation.Summary, operation.Description
+}
+
+func getExtensionStrings(operation *openapi3.Operation, name string, v any) []string {
+	values := []string{}
+	items, ok := v.([]any)
+	if !ok {
+		logger.Warningf("[+] Invalid type for %s in operation %s: %T; ignoring", name, operation.OperationID, v)
+		return values
+	}
+	for _, item := range items {
+		value, ok := item.(string)
+		if !ok {
+			logger.Warningf("[+] Invalid value in %s of operation %s: %v; ignoring", name, operation.OperationID, item)
+			continue
+		}
+		values = append(values, value)
+	}
+	return values
+}
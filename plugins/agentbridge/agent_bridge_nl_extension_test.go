@@ -0,0 +1,93 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"testing"
+
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetInputExamples(t *testing.T) {
+	tests := []struct {
+		description         string
+		extensions          map[string]any
+		expectedExamples    []string
+		expectedDescription string
+	}{
+		{
+			"No extension",
+			map[string]any{},
+			[]string{},
+			"",
+		},
+		{
+			"Both extensions",
+			map[string]any{
+				SPEC_EXT_AI_INPUT_EXAMPLES: []any{"list my gists"},
+				SPEC_EXT_NATURAL_LANGUAGE: map[string]any{
+					"description": "List the gists of the user",
+					"utterances":  []any{"show my snippets", "what are my gists"},
+				},
+			},
+			[]string{"list my gists", "show my snippets", "what are my gists"},
+			"List the gists of the user",
+		},
+		{
+			"Invalid values are ignored",
+			map[string]any{
+				SPEC_EXT_AI_INPUT_EXAMPLES: "list my gists",
+				SPEC_EXT_NATURAL_LANGUAGE: map[string]any{
+					"description": 42,
+					"utterances":  []any{"show my snippets", 3},
+				},
+			},
+			[]string{"show my snippets"},
+			"",
+		},
+		{
+			"Invalid extension",
+			map[string]any{
+				SPEC_EXT_NATURAL_LANGUAGE: []any{"show my snippets"},
+			},
+			[]string{},
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			operation := &openapi3.Operation{OperationID: "gists/list", Summary: "List gists", Extensions: tt.extensions}
+			assert.Equal(t, tt.expectedExamples, getInputExamples(operation))
+
+			summary, description := getOperationDescriptions(operation)
+			if tt.expectedDescription == "" {
+				assert.Equal(t, "List gists", summary)
+			} else {
+				assert.Equal(t, "", summary)
+				assert.Equal(t, tt.expectedDescription, description)
+			}
+		})
+	}
+}
+
+func TestNaturalLanguageExtensionInSpec(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromFile("../../configs/httpbin.org.oas.json")
+	assert.Nil(t, err)
+	operation := doc.Paths["/absolute-redirect/{n}"].Get
+
+	assert.Equal(t, []string{"return an absolute redirect 3 times", "return a 302 redirect code"}, getInputExamples(operation))
+
+	// The LLM gets the description of the extension instead of the summary
+	operationString, err := buildOperationString(operation)
+	assert.Nil(t, err)
+	assert.Contains(t, operationString, `"description":"This description overrides the 'summary' and 'description', it's the description of the tool"`)
+	assert.NotContains(t, operationString, "Absolutely 302 Redirects n times.")
+	assert.NotContains(t, operationString, SPEC_EXT_NATURAL_LANGUAGE)
+
+	// The operation itself is not modified
+	assert.Equal(t, "Absolutely 302 Redirects n times.", operation.Summary)
+	assert.Contains(t, operation.Extensions, SPEC_EXT_NATURAL_LANGUAGE)
+}
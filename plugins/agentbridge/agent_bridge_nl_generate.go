@@ -0,0 +1,536 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/TykTechnologies/kin-openapi/routers"
+	"github.com/TykTechnologies/tyk/apidef/oas"
+	"gopkg.in/yaml.v3"
+)
+
+const GENERATED_EXAMPLES_KEY_PREFIX = "generated_examples:" // + apiId, examples by operationId
+
+var (
+	// The keys of the operations in the path items of a spec
+	specOperationMethods = map[string]bool{
+		"get": true, "put": true, "post": true, "delete": true, "options": true, "head": true, "patch": true, "trace": true,
+	}
+
+	tmplGenerateSystemPrompt *template.Template
+	tmplGenerateUserPrompt   *template.Template
+
+	structuredGenerateResponse = []byte(`{
+"type": "object",
+"properties": {
+  "examples": {
+    "type": "array",
+    "items": {"type": "string"},
+    "description": "The example sentences, each one asking for the operation in a different way"
+  }
+},
+"required": ["examples"],
+"additionalProperties": false
+}`)
+)
+
+type TmplPromptGenerate struct {
+	Count     int
+	Operation rerankCandidate
+}
+
+type generateResponse struct {
+	Examples []string `json:"examples"`
+}
+
+// GeneratedExamples are the examples generated for an operation, with the
+// fingerprint of the operation when they were generated.
+type GeneratedExamples struct {
+	Fingerprint string   `json:"fingerprint"`
+	Examples    []string `json:"examples"`
+}
+
+// GenerateSpecExamples loads the spec with its plugin configuration and
+// generates the examples of its enabled operations.
+func GenerateSpecExamples(ctx context.Context, apiId string, specFilename string, count int) (map[string]GeneratedExamples, error) {
+	pluginDataConfig, apiDef, err := LoadSpec(apiId, specFilename)
+	if err != nil {
+		return nil, err
+	}
+	return generateAPIExamples(ctx, pluginDataConfig, apiDef, count), nil
+}
+
+// generateAPIExamples generates the examples of the enabled operations that
+// have none, neither in the spec nor in selectOperations. The operations for
+// which the LLM fails are skipped with a warning.
+func generateAPIExamples(ctx context.Context, pluginDataConfig *PluginDataConfig, apiDef *oas.OAS, count int) map[string]GeneratedExamples {
+	examples := map[string]GeneratedExamples{}
+	for pathName, pathItem := range apiDef.Paths {
+		for method, operation := range pathItem.Operations() {
+			if operation.OperationID == "" || !isOperationEnabled(pluginDataConfig, pathName, method, operation) {
+				continue
+			}
+			if len(getInputExamples(operation)) > 0 {
+				continue
+			}
+			if aiExtensionConfig := pluginDataConfig.SelectOperations[operation.OperationID]; aiExtensionConfig != nil && len(aiExtensionConfig.InputExamples) > 0 {
+				continue
+			}
+
+			route := &routers.Route{
+				Spec:      &apiDef.T,
+				Path:      pathName,
+				PathItem:  pathItem,
+				Method:    method,
+				Operation: operation,
+			}
+			operationExamples, err := generateOperationExamples(ctx, pluginDataConfig.LlmConfig, route, count)
+			if err != nil {
+				logger.Warningf("[+] Unable to generate the examples of operation %s: %s", operation.OperationID, err)
+				continue
+			}
+			if len(operationExamples) > 0 {
+				examples[operation.OperationID] = GeneratedExamples{Fingerprint: getOperationFingerprint(route), Examples: operationExamples}
+			}
+		}
+	}
+	return examples
+}
+
+// getOperationFingerprint hashes the description of the operation given to
+// the LLM. Its generated examples are stale once it changes.
+func getOperationFingerprint(route *routers.Route) string {
+	userPromptBuf := new(bytes.Buffer)
+	if err := tmplGenerateUserPrompt.Execute(userPromptBuf, TmplPromptGenerate{Operation: newRerankCandidate(route)}); err != nil {
+		logger.Warningf("[+] Unable to describe operation %s: %s", route.Operation.OperationID, err)
+		return ""
+	}
+	hash := sha256.Sum256(userPromptBuf.Bytes())
+	return hex.EncodeToString(hash[:])
+}
+
+// generateOperationExamples asks the LLM for count diverse sentences a user
+// could type to call the operation. Empty, duplicated or too long sentences
+// are dropped.
+func generateOperationExamples(ctx context.Context, llmConfig *NLAPIConfig, route *routers.Route, count int) ([]string, error) {
+	promptData := TmplPromptGenerate{Count: count, Operation: newRerankCandidate(route)}
+	systemPromptBuf := new(bytes.Buffer)
+	if err := tmplGenerateSystemPrompt.Execute(systemPromptBuf, promptData); err != nil {
+		return nil, fmt.Errorf("error while creating the system prompt: %w", err)
+	}
+	userPromptBuf := new(bytes.Buffer)
+	if err := tmplGenerateUserPrompt.Execute(userPromptBuf, promptData); err != nil {
+		return nil, fmt.Errorf("error while creating the user prompt: %w", err)
+	}
+
+	generateTool := JsonSchemaResponse{
+		Name:        "generate_examples",
+		Description: "Generate example sentences calling the operation",
+		Schema:      structuredGenerateResponse,
+	}
+	answer, err := llmCall(ctx, systemPromptBuf.String(), userPromptBuf.String(), &generateTool, llmConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	response := generateResponse{}
+	if err := json.Unmarshal([]byte(answer), &response); err != nil {
+		return nil, fmt.Errorf("invalid answer from the LLM: %w", err)
+	}
+
+	examples := []string{}
+	seen := map[string]bool{}
+	for _, example := range response.Examples {
+		example = strings.TrimSpace(example)
+		key := strings.ToLower(example)
+		if example == "" || len(example) > MAX_UTERANCE_LENGTH || seen[key] {
+			continue
+		}
+		seen[key] = true
+		examples = append(examples, example)
+		if len(examples) == count {
+			break
+		}
+	}
+	return examples, nil
+}
+
+// WriteAnnotatedSpec copies the spec with the generated examples added to the
+// x-nl-input-examples extension of their operation, for a review before
+// replacing the original spec.
+func WriteAnnotatedSpec(specFilename string, outputFilename string, examples map[string]GeneratedExamples) error {
+	specData, err := os.ReadFile(specFilename)
+	if err != nil {
+		return fmt.Errorf("can't read spec %s: %s", specFilename, err)
+	}
+	operationExamples := map[string][]string{}
+	for operationId, generated := range examples {
+		operationExamples[operationId] = generated.Examples
+	}
+	annotatedData, err := annotateSpecExamples(specData, operationExamples)
+	if err != nil {
+		return fmt.Errorf("can't annotate spec %s: %s", specFilename, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(outputFilename), 0o755); err != nil {
+		return fmt.Errorf("can't create directory for %s: %s", outputFilename, err)
+	}
+	if err := os.WriteFile(outputFilename, annotatedData, 0o644); err != nil {
+		return fmt.Errorf("can't write annotated spec %s: %s", outputFilename, err)
+	}
+	return nil
+}
+
+// annotateSpecExamples only patches the x-nl-input-examples of the operations
+// in the raw document, so the rest of the spec is kept as it is written: the
+// references, the order of the keys, the numbers and the format, JSON or YAML.
+func annotateSpecExamples(specData []byte, examples map[string][]string) ([]byte, error) {
+	if trimmed := bytes.TrimSpace(specData); len(trimmed) > 0 && trimmed[0] == '{' {
+		return annotateJSONSpecExamples(specData, examples)
+	}
+	return annotateYAMLSpecExamples(specData, examples)
+}
+
+// jsonSpecPatch replaces the bytes of the document from start to end.
+type jsonSpecPatch struct {
+	start int
+	end   int
+	text  string
+}
+
+func annotateJSONSpecExamples(specData []byte, examples map[string][]string) ([]byte, error) {
+	patches := []jsonSpecPatch{}
+	decoder := json.NewDecoder(bytes.NewReader(specData))
+	err := walkJSONObject(decoder, func(key string) error {
+		if key != "paths" {
+			return skipJSONValue(decoder)
+		}
+		return walkJSONObject(decoder, func(string) error {
+			return walkJSONObject(decoder, func(method string) error {
+				operation := json.RawMessage{}
+				if err := decoder.Decode(&operation); err != nil {
+					return err
+				}
+				if !specOperationMethods[method] {
+					return nil
+				}
+				start := int(decoder.InputOffset()) - len(operation)
+				patch, err := newJSONOperationPatch(specData, start, operation, examples)
+				if err == nil && patch != nil {
+					patches = append(patches, *patch)
+				}
+				return err
+			})
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	annotatedData := bytes.Buffer{}
+	offset := 0
+	for _, patch := range patches {
+		annotatedData.Write(specData[offset:patch.start])
+		annotatedData.WriteString(patch.text)
+		offset = patch.end
+	}
+	annotatedData.Write(specData[offset:])
+	return annotatedData.Bytes(), nil
+}
+
+// newJSONOperationPatch replaces the x-nl-input-examples of the operation
+// starting at start in the document, or inserts them after its operationId.
+// It returns nil when the operation has no examples.
+func newJSONOperationPatch(specData []byte, start int, operation json.RawMessage, examples map[string][]string) (*jsonSpecPatch, error) {
+	decoder := json.NewDecoder(bytes.NewReader(operation))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, err
+	}
+	operationId := ""
+	var operationIdKey, operationIdEnd, examplesKey, examplesStart, examplesEnd int
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := token.(string)
+		keyEnd := start + int(decoder.InputOffset())
+		value := json.RawMessage{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		valueEnd := start + int(decoder.InputOffset())
+		switch key {
+		case "operationId":
+			if err := json.Unmarshal(value, &operationId); err != nil {
+				return nil, err
+			}
+			operationIdKey, operationIdEnd = keyEnd-len(`"operationId"`), valueEnd
+		case SPEC_EXT_AI_INPUT_EXAMPLES:
+			examplesKey, examplesStart, examplesEnd = keyEnd-len(`"`+SPEC_EXT_AI_INPUT_EXAMPLES+`"`), valueEnd-len(value), valueEnd
+		}
+	}
+	operationExamples, exists := examples[operationId]
+	if operationId == "" || !exists {
+		return nil, nil
+	}
+
+	if examplesEnd > 0 {
+		space, indent := getJSONKeyIndent(specData, start, examplesKey)
+		text, err := marshalJSONExamples(operationExamples, space, indent)
+		return &jsonSpecPatch{start: examplesStart, end: examplesEnd, text: text}, err
+	}
+	// Inserted after the operationId, with the same indentation
+	space, indent := getJSONKeyIndent(specData, start, operationIdKey)
+	text, err := marshalJSONExamples(operationExamples, space, indent)
+	separator := ":"
+	if space != "" {
+		separator = ": "
+	}
+	text = "," + space + `"` + SPEC_EXT_AI_INPUT_EXAMPLES + `"` + separator + text
+	return &jsonSpecPatch{start: operationIdEnd, end: operationIdEnd, text: text}, err
+}
+
+// getJSONKeyIndent returns the spaces before the key of an object starting at
+// objectStart, and the indentation unit of the document when the keys are on
+// their own line.
+func getJSONKeyIndent(specData []byte, objectStart int, keyStart int) (string, string) {
+	spaceStart := keyStart
+	for spaceStart > 0 && strings.ContainsRune(" \t\r\n", rune(specData[spaceStart-1])) {
+		spaceStart--
+	}
+	space := string(specData[spaceStart:keyStart])
+	newline := strings.LastIndex(space, "\n")
+	if newline < 0 {
+		return space, ""
+	}
+	keyIndent := space[newline+1:]
+
+	// The indentation of the line of the object
+	lineStart := bytes.LastIndexByte(specData[:objectStart], '\n') + 1
+	objectIndent := specData[lineStart:objectStart]
+	objectIndent = objectIndent[:len(objectIndent)-len(bytes.TrimLeft(objectIndent, " \t"))]
+	if strings.HasPrefix(keyIndent, string(objectIndent)) && len(keyIndent) > len(objectIndent) {
+		return space, keyIndent[len(objectIndent):]
+	}
+	return space, "  "
+}
+
+// marshalJSONExamples writes the examples on a line each after the space of
+// their key, or on one line when the space has no line break.
+func marshalJSONExamples(examples []string, space string, indent string) (string, error) {
+	buffer := bytes.Buffer{}
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if newline := strings.LastIndex(space, "\n"); newline >= 0 {
+		encoder.SetIndent(space[newline+1:], indent)
+	}
+	if err := encoder.Encode(examples); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buffer.String(), "\n"), nil
+}
+
+// walkJSONObject calls walkValue on each key of the next value of the decoder,
+// which must read the value of the key. Other values are skipped.
+func walkJSONObject(decoder *json.Decoder, walkValue func(key string) error) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != json.Delim('{') {
+		if token == json.Delim('[') {
+			for decoder.More() {
+				if err := skipJSONValue(decoder); err != nil {
+					return err
+				}
+			}
+			_, err = decoder.Token()
+		}
+		return err
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := token.(string)
+		if err := walkValue(key); err != nil {
+			return err
+		}
+	}
+	_, err = decoder.Token()
+	return err
+}
+
+func skipJSONValue(decoder *json.Decoder) error {
+	return decoder.Decode(&json.RawMessage{})
+}
+
+// annotateYAMLSpecExamples edits the YAML nodes of the operations, the other
+// nodes are written back as they were parsed, with their comments.
+func annotateYAMLSpecExamples(specData []byte, examples map[string][]string) ([]byte, error) {
+	document := yaml.Node{}
+	if err := yaml.Unmarshal(specData, &document); err != nil {
+		return nil, err
+	}
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("the spec is not an object")
+	}
+	root := document.Content[0]
+
+	if paths := getYAMLMappingValue(root, "paths"); paths != nil && paths.Kind == yaml.MappingNode {
+		for index := 1; index < len(paths.Content); index += 2 {
+			pathItem := paths.Content[index]
+			if pathItem.Kind != yaml.MappingNode {
+				continue
+			}
+			for methodIndex := 0; methodIndex+1 < len(pathItem.Content); methodIndex += 2 {
+				operation := pathItem.Content[methodIndex+1]
+				if !specOperationMethods[pathItem.Content[methodIndex].Value] || operation.Kind != yaml.MappingNode {
+					continue
+				}
+				setYAMLOperationExamples(operation, examples)
+			}
+		}
+	}
+
+	buffer := bytes.Buffer{}
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(getYAMLIndent(root))
+	if err := encoder.Encode(&document); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// setYAMLOperationExamples replaces the x-nl-input-examples of the operation,
+// or inserts them after its operationId.
+func setYAMLOperationExamples(operation *yaml.Node, examples map[string][]string) {
+	operationId := getYAMLMappingValue(operation, "operationId")
+	if operationId == nil {
+		return
+	}
+	operationExamples, exists := examples[operationId.Value]
+	if operationId.Value == "" || !exists {
+		return
+	}
+
+	sequence := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for _, example := range operationExamples {
+		sequence.Content = append(sequence.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: example})
+	}
+	for index := 0; index+1 < len(operation.Content); index += 2 {
+		if operation.Content[index].Value == SPEC_EXT_AI_INPUT_EXAMPLES {
+			operation.Content[index+1] = sequence
+			return
+		}
+	}
+	for index := 0; index+1 < len(operation.Content); index += 2 {
+		if operation.Content[index+1] == operationId {
+			key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: SPEC_EXT_AI_INPUT_EXAMPLES}
+			operation.Content = slices.Insert(operation.Content, index+2, key, sequence)
+			return
+		}
+	}
+}
+
+func getYAMLMappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for index := 0; index+1 < len(mapping.Content); index += 2 {
+		if mapping.Content[index].Value == key {
+			return mapping.Content[index+1]
+		}
+	}
+	return nil
+}
+
+// getYAMLIndent returns the indentation of the first nested mapping of the
+// document, 2 by default.
+func getYAMLIndent(root *yaml.Node) int {
+	for index := 1; index < len(root.Content); index += 2 {
+		value := root.Content[index]
+		if value.Kind == yaml.MappingNode && len(value.Content) > 0 && value.Style&yaml.FlowStyle == 0 {
+			if indent := value.Content[0].Column - root.Content[index-1].Column; indent > 0 {
+				return indent
+			}
+		}
+	}
+	return 2
+}
+
+// SaveGeneratedExamples replaces the generated examples of the API in the
+// store. They are added to the operations without examples when the API is
+// loaded, as long as the operations don't change.
+func SaveGeneratedExamples(apiId string, examples map[string]GeneratedExamples) error {
+	if agentBridgeStore == nil {
+		return fmt.Errorf("storage is not configured")
+	}
+	jsonExamples, err := json.Marshal(examples)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the generated examples: %w", err)
+	}
+	if err := agentBridgeStore.SetKey(GENERATED_EXAMPLES_KEY_PREFIX+apiId, string(jsonExamples), AGENT_BRIDGE_DEFAULT_TTL); err != nil {
+		return fmt.Errorf("failed to save the generated examples: %w", err)
+	}
+	return nil
+}
+
+// getGeneratedExamples returns the examples saved by nl-generate for the API,
+// by operationId. They are empty when there are none.
+func getGeneratedExamples(apiId string) map[string]GeneratedExamples {
+	examples := map[string]GeneratedExamples{}
+	if agentBridgeStore == nil || apiId == "" {
+		return examples
+	}
+	value, err := agentBridgeStore.GetKey(GENERATED_EXAMPLES_KEY_PREFIX + apiId)
+	if err != nil {
+		return examples
+	}
+	if err := json.Unmarshal([]byte(value), &examples); err != nil {
+		logger.Warningf("[+] Invalid generated examples for api id %s: %s; ignoring", apiId, err)
+		return map[string]GeneratedExamples{}
+	}
+	return examples
+}
+
+func initGenerateTemplates() {
+	var err error
+
+	systemPrompt := `You write the sentences that users type to call an operation of an API, in natural language.
+Write {{.Count}} sentences, as different as possible from each other: vary the wording, the length and the level of detail, and use realistic values for the parameters.
+Each sentence must only match this operation, not a similar operation of the API (for example, reading an item is not updating it).
+DO NOT mention the operationId, the HTTP method or the path.`
+
+	userPrompt := `The operation:
+====
+{{with .Operation}}operationId: {{.OperationID}}
+{{.Method}} {{.Path}}
+{{if .Summary}}Summary: {{.Summary}}
+{{end}}{{if .Description}}Description: {{.Description}}
+{{end}}{{if .Parameters}}Parameters:
+{{range .Parameters}}- {{.}}
+{{end}}{{end}}{{end}}====`
+
+	tmplGenerateSystemPrompt, err = template.New("system_prompt_generate").Parse(systemPrompt)
+	if err != nil {
+		logger.Fatalf("[+] Error parsing the examples generation system prompt template: %s", err)
+	}
+	tmplGenerateUserPrompt, err = template.New("user_prompt_generate").Parse(userPrompt)
+	if err != nil {
+		logger.Fatalf("[+] Error parsing the examples generation user prompt template: %s", err)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+	"github.com/TykTechnologies/kin-openapi/routers"
+	"github.com/TykTechnologies/tyk/apidef/oas"
+	"github.com/stretchr/testify/assert"
+)
+
+const generateTestSpec = `{
+	"openapi": "3.0.0",
+	"info": {"title": "Issues", "version": "1.0.0"},
+	"paths": {
+		"/issues": {
+			"get": {
+				"operationId": "listIssues",
+				"summary": "List the issues",
+				"x-nl-input-examples": ["show me the open issues"],
+				"responses": {"200": {"description": "OK"}}
+			}
+		},
+		"/issues/{id}": {
+			"parameters": [{"name": "id", "in": "path", "required": true, "description": "The issue number", "schema": {"type": "integer"}}],
+			"get": {
+				"operationId": "getIssue",
+				"summary": "Get an issue",
+				"responses": {"200": {"description": "OK"}}
+			},
+			"delete": {
+				"operationId": "deleteIssue",
+				"x-nl-input-examples-disabled": true,
+				"responses": {"204": {"description": "Deleted"}}
+			}
+		}
+	}
+}`
+
+func TestGenerateAPIExamples(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(generateTestSpec))
+	assert.Nil(t, err)
+	apiDef := &oas.OAS{T: *doc}
+
+	fake := &fakeLLMProvider{completions: []string{
+		`{"examples": ["show me the issue 42", " Show me the issue 42", "", "what is the status of ticket 7?", "get issue 3"]}`,
+	}}
+	config := &PluginDataConfig{LlmConfig: &NLAPIConfig{provider: fake}, SelectOperations: map[string]*AIExtensionConfig{}}
+
+	examples := generateAPIExamples(context.TODO(), config, apiDef, 2)
+	// Only getIssue has no examples and is enabled; duplicates and empty sentences are dropped
+	pathItem := apiDef.Paths["/issues/{id}"]
+	fingerprint := getOperationFingerprint(&routers.Route{Spec: &apiDef.T, Path: "/issues/{id}", PathItem: pathItem, Method: "GET", Operation: pathItem.Get})
+	assert.NotEmpty(t, fingerprint)
+	assert.Equal(t, map[string]GeneratedExamples{
+		"getIssue": {Fingerprint: fingerprint, Examples: []string{"show me the issue 42", "what is the status of ticket 7?"}},
+	}, examples)
+	assert.Len(t, fake.systemPrompts, 1)
+	assert.Contains(t, fake.systemPrompts[0], "Write 2 sentences")
+	assert.Contains(t, fake.userPrompts[0], "operationId: getIssue\nGET /issues/{id}\nSummary: Get an issue\n")
+	assert.Contains(t, fake.userPrompts[0], "- id (in path): The issue number")
+	assert.Equal(t, "generate_examples", fake.schemas[0].Name)
+
+	// An operation for which the LLM fails is skipped
+	fake = &fakeLLMProvider{completions: []string{`not json`}}
+	config.LlmConfig = &NLAPIConfig{provider: fake}
+	assert.Empty(t, generateAPIExamples(context.TODO(), config, apiDef, 2))
+}
+
+func TestAnnotateSpecExamples(t *testing.T) {
+	annotated, err := annotateSpecExamples([]byte(generateTestSpec), map[string][]string{
+		"getIssue":   {"show me the issue 42", "is #7 <done>?"},
+		"listIssues": {"list the issues"},
+	})
+	assert.Nil(t, err)
+	// Only the examples are changed, with the indentation of the spec
+	expected := strings.Replace(generateTestSpec, `"operationId": "getIssue",`,
+		"\"operationId\": \"getIssue\",\n\t\t\t\t\"x-nl-input-examples\": [\n\t\t\t\t\t\"show me the issue 42\",\n\t\t\t\t\t\"is #7 <done>?\"\n\t\t\t\t],", 1)
+	expected = strings.Replace(expected, `"x-nl-input-examples": ["show me the open issues"]`,
+		"\"x-nl-input-examples\": [\n\t\t\t\t\t\"list the issues\"\n\t\t\t\t]", 1)
+	assert.Equal(t, expected, string(annotated))
+
+	doc, err := openapi3.NewLoader().LoadFromData(annotated)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"show me the issue 42", "is #7 <done>?"}, getInputExamples(doc.Paths["/issues/{id}"].Get))
+
+	// On a single line
+	annotated, err = annotateSpecExamples([]byte(`{"paths":{"/issues":{"get":{"operationId":"listIssues","x-max":1.50}}}}`), map[string][]string{"listIssues": {"list the issues"}})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"paths":{"/issues":{"get":{"operationId":"listIssues","x-nl-input-examples":["list the issues"],"x-max":1.50}}}}`, string(annotated))
+
+	_, err = annotateSpecExamples([]byte("{not json"), nil)
+	assert.NotNil(t, err)
+}
+
+func TestAnnotateYAMLSpecExamples(t *testing.T) {
+	spec := `openapi: 3.0.0
+info:
+  title: Issues
+  version: "1.0"
+paths:
+  /issues:
+    get:
+      # The open issues first
+      operationId: listIssues
+      x-max: 1.50
+      responses:
+        "200":
+          description: OK
+`
+	annotated, err := annotateSpecExamples([]byte(spec), map[string][]string{"listIssues": {"list the issues"}})
+	assert.Nil(t, err)
+	expected := strings.Replace(spec, "operationId: listIssues\n", "operationId: listIssues\n      x-nl-input-examples:\n        - list the issues\n", 1)
+	assert.Equal(t, expected, string(annotated))
+
+	_, err = annotateSpecExamples([]byte("- not an object"), nil)
+	assert.NotNil(t, err)
+}
+
+func TestAddSpecGeneratedExamples(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(`{
+		"openapi": "3.0.0",
+		"info": {"title": "Issues", "version": "1.0.0"},
+		"paths": {
+			"/issues": {
+				"get": {"operationId": "listIssues", "summary": "List the issues", "description": "The open issues first", "responses": {"200": {"description": "OK"}}}
+			},
+			"/issues/{id}": {
+				"get": {"operationId": "getIssue", "summary": "Get an issue", "description": "An issue by number", "responses": {"200": {"description": "OK"}}}
+			}
+		}
+	}`))
+	assert.Nil(t, err)
+	apiDef := &oas.OAS{T: *doc}
+	pathItem := apiDef.Paths["/issues/{id}"]
+	fingerprint := getOperationFingerprint(&routers.Route{Spec: &apiDef.T, Path: "/issues/{id}", PathItem: pathItem, Method: "GET", Operation: pathItem.Get})
+
+	apiId := "test-generated-examples"
+	t.Cleanup(func() { agentBridgeStore.DeleteKey(GENERATED_EXAMPLES_KEY_PREFIX + apiId) })
+	assert.Nil(t, SaveGeneratedExamples(apiId, map[string]GeneratedExamples{
+		"getIssue": {Fingerprint: fingerprint, Examples: []string{"show me the issue 42"}},
+	}))
+	pluginDataConfig := &PluginDataConfig{APIID: apiId, SelectOperations: map[string]*AIExtensionConfig{}}
+	addSpecSelectOperations(pluginDataConfig, apiDef)
+	// The operations without generated examples rely on their descriptions
+	assert.Equal(t, map[string]*AIExtensionConfig{
+		"listIssues": {InputExamples: []string{"The open issues first", "List the issues"}},
+		"getIssue":   {InputExamples: []string{"show me the issue 42"}},
+	}, pluginDataConfig.SelectOperations)
+
+	// The examples of an operation that changed since are ignored
+	assert.Nil(t, SaveGeneratedExamples(apiId, map[string]GeneratedExamples{
+		"getIssue": {Fingerprint: "stale", Examples: []string{"show me the issue 42"}},
+	}))
+	pluginDataConfig = &PluginDataConfig{APIID: apiId, SelectOperations: map[string]*AIExtensionConfig{}}
+	addSpecSelectOperations(pluginDataConfig, apiDef)
+	assert.Equal(t, map[string]*AIExtensionConfig{
+		"listIssues": {InputExamples: []string{"The open issues first", "List the issues"}},
+		"getIssue":   {InputExamples: []string{"An issue by number", "Get an issue"}},
+	}, pluginDataConfig.SelectOperations)
+}
@@ -0,0 +1,119 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+	"github.com/TykTechnologies/kin-openapi/routers"
+)
+
+const SPEC_EXT_AI_INPUT_EXAMPLES_DISABLED = "x-nl-input-examples-disabled"
+
+// OperationFilter selects operations of the API. An operation matches when
+// any of the lists matches it.
+type OperationFilter struct {
+	OperationIDs []string `json:"operationIds"`
+	Tags         []string `json:"tags"`
+	Methods      []string `json:"methods"`
+	Paths        []string `json:"paths"` // Globs on the OpenAPI paths, "*" matches any characters, "/" included
+
+	pathRegexps []*regexp.Regexp // The Paths, compiled by parseOperationFilter
+}
+
+func parseOperationFilter(configData map[string]any, key string) OperationFilter {
+	operationFilter := OperationFilter{}
+
+	v, exists := configData[key]
+	if !exists {
+		return operationFilter
+	}
+	filterData, ok := v.(map[string]any)
+	if !ok {
+		logger.Warningf("[+] Invalid type for %s: %T; ignoring", key, v)
+		return operationFilter
+	}
+
+	operationFilter.OperationIDs = append(operationFilter.OperationIDs, getConfigStrings(filterData, "operationIds")...)
+	operationFilter.Tags = append(operationFilter.Tags, getConfigStrings(filterData, "tags")...)
+	for _, method := range getConfigStrings(filterData, "methods") {
+		operationFilter.Methods = append(operationFilter.Methods, strings.ToUpper(method))
+	}
+	for _, pathGlob := range getConfigStrings(filterData, "paths") {
+		re, err := globToRegexp(pathGlob)
+		if err != nil {
+			logger.Warningf("[+] Invalid path in %s: %s; ignoring", key, pathGlob)
+			continue
+		}
+		operationFilter.Paths = append(operationFilter.Paths, pathGlob)
+		operationFilter.pathRegexps = append(operationFilter.pathRegexps, re)
+	}
+	return operationFilter
+}
+
+func (filter OperationFilter) isEmpty() bool {
+	return len(filter.OperationIDs) == 0 && len(filter.Tags) == 0 && len(filter.Methods) == 0 && len(filter.Paths) == 0
+}
+
+func (filter OperationFilter) matches(path string, method string, operation *openapi3.Operation) bool {
+	if slices.Contains(filter.OperationIDs, operation.OperationID) {
+		return true
+	}
+	if slices.Contains(filter.Methods, strings.ToUpper(method)) {
+		return true
+	}
+	for _, tag := range operation.Tags {
+		if slices.Contains(filter.Tags, tag) {
+			return true
+		}
+	}
+	for _, re := range filter.pathRegexps {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func globToRegexp(glob string) (*regexp.Regexp, error) {
+	pattern := regexp.QuoteMeta(glob)
+	pattern = strings.ReplaceAll(pattern, `\*`, ".*")
+	pattern = strings.ReplaceAll(pattern, `\?`, ".")
+	return regexp.Compile("^" + pattern + "$")
+}
+
+// isOperationEnabled tells if the operation can be reached with a Natural
+// Language query. It is disabled by the x-nl-input-examples-disabled
+// extension, by excludeOperations, or when it is not in includeOperations.
+func isOperationEnabled(config *PluginDataConfig, path string, method string, operation *openapi3.Operation) bool {
+	if ext, exists := operation.Extensions[SPEC_EXT_AI_INPUT_EXAMPLES_DISABLED]; exists {
+		// The extension is usually the list of the disabled examples
+		if disabled, ok := ext.(bool); !ok || disabled {
+			return false
+		}
+	}
+	if config.ExcludeOperations.matches(path, method, operation) {
+		return false
+	}
+	if !config.IncludeOperations.isEmpty() && !config.IncludeOperations.matches(path, method, operation) {
+		return false
+	}
+	return true
+}
+
+func isRouteEnabled(config *PluginDataConfig, route *routers.Route) bool {
+	return isOperationEnabled(config, route.Path, route.Method, route.Operation)
+}
+
+func newOperationDisabledError(route *routers.Route) error {
+	logger.Debugf("[+] Operation %s is not enabled for Natural Language queries", route.Operation.OperationID)
+	return &nlQueryError{
+		StatusCode: http.StatusNotFound,
+		Message:    "i'm sorry but this operation is not available for natural language queries",
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+	"github.com/TykTechnologies/tyk/apidef/oas"
+	"github.com/TykTechnologies/tyk/ctx"
+	"github.com/stretchr/testify/assert"
+)
+
+const operationFilterTestSpec = `{
+	"openapi": "3.0.0",
+	"info": {"title": "Jira", "version": "1.0.0"},
+	"paths": {
+		"/rest/api/3/issue/{id}": {
+			"get": {"operationId": "getIssue", "tags": ["Issues"], "x-nl-input-examples": ["show the issue"], "responses": {"200": {"description": "OK"}}},
+			"delete": {"operationId": "deleteIssue", "tags": ["Issues"], "x-nl-input-examples": ["delete the issue"], "responses": {"204": {"description": "Deleted"}}}
+		},
+		"/rest/api/3/issuetype": {
+			"get": {"operationId": "getIssueTypes", "tags": ["Issue types"], "x-nl-input-examples-disabled": ["list the kinds of issues"], "responses": {"200": {"description": "OK"}}},
+			"post": {"operationId": "createIssueType", "tags": ["Issue types"], "x-nl-input-examples-disabled": false, "x-nl-input-examples": ["create a kind of issue"], "responses": {"201": {"description": "Created"}}}
+		},
+		"/rest/agile/1.0/board": {
+			"get": {"operationId": "getBoards", "tags": ["Boards"], "x-nl-input-examples": ["list the boards"], "responses": {"200": {"description": "OK"}}}
+		}
+	}
+}`
+
+func TestIsOperationEnabled(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(operationFilterTestSpec))
+	assert.Nil(t, err)
+
+	tests := []struct {
+		description string
+		config      *PluginDataConfig
+		expected    []string
+	}{
+		{
+			"Only the extension",
+			&PluginDataConfig{},
+			[]string{"createIssueType", "deleteIssue", "getBoards", "getIssue"},
+		},
+		{
+			"Exclude by method",
+			&PluginDataConfig{ExcludeOperations: OperationFilter{Methods: []string{http.MethodDelete}}},
+			[]string{"createIssueType", "getBoards", "getIssue"},
+		},
+		{
+			"Include by path glob",
+			&PluginDataConfig{IncludeOperations: parseOperationFilter(map[string]any{"includeOperations": map[string]any{"paths": []any{"/rest/api/3/*"}}}, "includeOperations")},
+			[]string{"createIssueType", "deleteIssue", "getIssue"},
+		},
+		{
+			"Include by tag, exclude by operationId",
+			&PluginDataConfig{
+				IncludeOperations: OperationFilter{Tags: []string{"Issues", "Boards"}},
+				ExcludeOperations: OperationFilter{OperationIDs: []string{"getBoards"}},
+			},
+			[]string{"deleteIssue", "getIssue"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			enabled := []string{}
+			for path, pathItem := range doc.Paths {
+				for method, operation := range pathItem.Operations() {
+					if isOperationEnabled(tt.config, path, method, operation) {
+						enabled = append(enabled, operation.OperationID)
+					}
+				}
+			}
+			assert.ElementsMatch(t, tt.expected, enabled)
+		})
+	}
+}
+
+func TestDisabledOperationIsNotReachable(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(operationFilterTestSpec))
+	assert.Nil(t, err)
+	oasDef := &oas.OAS{T: *doc}
+	oasDef.SetTykExtension(&oas.XTykAPIGateway{
+		Info:   oas.Info{ID: "jira-filter"},
+		Server: oas.Server{ListenPath: oas.ListenPath{Value: "/jira/"}},
+	})
+
+	pluginConfigLock.Lock()
+	pluginConfig["jira-filter"] = &PluginDataConfig{APIID: "jira-filter", ExcludeOperations: OperationFilter{Methods: []string{http.MethodDelete}}}
+	pluginConfigLock.Unlock()
+	defer func() {
+		pluginConfigLock.Lock()
+		delete(pluginConfig, "jira-filter")
+		pluginConfigLock.Unlock()
+	}()
+
+	r := httptest.NewRequest(http.MethodDelete, "/jira/rest/api/3/issue/10", strings.NewReader("delete the issue 10"))
+	r = r.WithContext(context.WithValue(r.Context(), ctx.OASDefinition, oasDef))
+
+	err = rewriteQueryForRoute(r, getRouteForOperation(oasDef, "deleteIssue"), map[string]string{"id": "10"})
+	queryError := &nlQueryError{}
+	assert.ErrorAs(t, err, &queryError)
+	assert.Equal(t, http.StatusNotFound, queryError.StatusCode)
+}
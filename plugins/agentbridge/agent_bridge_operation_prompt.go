@@ -0,0 +1,209 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+)
+
+const (
+	DEFAULT_MAX_OPERATION_PROMPT_TOKENS = 4000
+	CHARS_PER_TOKEN                     = 4   // Average size of a token for the JSON of the specs
+	MAX_COMPACT_SCHEMA_DEPTH            = 4   // Nesting levels of the schemas kept by the first compact rendering
+	MAX_COMPACT_DESCRIPTION_LENGTH      = 200 // In characters
+)
+
+// compactOperation is the operation given to the LLM when the full one is too
+// large. Unlike openapi3.Operation, it has no responses key. The fields are
+// in the order of the keys of openapi3.Operation.MarshalJSON.
+type compactOperation struct {
+	Deprecated  bool                     `json:"deprecated,omitempty"`
+	Description string                   `json:"description,omitempty"`
+	OperationID string                   `json:"operationId,omitempty"`
+	Parameters  openapi3.Parameters      `json:"parameters,omitempty"`
+	RequestBody *openapi3.RequestBodyRef `json:"requestBody,omitempty"`
+	Summary     string                   `json:"summary,omitempty"`
+}
+
+// estimateTokens measures the size of a prompt in tokens, without the
+// tokenizer of the model.
+func estimateTokens(text string) int {
+	return (len(text) + CHARS_PER_TOKEN - 1) / CHARS_PER_TOKEN
+}
+
+// buildBudgetedOperationString renders the operation for the LLM in at most
+// maxTokens tokens (no limit when 0). The operation is rendered as is when it
+// fits; otherwise the response schemas are dropped, the references are
+// inlined and the schemas are collapsed deeper than a decreasing depth, then
+// only the required fields of the nested objects are kept.
+func buildBudgetedOperationString(operation *openapi3.Operation, maxTokens int) (string, error) {
+	operationString, err := buildOperationString(operation)
+	if err != nil || maxTokens <= 0 || estimateTokens(operationString) <= maxTokens {
+		return operationString, err
+	}
+	logger.Debugf("[+] The operation %s needs %d tokens, compacting it to %d", operation.OperationID, estimateTokens(operationString), maxTokens)
+
+	smallest := operationString
+	for depth := MAX_COMPACT_SCHEMA_DEPTH; depth >= 1; depth-- {
+		for _, requiredOnly := range []bool{false, true} {
+			compactString, err := buildCompactOperationString(operation, depth, requiredOnly)
+			if err != nil {
+				return "", err
+			}
+			if estimateTokens(compactString) <= maxTokens {
+				return compactString, nil
+			}
+			if len(compactString) < len(smallest) {
+				smallest = compactString
+			}
+		}
+	}
+	logger.Warningf("[+] The operation %s needs %d tokens, more than maxOperationPromptTokens (%d)", operation.OperationID, estimateTokens(smallest), maxTokens)
+	return smallest, nil
+}
+
+// buildCompactOperationString renders the operation without its responses,
+// with the schemas inlined up to maxDepth nested levels. Only the media type
+// of the request body used by the rewrite is kept.
+func buildCompactOperationString(operation *openapi3.Operation, maxDepth int, requiredOnly bool) (string, error) {
+	operation = applyNaturalLanguageDescription(operation)
+	compact := &compactOperation{
+		OperationID: operation.OperationID,
+		Summary:     operation.Summary,
+		Description: truncateDescription(operation.Description),
+		Deprecated:  operation.Deprecated,
+	}
+
+	for _, parameterRef := range operation.Parameters {
+		if parameterRef == nil || parameterRef.Value == nil {
+			continue
+		}
+		parameter := *parameterRef.Value
+		parameter.Extensions = nil
+		parameter.Description = truncateDescription(parameter.Description)
+		parameter.Example = nil
+		parameter.Examples = nil
+		parameter.Schema = compactSchemaRef(parameter.Schema, 0, maxDepth, requiredOnly)
+		parameter.Content = compactContent(parameter.Content, maxDepth, requiredOnly)
+		compact.Parameters = append(compact.Parameters, &openapi3.ParameterRef{Value: &parameter})
+	}
+
+	if operation.RequestBody != nil && operation.RequestBody.Value != nil {
+		content := operation.RequestBody.Value.Content
+		if mediaType := getRequestBodyContentType(operation); content.Get(mediaType) != nil {
+			content = openapi3.Content{mediaType: content.Get(mediaType)}
+		}
+		compact.RequestBody = &openapi3.RequestBodyRef{Value: &openapi3.RequestBody{
+			Description: truncateDescription(operation.RequestBody.Value.Description),
+			Required:    operation.RequestBody.Value.Required,
+			Content:     compactContent(content, maxDepth, requiredOnly),
+		}}
+	}
+
+	operationString, err := json.Marshal(compact)
+	if err != nil {
+		return "", err
+	}
+	return string(operationString) + "\n", nil
+}
+
+func compactContent(content openapi3.Content, maxDepth int, requiredOnly bool) openapi3.Content {
+	if content == nil {
+		return nil
+	}
+	compact := openapi3.Content{}
+	for mediaType, media := range content {
+		if media == nil {
+			continue
+		}
+		compact[mediaType] = &openapi3.MediaType{Schema: compactSchemaRef(media.Schema, 0, maxDepth, requiredOnly)}
+	}
+	return compact
+}
+
+// compactSchemaRef inlines the schema, without the nested levels from
+// maxDepth and, with requiredOnly, without the optional properties of the
+// nested objects. The depth grows with every keyword, so recursive schemas
+// are cut too.
+func compactSchemaRef(schemaRef *openapi3.SchemaRef, depth int, maxDepth int, requiredOnly bool) *openapi3.SchemaRef {
+	if schemaRef == nil || schemaRef.Value == nil {
+		return nil
+	}
+	schema := schemaRef.Value
+	compact := &openapi3.Schema{
+		Type:        schema.Type,
+		Format:      schema.Format,
+		Description: truncateDescription(schema.Description),
+		Enum:        schema.Enum,
+		Default:     schema.Default,
+		Nullable:    schema.Nullable,
+		Required:    schema.Required,
+	}
+
+	nested := len(schema.Properties) > 0 || schema.Items != nil || schema.AdditionalProperties.Schema != nil ||
+		len(schema.AllOf) > 0 || len(schema.AnyOf) > 0 || len(schema.OneOf) > 0
+	if !nested {
+		return &openapi3.SchemaRef{Value: compact}
+	}
+	if depth >= maxDepth {
+		compact.Required = nil
+		compact.Description = appendDescriptionNote(compact.Description, "nested fields omitted")
+		return &openapi3.SchemaRef{Value: compact}
+	}
+
+	compact.Items = compactSchemaRef(schema.Items, depth+1, maxDepth, requiredOnly)
+	compact.AdditionalProperties.Schema = compactSchemaRef(schema.AdditionalProperties.Schema, depth+1, maxDepth, requiredOnly)
+	compact.AllOf = compactSchemaRefs(schema.AllOf, depth+1, maxDepth, requiredOnly)
+	compact.AnyOf = compactSchemaRefs(schema.AnyOf, depth+1, maxDepth, requiredOnly)
+	compact.OneOf = compactSchemaRefs(schema.OneOf, depth+1, maxDepth, requiredOnly)
+
+	omitted := 0
+	for name, property := range schema.Properties {
+		// The fields of the request itself are all kept
+		if requiredOnly && depth > 0 && !slices.Contains(schema.Required, name) {
+			omitted++
+			continue
+		}
+		if compact.Properties == nil {
+			compact.Properties = openapi3.Schemas{}
+		}
+		compact.Properties[name] = compactSchemaRef(property, depth+1, maxDepth, requiredOnly)
+	}
+	if omitted > 0 {
+		compact.Description = appendDescriptionNote(compact.Description, fmt.Sprintf("%d optional fields omitted", omitted))
+	}
+	return &openapi3.SchemaRef{Value: compact}
+}
+
+func compactSchemaRefs(schemaRefs openapi3.SchemaRefs, depth int, maxDepth int, requiredOnly bool) openapi3.SchemaRefs {
+	var compact openapi3.SchemaRefs
+	for _, schemaRef := range schemaRefs {
+		if compactRef := compactSchemaRef(schemaRef, depth, maxDepth, requiredOnly); compactRef != nil {
+			compact = append(compact, compactRef)
+		}
+	}
+	return compact
+}
+
+func truncateDescription(description string) string {
+	description = strings.TrimSpace(description)
+	if len(description) <= MAX_COMPACT_DESCRIPTION_LENGTH {
+		return description
+	}
+	// Don't cut a multi-byte character
+	cut := strings.ToValidUTF8(description[:MAX_COMPACT_DESCRIPTION_LENGTH], "")
+	return strings.TrimSpace(cut) + "..."
+}
+
+func appendDescriptionNote(description string, note string) string {
+	if description == "" {
+		return "(" + note + ")"
+	}
+	return description + " (" + note + ")"
+}
@@ -0,0 +1,139 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+const operationPromptTestSpec = `{
+	"openapi": "3.0.0",
+	"info": {"title": "Users", "version": "1.0.0"},
+	"paths": {
+		"/users": {
+			"post": {
+				"operationId": "createUser",
+				"summary": "Create a user",
+				"requestBody": {
+					"content": {
+						"application/json": {"schema": {"$ref": "#/components/schemas/User"}},
+						"application/xml": {"schema": {"$ref": "#/components/schemas/User"}}
+					}
+				},
+				"responses": {
+					"201": {"description": "Created", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/User"}}}}
+				}
+			}
+		}
+	},
+	"components": {
+		"schemas": {
+			"User": {
+				"type": "object",
+				"required": ["name"],
+				"properties": {
+					"name": {"type": "string"},
+					"nickname": {"type": "string", "description": "` + "How the user is called by their friends. This description has many more details than needed, to make it long enough to be truncated by the compact rendering of the operations, which keeps the first 200 characters of the descriptions" + `"},
+					"address": {"$ref": "#/components/schemas/Address"},
+					"manager": {"$ref": "#/components/schemas/User"}
+				}
+			},
+			"Address": {
+				"type": "object",
+				"required": ["city"],
+				"properties": {
+					"city": {"type": "string"},
+					"street": {"type": "string"},
+					"location": {"type": "object", "properties": {"lat": {"type": "number"}, "lon": {"type": "number"}}}
+				}
+			}
+		}
+	}
+}`
+
+func TestBuildBudgetedOperationString(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(operationPromptTestSpec))
+	assert.Nil(t, err)
+	operation := doc.Paths["/users"].Post
+
+	full, err := buildOperationString(operation)
+	assert.Nil(t, err)
+
+	// The operation is rendered as is when it fits, or without limit
+	got, err := buildBudgetedOperationString(operation, estimateTokens(full))
+	assert.Nil(t, err)
+	assert.Equal(t, full, got)
+	got, err = buildBudgetedOperationString(operation, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, full, got)
+
+	// Otherwise the responses are dropped and the references inlined
+	got, err = buildBudgetedOperationString(operation, estimateTokens(full)-1)
+	assert.Nil(t, err)
+	assert.LessOrEqual(t, estimateTokens(got), estimateTokens(full)-1)
+	assert.NotContains(t, got, "responses")
+	assert.NotContains(t, got, "$ref")
+	assert.NotContains(t, got, "application/xml")
+
+	compact, err := buildCompactOperationString(operation, MAX_COMPACT_SCHEMA_DEPTH, false)
+	assert.Nil(t, err)
+	assert.Contains(t, compact, `"location":{"properties":{"lat":{"type":"number"},"lon":{"type":"number"}},"type":"object"}`)
+
+	// The recursive schema is cut
+	compact, err = buildCompactOperationString(operation, 2, false)
+	assert.Nil(t, err)
+	assert.Contains(t, compact, `"location":{"description":"(nested fields omitted)","type":"object"}`)
+	assert.Contains(t, compact, `"manager":{"properties":{"address":{"description":"(nested fields omitted)","type":"object"}`)
+	assert.Contains(t, compact, "which keeps the first 20...\"")
+
+	// The required fields of the nested objects are kept first
+	compact, err = buildCompactOperationString(operation, 2, true)
+	assert.Nil(t, err)
+	assert.Contains(t, compact, `"address":{"description":"(2 optional fields omitted)","properties":{"city":{"type":"string"}},"required":["city"],"type":"object"}`)
+	// All the fields of the request body are kept
+	assert.Contains(t, compact, `"nickname":`)
+
+	// The smallest rendering is used when nothing fits
+	got, err = buildBudgetedOperationString(operation, 1)
+	assert.Nil(t, err)
+	smallest, _ := buildCompactOperationString(operation, 1, true)
+	assert.Equal(t, smallest, got)
+}
+
+func TestBuildBudgetedOperationStringConfigs(t *testing.T) {
+	const maxTokens = 2000
+
+	specFilenames, err := filepath.Glob("../../configs/*.oas.json")
+	assert.Nil(t, err)
+	for _, specFilename := range specFilenames {
+		doc, err := openapi3.NewLoader().LoadFromFile(specFilename)
+		if err != nil {
+			// The loader doesn't handle every recursive schema
+			t.Logf("Skipping %s: %s", specFilename, err)
+			continue
+		}
+		t.Run(filepath.Base(specFilename), func(t *testing.T) {
+			for path, pathItem := range doc.Paths {
+				for method, operation := range pathItem.Operations() {
+					full, err := buildOperationString(operation)
+					assert.Nil(t, err)
+					got, err := buildBudgetedOperationString(operation, maxTokens)
+					assert.Nil(t, err)
+					assert.LessOrEqual(t, estimateTokens(got), maxTokens, "%s %s", method, path)
+					if estimateTokens(full) <= maxTokens {
+						assert.Equal(t, full, got, "%s %s", method, path)
+					} else {
+						assert.True(t, strings.HasPrefix(got, "{"), "%s %s", method, path)
+						assert.Contains(t, got, `"operationId":"`+operation.OperationID+`"`)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,369 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/TykTechnologies/kin-openapi/routers"
+	"github.com/TykTechnologies/tyk/apidef/oas"
+)
+
+const (
+	DEFAULT_MAX_PLAN_STEPS      = 5
+	DEFAULT_PLANNER_GATEWAY_URL = "http://localhost:8080"
+	PLANNER_CANDIDATES          = 10   // Operations given to the LLM as tools
+	MAX_STEP_RESPONSE_LENGTH    = 4000 // In characters, for the LLM and the trace
+	PLANNER_HTTP_TIMEOUT        = 60 * time.Second
+	MAX_TOOL_NAME_LENGTH        = 64
+	TOOL_NAME_HASH_LENGTH       = 8 // Hex characters of the hash added to the changed tool names
+)
+
+var tmplPlannerSystemPrompt *template.Template
+
+type TmplPromptPlanner struct {
+	MaxSteps int
+}
+
+// planStep is one call made by the planner, as reported in the trace.
+type planStep struct {
+	Step        int    `json:"step"`
+	OperationID string `json:"operationId"`
+	Method      string `json:"method,omitempty"`
+	URL         string `json:"url,omitempty"`
+	StatusCode  int    `json:"status,omitempty"`
+	Response    string `json:"response,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// planResponse is sent back to the client once the plan is over.
+type planResponse struct {
+	Answer   string     `json:"answer"`
+	Complete bool       `json:"complete"` // False when the step limit was reached
+	Steps    []planStep `json:"steps"`
+}
+
+// planExecutor sends the request of one step and fills the step with the outcome.
+type planExecutor func(ctx context.Context, route *routers.Route, params *openAPIOperationParams, step *planStep)
+
+func isPlannerEnabled(r *http.Request) bool {
+	return isEnabled(r.Header.Get(HEADER_X_NL_PLANNER))
+}
+
+// getRouteForOperation finds the route of an operation in the API definition.
+func getRouteForOperation(apidef *oas.OAS, operationID string) *routers.Route {
+	for path, pathItem := range apidef.Paths {
+		for method, operation := range pathItem.Operations() {
+			if operation.OperationID == operationID {
+				return &routers.Route{
+					Spec:      &apidef.T,
+					Path:      path,
+					PathItem:  pathItem,
+					Method:    method,
+					Operation: operation,
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// getPlannerRoutes returns the routes of the candidates matching the query
+// enough to be called by the planner.
+func getPlannerRoutes(apidef *oas.OAS, candidates []OperationCandidate, relevanceThreshold float64) []*routers.Route {
+	routes := []*routers.Route{}
+	for _, candidate := range candidates {
+		if candidate.Relevance < relevanceThreshold {
+			logger.Debugf("[+] Operation %s is not relevant enough for the plan: %f", candidate.OperationID, candidate.Relevance)
+			continue
+		}
+		if route := getRouteForOperation(apidef, candidate.OperationID); route != nil {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// getToolName turns the operationId into a valid tool name for the LLM APIs,
+// made of at most 64 letters, digits, '_' and '-'. Names changed or already
+// taken get a hash of the operationId.
+func getToolName(operationID string, taken map[string]*routers.Route) string {
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, operationID)
+	if name == "" {
+		name = "operation"
+	}
+	if _, present := taken[name]; !present && len(name) <= MAX_TOOL_NAME_LENGTH {
+		return name
+	}
+
+	hash := sha256.Sum256([]byte(operationID))
+	suffix := "_" + hex.EncodeToString(hash[:])[:TOOL_NAME_HASH_LENGTH]
+	return name[:min(len(name), MAX_TOOL_NAME_LENGTH-len(suffix))] + suffix
+}
+
+// processPlan answers a query needing several operations of the API. The
+// LLM chooses the operations, the outputs of a call can be used by the next ones.
+func processPlan(rw http.ResponseWriter, r *http.Request, config *PluginDataConfig, nlq string) {
+	apidef := getOASDefinition(r)
+	if apidef == nil {
+		logger.Errorf("[+] processPlan: API definition is nil")
+		http.Error(rw, INTERNAL_ERROR_MSG, http.StatusInternalServerError)
+		return
+	}
+
+	candidates, err := FindSelectOperationCandidates(r.Context(), config.APIID, nlq, PLANNER_CANDIDATES)
+	if err != nil {
+		logger.Errorf("[+] Error while selecting operations: %s", err)
+		http.Error(rw, INTERNAL_ERROR_MSG, http.StatusInternalServerError)
+		return
+	}
+	routes := getPlannerRoutes(apidef, candidates, config.RelevanceThreshold)
+	if len(routes) == 0 {
+		logger.Debugf("[+] No matching operation found")
+		http.Error(rw, "No matching operation found", http.StatusNotFound)
+		return
+	}
+
+	executor := newGatewayPlanExecutor(r, config)
+	steps, complete, err := runPlan(r.Context(), config, routes, nlq, executor)
+	if err != nil {
+		logger.Errorf("[+] Error while running the plan: %s", err)
+		http.Error(rw, INTERNAL_ERROR_MSG, http.StatusInternalServerError)
+		return
+	}
+
+	answer, err := responseToNL(r, formatPlanTrace(steps, complete))
+	if err != nil {
+		logger.Errorf("[+] Error while converting the plan to Natural Language: %s", err)
+		http.Error(rw, INTERNAL_ERROR_MSG, http.StatusInternalServerError)
+		return
+	}
+
+	response, err := json.Marshal(planResponse{Answer: answer, Complete: complete, Steps: steps})
+	if err != nil {
+		logger.Errorf("[+] Error while marshalling the plan: %s", err)
+		http.Error(rw, INTERNAL_ERROR_MSG, http.StatusInternalServerError)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	_, _ = rw.Write(response)
+}
+
+// runPlan lets the LLM call the operations, as tools, until it has the answer
+// or the step limit is reached.
+func runPlan(ctx context.Context, config *PluginDataConfig, routes []*routers.Route, nlq string, execute planExecutor) ([]planStep, bool, error) {
+	if config.LlmConfig == nil || config.LlmConfig.provider == nil {
+		return nil, false, fmt.Errorf("no LLM configured")
+	}
+
+	tools := []LLMTool{}
+	routesByTool := map[string]*routers.Route{}
+	for _, route := range routes {
+		operationString, err := buildBudgetedOperationString(route.Operation, config.MaxOperationPromptTokens)
+		if err != nil {
+			logger.Warningf("[+] Error while building operation string for %s: %s", route.Operation.OperationID, err)
+			continue
+		}
+		parameters, err := buildStructuredOASResponse(route.Operation)
+		if err != nil {
+			logger.Warningf("[+] Error while building the request schema for %s: %s", route.Operation.OperationID, err)
+			continue
+		}
+		toolName := getToolName(route.Operation.OperationID, routesByTool)
+		tools = append(tools, LLMTool{
+			Name:        toolName,
+			Description: fmt.Sprintf("%s %s\n%s", route.Method, route.Path, operationString),
+			Parameters:  parameters,
+		})
+		routesByTool[toolName] = route
+	}
+
+	systemPromptBuf := new(bytes.Buffer)
+	if err := tmplPlannerSystemPrompt.Execute(systemPromptBuf, TmplPromptPlanner{MaxSteps: config.MaxPlanSteps}); err != nil {
+		return nil, false, fmt.Errorf("error while creating the system prompt: %w", err)
+	}
+	messages := []LLMMessage{
+		{Role: LLM_ROLE_SYSTEM, Content: systemPromptBuf.String()},
+		{Role: LLM_ROLE_USER, Content: nlq},
+	}
+
+	steps := []planStep{}
+	// One more round to let the LLM conclude after the last step
+	for round := 0; round <= config.MaxPlanSteps; round++ {
+		resp, err := config.LlmConfig.provider.ChatWithTools(ctx, messages, tools)
+		if err != nil {
+			return steps, false, fmt.Errorf("failed to query LLM: %w", err)
+		}
+		if resp.Done || len(resp.ToolCalls) == 0 {
+			logger.Debugf("[+] Plan is over after %d steps", len(steps))
+			return steps, true, nil
+		}
+		if len(steps) >= config.MaxPlanSteps {
+			logger.Debugf("[+] Plan reached the limit of %d steps", config.MaxPlanSteps)
+			return steps, false, nil
+		}
+
+		messages = append(messages, LLMMessage{Role: LLM_ROLE_ASSISTANT, Content: resp.Content, ToolCalls: resp.ToolCalls})
+		for _, toolCall := range resp.ToolCalls {
+			if len(steps) >= config.MaxPlanSteps {
+				messages = append(messages, LLMMessage{Role: LLM_ROLE_TOOL, Content: "The step limit is reached, this operation was not called", ToolCallID: toolCall.ID})
+				continue
+			}
+
+			route := routesByTool[toolCall.Name]
+			step := planStep{Step: len(steps) + 1, OperationID: toolCall.Name}
+			if route != nil {
+				step.OperationID = route.Operation.OperationID
+			}
+			runPlanStep(ctx, config, route, toolCall.Arguments, execute, &step)
+			steps = append(steps, step)
+
+			content := step.Response
+			if step.Error != "" {
+				content = "Error: " + step.Error
+			} else if step.StatusCode != 0 {
+				content = fmt.Sprintf("%d %s", step.StatusCode, step.Response)
+			}
+			messages = append(messages, LLMMessage{Role: LLM_ROLE_TOOL, Content: content, ToolCallID: toolCall.ID})
+		}
+	}
+
+	return steps, false, nil
+}
+
+func runPlanStep(ctx context.Context, config *PluginDataConfig, route *routers.Route, arguments string, execute planExecutor, step *planStep) {
+	if route == nil {
+		step.Error = "unknown operation"
+		return
+	}
+
+	params := &openAPIOperationParams{}
+	if err := json.Unmarshal([]byte(arguments), params); err != nil {
+		step.Error = fmt.Sprintf("invalid arguments: %s", err)
+		return
+	}
+
+	// Actions needing a human confirmation can't be chained
+	if requiresConfirmation(config, route) {
+		step.Error = "this operation requires a confirmation and can't be part of a plan"
+		return
+	}
+
+	if config.ValidateRequests {
+		r, err := http.NewRequestWithContext(ctx, route.Method, route.Path, nil)
+		if err != nil {
+			step.Error = err.Error()
+			return
+		}
+		if validationErrors := validateOpenAPIParams(r, route, map[string]string{}, params); len(validationErrors) > 0 {
+			step.Error = "invalid request: " + strings.Join(validationErrors, "; ")
+			return
+		}
+	}
+
+	execute(ctx, route, params, step)
+}
+
+// newGatewayPlanExecutor sends the requests back to the gateway, with the
+// credentials of the original request, so the API policies still apply.
+func newGatewayPlanExecutor(r *http.Request, config *PluginDataConfig) planExecutor {
+	client := &http.Client{Timeout: PLANNER_HTTP_TIMEOUT}
+
+	return func(ctx context.Context, route *routers.Route, params *openAPIOperationParams, step *planStep) {
+		baseURL := strings.TrimSuffix(config.PlannerGatewayURL, "/") + strings.TrimSuffix(config.ListenPath, "/")
+		req, err := http.NewRequestWithContext(ctx, route.Method, baseURL+route.Path, nil)
+		if err != nil {
+			step.Error = err.Error()
+			return
+		}
+
+		// The credentials of the original request, including the API keys of the spec
+		secretHeaders, secretQueryParams := getSecretParams(route)
+		for _, name := range secretHeaders {
+			if values := r.Header.Values(name); len(values) > 0 {
+				req.Header[name] = slices.Clone(values)
+			}
+		}
+		query := url.Values{}
+		for _, name := range secretQueryParams {
+			if values, present := r.URL.Query()[name]; present {
+				query[name] = values
+			}
+		}
+		req.URL.RawQuery = query.Encode()
+
+		if err := applyOpenAPIParams(req, route, map[string]string{}, params); err != nil {
+			step.Error = "invalid request: " + err.Error()
+			return
+		}
+		// The URL was built before the path parameters were set
+		req.URL.RawPath = ""
+
+		step.Method = req.Method
+		step.URL = maskQueryParams(req.URL, secretQueryParams).String()
+
+		res, err := client.Do(req)
+		if err != nil {
+			step.Error = err.Error()
+			return
+		}
+		defer res.Body.Close()
+
+		body, err := io.ReadAll(io.LimitReader(res.Body, MAX_STEP_RESPONSE_LENGTH))
+		if err != nil {
+			step.Error = err.Error()
+			return
+		}
+		step.StatusCode = res.StatusCode
+		step.Response = string(body)
+	}
+}
+
+// formatPlanTrace describes the steps for the final Natural Language answer.
+func formatPlanTrace(steps []planStep, complete bool) string {
+	var sb strings.Builder
+	for _, step := range steps {
+		if step.Error != "" {
+			fmt.Fprintf(&sb, "Step %d, %s: error %s\n", step.Step, step.OperationID, step.Error)
+			continue
+		}
+		fmt.Fprintf(&sb, "Step %d, %s: %d %s\n", step.Step, step.OperationID, step.StatusCode, step.Response)
+	}
+	if !complete {
+		sb.WriteString("The plan was stopped before the end, the step limit was reached.\n")
+	}
+	return sb.String()
+}
+
+func initPlannerTemplates() {
+	var err error
+
+	systemPrompt := `You answer the user's request by calling the operations of an API, given as tools.
+When the request needs several operations, call them one after the other and use the output of the previous calls (ids, names, ...) as input of the next ones.
+You MUST use the exact name of the parameters. DO NOT invent values that are neither in the request nor in the previous outputs.
+You can make at most {{.MaxSteps}} calls. When you have everything you need, stop calling tools and give a short summary of what was done.`
+
+	tmplPlannerSystemPrompt, err = template.New("system_prompt_planner").Parse(systemPrompt)
+	if err != nil {
+		logger.Fatalf("[+] Error parsing the planner system prompt template: %s", err)
+	}
+}
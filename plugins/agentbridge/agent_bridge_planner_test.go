@@ -0,0 +1,234 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+	"github.com/TykTechnologies/kin-openapi/routers"
+	"github.com/TykTechnologies/tyk/apidef/oas"
+	"github.com/stretchr/testify/assert"
+)
+
+const plannerTestSpec = `{
+	"openapi": "3.0.0",
+	"info": {"title": "Issues", "version": "1.0.0"},
+	"paths": {
+		"/issues": {
+			"get": {
+				"operationId": "searchIssues",
+				"parameters": [{"name": "title", "in": "query", "required": true, "schema": {"type": "string"}}],
+				"responses": {"200": {"description": "OK"}}
+			}
+		},
+		"/issues/{id}/comments": {
+			"post": {
+				"operationId": "addComment",
+				"parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "integer"}}],
+				"requestBody": {"required": true, "content": {"application/json": {"schema": {"type": "object"}}}},
+				"responses": {"201": {"description": "Created"}}
+			}
+		}
+	},
+	"components": {
+		"securitySchemes": {
+			"headerKey": {"type": "apiKey", "in": "header", "name": "X-Issues-Key"},
+			"queryKey": {"type": "apiKey", "in": "query", "name": "key"}
+		}
+	}
+}`
+
+func searchThenComment() []*LLMToolResponse {
+	return []*LLMToolResponse{
+		{ToolCalls: []LLMToolCall{{ID: "1", Name: "searchIssues", Arguments: `{"in_query_params": {"title": ["Crash on startup"]}}`}}},
+		{ToolCalls: []LLMToolCall{{ID: "2", Name: "addComment", Arguments: `{"in_path_params": {"id": "42"}, "request_body": {"body": "Fixed"}}`}}},
+		{Content: "The comment was added to the issue 42", Done: true},
+	}
+}
+
+func TestRunPlan(t *testing.T) {
+	tests := []struct {
+		description      string
+		maxPlanSteps     int
+		toolResponses    []*LLMToolResponse
+		expectedSteps    []string
+		expectedComplete bool
+	}{
+		{"Chain two operations", 5, searchThenComment(), []string{"searchIssues", "addComment"}, true},
+		{"Step limit reached", 1, searchThenComment(), []string{"searchIssues"}, false},
+		{
+			"Invalid step is reported to the LLM",
+			5,
+			[]*LLMToolResponse{
+				{ToolCalls: []LLMToolCall{{ID: "1", Name: "addComment", Arguments: `{"in_path_params": {"id": "latest"}, "request_body": {}}`}}},
+				{Content: "I could not add the comment", Done: true},
+			},
+			[]string{"addComment"},
+			true,
+		},
+	}
+
+	routes := []*routers.Route{
+		getTestRoute(t, plannerTestSpec, "/issues", http.MethodGet),
+		getTestRoute(t, plannerTestSpec, "/issues/{id}/comments", http.MethodPost),
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			fake := &fakeLLMProvider{toolResponses: tt.toolResponses}
+			config := &PluginDataConfig{
+				LlmConfig:        &NLAPIConfig{provider: fake},
+				MaxPlanSteps:     tt.maxPlanSteps,
+				ValidateRequests: true,
+			}
+
+			executed := []string{}
+			executor := func(ctx context.Context, route *routers.Route, params *openAPIOperationParams, step *planStep) {
+				executed = append(executed, route.Operation.OperationID)
+				step.StatusCode = http.StatusOK
+				step.Response = `{"id": 42}`
+			}
+
+			steps, complete, err := runPlan(context.TODO(), config, routes, "add the comment 'Fixed' to the issue titled 'Crash on startup'", executor)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expectedComplete, complete)
+
+			operations := []string{}
+			for _, step := range steps {
+				operations = append(operations, step.OperationID)
+			}
+			assert.Equal(t, tt.expectedSteps, operations)
+			assert.Len(t, fake.messages[0], 2) // The system prompt and the query
+
+			// The output of a step is given to the LLM for the next one
+			if len(fake.messages) > 1 {
+				lastMessage := fake.messages[1][len(fake.messages[1])-1]
+				assert.Equal(t, LLM_ROLE_TOOL, lastMessage.Role)
+				if steps[0].Error == "" {
+					assert.Equal(t, `200 {"id": 42}`, lastMessage.Content)
+				} else {
+					assert.Contains(t, lastMessage.Content, "invalid request")
+					assert.Empty(t, executed)
+				}
+			}
+		})
+	}
+}
+
+func TestGatewayPlanExecutor(t *testing.T) {
+	var received *http.Request
+	var receivedBody string
+	gateway := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ := io.ReadAll(r.Body)
+		receivedBody = string(body)
+		rw.WriteHeader(http.StatusCreated)
+		_, _ = rw.Write([]byte(`{"id": 7}`))
+	}))
+	defer gateway.Close()
+
+	original := httptest.NewRequest(http.MethodPost, "/issues-api/?key=secret", nil)
+	original.Header.Set("Authorization", "Bearer token")
+	original.Header.Set("X-Issues-Key", "secret")
+	original.Header.Set("X-Debug", "true")
+	original.Header.Set("Content-Type", CONTENT_TYPE_NLQ)
+	config := &PluginDataConfig{PlannerGatewayURL: gateway.URL, ListenPath: "/issues-api/"}
+
+	route := getTestRoute(t, plannerTestSpec, "/issues/{id}/comments", http.MethodPost)
+	params := &openAPIOperationParams{InPathParams: map[string]string{"id": "42"}, RequestBody: json.RawMessage(`{"body": "Fixed"}`)}
+	step := &planStep{Step: 1, OperationID: "addComment"}
+	newGatewayPlanExecutor(original, config)(context.TODO(), route, params, step)
+
+	assert.Empty(t, step.Error)
+	assert.Equal(t, http.StatusCreated, step.StatusCode)
+	assert.Equal(t, `{"id": 7}`, step.Response)
+	assert.Equal(t, gateway.URL+"/issues-api/issues/42/comments?key=%2A%2A%2A%2A%2A%2A%2A%2A", step.URL)
+
+	assert.Equal(t, "/issues-api/issues/42/comments", received.URL.Path)
+	assert.Equal(t, "Bearer token", received.Header.Get("Authorization"))
+	// The API keys of the spec are forwarded too, not the other headers
+	assert.Equal(t, "secret", received.Header.Get("X-Issues-Key"))
+	assert.Equal(t, "secret", received.URL.Query().Get("key"))
+	assert.Empty(t, received.Header.Get("X-Debug"))
+	assert.Equal(t, "application/json", received.Header.Get("Content-Type"))
+	assert.Equal(t, `{"body":"Fixed"}`, receivedBody)
+}
+
+func TestRunPlanToolNames(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromFile("../../configs/gmail.googleapis.com.oas.json")
+	assert.Nil(t, err)
+	pathItem := doc.Paths["/gmail/v1/users/{userId}/drafts"]
+	assert.Equal(t, "gmail.users.drafts.list", pathItem.Get.OperationID)
+	routes := []*routers.Route{{Spec: doc, Path: "/gmail/v1/users/{userId}/drafts", PathItem: pathItem, Method: http.MethodGet, Operation: pathItem.Get}}
+
+	fake := &fakeLLMProvider{toolResponses: []*LLMToolResponse{
+		{ToolCalls: []LLMToolCall{{ID: "1", Name: "gmail_users_drafts_list", Arguments: `{"in_path_params": {"userId": "me"}}`}}},
+		{Content: "You have no drafts", Done: true},
+	}}
+	config := &PluginDataConfig{LlmConfig: &NLAPIConfig{provider: fake}, MaxPlanSteps: 5}
+
+	executed := []string{}
+	executor := func(ctx context.Context, route *routers.Route, params *openAPIOperationParams, step *planStep) {
+		executed = append(executed, route.Operation.OperationID)
+		step.StatusCode = http.StatusOK
+	}
+	steps, complete, err := runPlan(context.TODO(), config, routes, "list my drafts", executor)
+	assert.Nil(t, err)
+	assert.True(t, complete)
+	assert.Equal(t, []string{"gmail.users.drafts.list"}, executed)
+	assert.Equal(t, "gmail.users.drafts.list", steps[0].OperationID)
+}
+
+func TestGetToolName(t *testing.T) {
+	validToolName := regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+	taken := map[string]*routers.Route{}
+	for _, operationID := range []string{
+		"searchIssues",
+		"gmail.users.drafts.list",
+		"gmail_users_drafts_list",
+		"",
+		"repos/list-for-org",
+		strings.Repeat("getTheVeryLongOperation", 5),
+	} {
+		name := getToolName(operationID, taken)
+		assert.Regexp(t, validToolName, name)
+		assert.NotContains(t, taken, name)
+		taken[name] = nil
+	}
+	assert.Contains(t, taken, "searchIssues")
+	assert.Contains(t, taken, "gmail_users_drafts_list")
+}
+
+func TestGetPlannerRoutes(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(plannerTestSpec))
+	assert.Nil(t, err)
+	candidates := []OperationCandidate{
+		{OperationID: "searchIssues", Relevance: 0.8},
+		{OperationID: "unknownOperation", Relevance: 0.7},
+		{OperationID: "addComment", Relevance: 0.4},
+	}
+
+	routes := getPlannerRoutes(&oas.OAS{T: *doc}, candidates, 0.5)
+	assert.Len(t, routes, 1)
+	assert.Equal(t, "searchIssues", routes[0].Operation.OperationID)
+	assert.Len(t, getPlannerRoutes(&oas.OAS{T: *doc}, candidates, 0), 2)
+}
+
+func TestFormatPlanTrace(t *testing.T) {
+	trace := formatPlanTrace([]planStep{
+		{Step: 1, OperationID: "searchIssues", StatusCode: 200, Response: `[{"id": 42}]`},
+		{Step: 2, OperationID: "addComment", Error: "unknown operation"},
+	}, false)
+	assert.Equal(t, `Step 1, searchIssues: 200 [{"id": 42}]
+Step 2, addComment: error unknown operation
+The plan was stopped before the end, the step limit was reached.
+`, trace)
+}
@@ -0,0 +1,287 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"maps"
+	"mime/multipart"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+)
+
+const (
+	MEDIA_TYPE_JSON      = "application/json"
+	MEDIA_TYPE_FORM      = "application/x-www-form-urlencoded"
+	MEDIA_TYPE_MULTIPART = "multipart/form-data"
+	MEDIA_TYPE_XML       = "application/xml"
+
+	DEFAULT_XML_ROOT_ELEMENT = "request"
+)
+
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "" || mediaType == MEDIA_TYPE_JSON || strings.HasSuffix(mediaType, "+json")
+}
+
+func isXMLMediaType(mediaType string) bool {
+	return mediaType == MEDIA_TYPE_XML || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
+// isFieldsMediaType tells if the body is made of fields, encoded by the
+// gateway instead of being sent as JSON.
+func isFieldsMediaType(mediaType string) bool {
+	return mediaType == MEDIA_TYPE_FORM || mediaType == MEDIA_TYPE_MULTIPART || isXMLMediaType(mediaType)
+}
+
+// getRequestBodyContentType returns the media type of the operation request
+// body, preferring JSON, then the encodings of fields when several are
+// accepted.
+func getRequestBodyContentType(operation *openapi3.Operation) string {
+	if operation == nil || operation.RequestBody == nil || operation.RequestBody.Value == nil {
+		return MEDIA_TYPE_JSON
+	}
+	content := operation.RequestBody.Value.Content
+	if content.Get(MEDIA_TYPE_JSON) != nil || len(content) == 0 {
+		return MEDIA_TYPE_JSON
+	}
+	mediaTypes := slices.Sorted(maps.Keys(content))
+	for _, accepts := range []func(string) bool{isJSONMediaType, isFieldsMediaType} {
+		if i := slices.IndexFunc(mediaTypes, accepts); i >= 0 {
+			return mediaTypes[i]
+		}
+	}
+	return mediaTypes[0]
+}
+
+func getRequestBodySchema(operation *openapi3.Operation, mediaType string) *openapi3.SchemaRef {
+	if operation == nil || operation.RequestBody == nil || operation.RequestBody.Value == nil {
+		return nil
+	}
+	if media := operation.RequestBody.Value.Content.Get(mediaType); media != nil {
+		return media.Schema
+	}
+	return nil
+}
+
+// encodeRequestBody serializes the body generated by the LLM in the media type
+// of the operation. The content type includes the multipart boundary.
+func encodeRequestBody(mediaType string, body json.RawMessage, schema *openapi3.SchemaRef) ([]byte, string, error) {
+	var text string
+	isText := json.Unmarshal(body, &text) == nil
+
+	switch {
+	case isJSONMediaType(mediaType):
+		// An object stringified by the LLM is not sent as is
+		if isText && schema != nil && schema.Value != nil && (schema.Value.Type == "object" || schema.Value.Type == "array") {
+			return nil, "", fmt.Errorf("the request body must be a JSON %s, not a string", schema.Value.Type)
+		}
+		compact := &bytes.Buffer{}
+		if err := json.Compact(compact, body); err != nil {
+			return nil, "", fmt.Errorf("the request body is not valid JSON: %w", err)
+		}
+		return compact.Bytes(), mediaType, nil
+
+	case isFieldsMediaType(mediaType):
+		fields := map[string]any{}
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return nil, "", fmt.Errorf("the request body must be an object with the fields of %s: %w", mediaType, err)
+		}
+		return encodeRequestBodyFields(mediaType, fields, schema)
+	}
+
+	// The other media types are sent as text
+	if !isText {
+		return nil, "", fmt.Errorf("the request body must be a string for %s", mediaType)
+	}
+	return []byte(text), mediaType, nil
+}
+
+// encodeRequestBodyFields serializes the fields of a form, multipart or XML body
+func encodeRequestBodyFields(mediaType string, fields map[string]any, schema *openapi3.SchemaRef) ([]byte, string, error) {
+	switch {
+	case mediaType == MEDIA_TYPE_FORM:
+		values := url.Values{}
+		for name, value := range fields {
+			for _, item := range asFieldValues(value) {
+				values.Add(name, formatFieldValue(item))
+			}
+		}
+		return []byte(values.Encode()), mediaType, nil
+
+	case mediaType == MEDIA_TYPE_MULTIPART:
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		for _, name := range slices.Sorted(maps.Keys(fields)) {
+			binary := isBinaryProperty(getPropertySchema(schema, name))
+			for _, item := range asFieldValues(fields[name]) {
+				if err := writeMultipartField(writer, name, formatFieldValue(item), binary); err != nil {
+					return nil, "", err
+				}
+			}
+		}
+		if err := writer.Close(); err != nil {
+			return nil, "", err
+		}
+		return body.Bytes(), writer.FormDataContentType(), nil
+
+	case isXMLMediaType(mediaType):
+		body, err := encodeXMLBody(fields, schema)
+		return body, mediaType, err
+	}
+	return nil, "", fmt.Errorf("unsupported media type for the request body fields: %s", mediaType)
+}
+
+func writeMultipartField(writer *multipart.Writer, name string, value string, binary bool) error {
+	if !binary {
+		return writer.WriteField(name, value)
+	}
+	part, err := writer.CreateFormFile(name, name)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write([]byte(value))
+	return err
+}
+
+// asFieldValues returns the values of a repeated field
+func asFieldValues(value any) []any {
+	if values, ok := value.([]any); ok {
+		return values
+	}
+	return []any{value}
+}
+
+// formatFieldValue writes a scalar as text, and nested values as JSON
+func formatFieldValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+func getPropertySchema(schema *openapi3.SchemaRef, name string) *openapi3.SchemaRef {
+	if schema == nil || schema.Value == nil {
+		return nil
+	}
+	if property, present := schema.Value.Properties[name]; present {
+		return property
+	}
+	for _, allOf := range schema.Value.AllOf {
+		if property := getPropertySchema(allOf, name); property != nil {
+			return property
+		}
+	}
+	return nil
+}
+
+func isBinaryProperty(schema *openapi3.SchemaRef) bool {
+	return schema != nil && schema.Value != nil && schema.Value.Type == "string" && schema.Value.Format == "binary"
+}
+
+// encodeXMLBody writes the fields in a root element named after the schema.
+// The xml name, attribute and wrapped keywords of the schemas are followed.
+func encodeXMLBody(fields map[string]any, schema *openapi3.SchemaRef) ([]byte, error) {
+	root := DEFAULT_XML_ROOT_ELEMENT
+	if schema != nil && schema.Ref != "" {
+		root = path.Base(schema.Ref)
+	}
+	root = getXMLName(root, schema)
+
+	body := &bytes.Buffer{}
+	body.WriteString(xml.Header)
+	encoder := xml.NewEncoder(body)
+	if err := writeXMLElement(encoder, root, fields, schema); err != nil {
+		return nil, err
+	}
+	if err := encoder.Flush(); err != nil {
+		return nil, err
+	}
+	return body.Bytes(), nil
+}
+
+func getXMLName(name string, schema *openapi3.SchemaRef) string {
+	if schema != nil && schema.Value != nil && schema.Value.XML != nil && schema.Value.XML.Name != "" {
+		return schema.Value.XML.Name
+	}
+	return name
+}
+
+func writeXMLElement(encoder *xml.Encoder, name string, value any, schema *openapi3.SchemaRef) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+
+	case []any:
+		var items *openapi3.SchemaRef
+		if schema != nil && schema.Value != nil {
+			items = schema.Value.Items
+		}
+		itemName := getXMLName(name, items)
+		wrapped := schema != nil && schema.Value != nil && schema.Value.XML != nil && schema.Value.XML.Wrapped
+		if wrapped {
+			if err := encoder.EncodeToken(xml.StartElement{Name: xml.Name{Local: name}}); err != nil {
+				return err
+			}
+		}
+		for _, item := range v {
+			if err := writeXMLElement(encoder, itemName, item, items); err != nil {
+				return err
+			}
+		}
+		if wrapped {
+			return encoder.EncodeToken(xml.EndElement{Name: xml.Name{Local: name}})
+		}
+		return nil
+
+	case map[string]any:
+		start := xml.StartElement{Name: xml.Name{Local: name}}
+		children := []string{}
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			property := getPropertySchema(schema, key)
+			if property != nil && property.Value != nil && property.Value.XML != nil && property.Value.XML.Attribute {
+				start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: getXMLName(key, property)}, Value: formatFieldValue(v[key])})
+			} else {
+				children = append(children, key)
+			}
+		}
+		if err := encoder.EncodeToken(start); err != nil {
+			return err
+		}
+		for _, key := range children {
+			property := getPropertySchema(schema, key)
+			if err := writeXMLElement(encoder, getXMLName(key, property), v[key], property); err != nil {
+				return err
+			}
+		}
+		return encoder.EncodeToken(start.End())
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := encoder.EncodeToken(xml.CharData(formatFieldValue(value))); err != nil {
+		return err
+	}
+	return encoder.EncodeToken(start.End())
+}
@@ -0,0 +1,278 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+const requestBodyTestSpec = `{
+	"openapi": "3.0.0",
+	"info": {"title": "Pets", "version": "1.0.0"},
+	"paths": {
+		"/pets": {
+			"post": {
+				"operationId": "addPet",
+				"requestBody": {
+					"required": true,
+					"content": {
+						"application/x-www-form-urlencoded": {"schema": {"$ref": "#/components/schemas/Pet"}},
+						"application/xml": {"schema": {"$ref": "#/components/schemas/Pet"}}
+					}
+				},
+				"responses": {"200": {"description": "OK"}}
+			}
+		},
+		"/pets/{id}": {
+			"put": {
+				"operationId": "updatePet",
+				"parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "integer"}}],
+				"requestBody": {
+					"content": {
+						"application/xml": {"schema": {"$ref": "#/components/schemas/Pet"}}
+					}
+				},
+				"responses": {"200": {"description": "OK"}}
+			}
+		},
+		"/pets/{id}/photo": {
+			"post": {
+				"operationId": "uploadPhoto",
+				"parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "integer"}}],
+				"requestBody": {
+					"content": {
+						"multipart/form-data": {
+							"schema": {
+								"type": "object",
+								"properties": {
+									"caption": {"type": "string"},
+									"file": {"type": "string", "format": "binary"}
+								}
+							}
+						}
+					}
+				},
+				"responses": {"200": {"description": "OK"}}
+			}
+		}
+	},
+	"components": {
+		"schemas": {
+			"Pet": {
+				"type": "object",
+				"required": ["name"],
+				"properties": {
+					"id": {"type": "integer", "xml": {"attribute": true}},
+					"name": {"type": "string"},
+					"tags": {
+						"type": "array",
+						"xml": {"wrapped": true},
+						"items": {"type": "string", "xml": {"name": "tag"}}
+					}
+				}
+			}
+		}
+	}
+}`
+
+func TestGetRequestBodyContentType(t *testing.T) {
+	tests := []struct {
+		description string
+		mediaTypes  []string
+		expected    string
+	}{
+		{"No body", nil, MEDIA_TYPE_JSON},
+		{"JSON first", []string{MEDIA_TYPE_XML, MEDIA_TYPE_JSON, MEDIA_TYPE_FORM}, MEDIA_TYPE_JSON},
+		{"JSON-like type", []string{MEDIA_TYPE_XML, "application/merge-patch+json"}, "application/merge-patch+json"},
+		{"Fields before other types", []string{"application/octet-stream", MEDIA_TYPE_MULTIPART}, MEDIA_TYPE_MULTIPART},
+		{"Other type", []string{"application/octet-stream"}, "application/octet-stream"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			operation := &openapi3.Operation{}
+			if tt.mediaTypes != nil {
+				content := openapi3.Content{}
+				for _, mediaType := range tt.mediaTypes {
+					content[mediaType] = openapi3.NewMediaType()
+				}
+				operation.RequestBody = &openapi3.RequestBodyRef{Value: &openapi3.RequestBody{Content: content}}
+			}
+			assert.Equal(t, tt.expected, getRequestBodyContentType(operation))
+		})
+	}
+}
+
+func TestEncodeRequestBody(t *testing.T) {
+	route := getTestRoute(t, requestBodyTestSpec, "/pets", http.MethodPost)
+	petSchema := getRequestBodySchema(route.Operation, MEDIA_TYPE_XML)
+	fields := map[string]any{"id": float64(42), "name": "Rex & co", "tags": []any{"dog", "good"}}
+
+	t.Run("Form", func(t *testing.T) {
+		body, contentType, err := encodeRequestBodyFields(MEDIA_TYPE_FORM, fields, petSchema)
+		assert.Nil(t, err)
+		assert.Equal(t, MEDIA_TYPE_FORM, contentType)
+		values, err := url.ParseQuery(string(body))
+		assert.Nil(t, err)
+		assert.Equal(t, url.Values{"id": {"42"}, "name": {"Rex & co"}, "tags": {"dog", "good"}}, values)
+	})
+
+	t.Run("Multipart", func(t *testing.T) {
+		route := getTestRoute(t, requestBodyTestSpec, "/pets/{id}/photo", http.MethodPost)
+		schema := getRequestBodySchema(route.Operation, MEDIA_TYPE_MULTIPART)
+		body, contentType, err := encodeRequestBodyFields(MEDIA_TYPE_MULTIPART, map[string]any{"caption": "On the beach", "file": "PNG..."}, schema)
+		assert.Nil(t, err)
+
+		mediaType, params, err := mime.ParseMediaType(contentType)
+		assert.Nil(t, err)
+		assert.Equal(t, MEDIA_TYPE_MULTIPART, mediaType)
+		reader := multipart.NewReader(strings.NewReader(string(body)), params["boundary"])
+
+		part, err := reader.NextPart()
+		assert.Nil(t, err)
+		assert.Equal(t, "caption", part.FormName())
+		assert.Equal(t, "", part.FileName())
+		value, _ := io.ReadAll(part)
+		assert.Equal(t, "On the beach", string(value))
+
+		part, err = reader.NextPart()
+		assert.Nil(t, err)
+		assert.Equal(t, "file", part.FormName())
+		assert.Equal(t, "file", part.FileName())
+		value, _ = io.ReadAll(part)
+		assert.Equal(t, "PNG...", string(value))
+
+		_, err = reader.NextPart()
+		assert.Equal(t, io.EOF, err)
+	})
+
+	t.Run("XML", func(t *testing.T) {
+		body, contentType, err := encodeRequestBodyFields(MEDIA_TYPE_XML, fields, petSchema)
+		assert.Nil(t, err)
+		assert.Equal(t, MEDIA_TYPE_XML, contentType)
+		assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+			`<Pet id="42"><name>Rex &amp; co</name><tags><tag>dog</tag><tag>good</tag></tags></Pet>`, string(body))
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		_, _, err := encodeRequestBodyFields("application/octet-stream", fields, nil)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestEncodeRequestBodyMediaTypes(t *testing.T) {
+	route := getTestRoute(t, requestBodyTestSpec, "/pets", http.MethodPost)
+	petSchema := getRequestBodySchema(route.Operation, MEDIA_TYPE_XML)
+
+	tests := []struct {
+		description         string
+		mediaType           string
+		body                string
+		schema              *openapi3.SchemaRef
+		expectedBody        string
+		expectedContentType string
+		expectedError       string
+	}{
+		{"JSON is compacted", MEDIA_TYPE_JSON, `{"name": "Rex", "id": 42}`, petSchema, `{"name":"Rex","id":42}`, MEDIA_TYPE_JSON, ""},
+		{"JSON-like type", "application/merge-patch+json", `{"name": null}`, petSchema, `{"name":null}`, "application/merge-patch+json", ""},
+		{"Stringified object", MEDIA_TYPE_JSON, `"{\"name\": \"Rex\"}"`, petSchema, "", "", "the request body must be a JSON object, not a string"},
+		{"String without schema", MEDIA_TYPE_JSON, `"Rex"`, nil, `"Rex"`, MEDIA_TYPE_JSON, ""},
+		{"Form fields", MEDIA_TYPE_FORM, `{"name": "Rex"}`, petSchema, "name=Rex", MEDIA_TYPE_FORM, ""},
+		{"Form without fields", MEDIA_TYPE_FORM, `["Rex"]`, petSchema, "", "", "the request body must be an object with the fields of application/x-www-form-urlencoded"},
+		{"Text", "text/plain", `"Hello"`, nil, "Hello", "text/plain", ""},
+		{"Text not in a string", "text/plain", `{"text": "Hello"}`, nil, "", "", "the request body must be a string for text/plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			body, contentType, err := encodeRequestBody(tt.mediaType, json.RawMessage(tt.body), tt.schema)
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expectedBody, string(body))
+			assert.Equal(t, tt.expectedContentType, contentType)
+		})
+	}
+}
+
+func TestApplyOpenAPIParamsWithRequestBody(t *testing.T) {
+	t.Run("Form", func(t *testing.T) {
+		route := getTestRoute(t, requestBodyTestSpec, "/pets", http.MethodPost)
+		params := &openAPIOperationParams{RequestBody: json.RawMessage(`{"name": "Rex", "id": 42}`)}
+
+		r := httptest.NewRequest(http.MethodPost, "/pets", strings.NewReader("add the pet Rex with the id 42"))
+		r.Header.Set("Content-Type", CONTENT_TYPE_NLQ)
+		assert.Empty(t, validateOpenAPIParams(r, route, map[string]string{}, params))
+
+		assert.Nil(t, applyOpenAPIParams(r, route, map[string]string{}, params))
+		assert.Equal(t, MEDIA_TYPE_FORM, r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "id=42&name=Rex", string(body))
+		assert.Equal(t, int64(len(body)), r.ContentLength)
+	})
+
+	t.Run("XML", func(t *testing.T) {
+		route := getTestRoute(t, requestBodyTestSpec, "/pets/{id}", http.MethodPut)
+		params := &openAPIOperationParams{
+			InPathParams: map[string]string{"id": "42"},
+			RequestBody:  json.RawMessage(`{"name": "Rex"}`),
+		}
+
+		r := httptest.NewRequest(http.MethodPost, "/pets/{id}", strings.NewReader("rename the pet 42 to Rex"))
+		// The XML body is sent without being validated
+		assert.Empty(t, validateOpenAPIParams(r, route, map[string]string{}, params))
+
+		assert.Nil(t, applyOpenAPIParams(r, route, map[string]string{}, params))
+		assert.Equal(t, "/pets/42", r.URL.Path)
+		assert.Equal(t, MEDIA_TYPE_XML, r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		assert.Contains(t, string(body), "<Pet><name>Rex</name></Pet>")
+	})
+}
+
+func TestApplyOpenAPIParamsWithInvalidRequestBody(t *testing.T) {
+	route := getTestRoute(t, requestBodyTestSpec, "/pets", http.MethodPost)
+	params := &openAPIOperationParams{RequestBody: json.RawMessage(`"name=Rex"`)}
+
+	r := httptest.NewRequest(http.MethodPost, "/pets", strings.NewReader("add the pet Rex"))
+	assert.ErrorContains(t, applyOpenAPIParams(r, route, map[string]string{}, params), "must be an object")
+	// The LLM is asked to fix it
+	assert.Equal(t, []string{"the request body must be an object with the fields of application/x-www-form-urlencoded: json: cannot unmarshal string into Go value of type map[string]interface {}"},
+		validateOpenAPIParams(r, route, map[string]string{}, params))
+}
+
+func TestLlmNlToOpenAPIRequestWithRequestBody(t *testing.T) {
+	fake := &fakeLLMProvider{
+		completions: []string{`{
+			"in_path_params": {},
+			"in_query_params": {},
+			"in_header_params": {},
+			"request_body": {"name": "Rex", "tags": ["dog"]}
+		}`},
+	}
+	route := getTestRoute(t, requestBodyTestSpec, "/pets", http.MethodPost)
+
+	params := llmNlToOpenAPIRequest(context.TODO(), route.Operation, "add the dog Rex", &NLAPIConfig{provider: fake})
+	assert.NotNil(t, params)
+	assert.JSONEq(t, `{"name": "Rex", "tags": ["dog"]}`, string(params.RequestBody))
+
+	assert.Len(t, fake.schemas, 1)
+	expectedSchema, err := buildStructuredOASResponse(route.Operation)
+	assert.Nil(t, err)
+	assert.Equal(t, expectedSchema, fake.schemas[0].Schema)
+}
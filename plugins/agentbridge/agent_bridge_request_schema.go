@@ -0,0 +1,138 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"encoding/json"
+
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+)
+
+const (
+	MAX_REQUEST_BODY_SCHEMA_DEPTH = 6 // Nesting levels of the request body schema given to the LLM
+)
+
+// buildStructuredOASResponse returns the schema of the request generated by
+// the LLM for the operation. The parameters of the operation are listed, as
+// strings, and the request body follows the schema of the media type sent
+// upstream, so the LLM fills typed fields instead of writing the body.
+func buildStructuredOASResponse(operation *openapi3.Operation) ([]byte, error) {
+	inPathParams := map[string]any{}
+	inQueryParams := map[string]any{}
+	inHeaderParams := map[string]any{}
+	inCookieParams := map[string]any{}
+	if operation != nil {
+		for _, parameterRef := range operation.Parameters {
+			if parameterRef == nil || parameterRef.Value == nil {
+				continue
+			}
+			parameter := parameterRef.Value
+			switch parameter.In {
+			case openapi3.ParameterInPath:
+				inPathParams[parameter.Name] = getParameterValueSchema(parameter)
+			case openapi3.ParameterInQuery:
+				inQueryParams[parameter.Name] = getParameterValuesSchema(parameter)
+			case openapi3.ParameterInHeader:
+				inHeaderParams[parameter.Name] = getParameterValuesSchema(parameter)
+			case openapi3.ParameterInCookie:
+				inCookieParams[parameter.Name] = getParameterValueSchema(parameter)
+			}
+		}
+	}
+
+	properties := map[string]any{
+		"in_path_params": map[string]any{
+			"description":          "The parameters that are inside the path",
+			"type":                 "object",
+			"properties":           inPathParams,
+			"additionalProperties": map[string]any{"type": "string"},
+		},
+		"in_query_params": map[string]any{
+			"description":          "The parameters that are part of the query string. Each parameter is an array of strings",
+			"type":                 "object",
+			"properties":           inQueryParams,
+			"additionalProperties": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		},
+		"in_header_params": map[string]any{
+			"description":          "The parameters that are in the headers. Each parameter is an array of strings",
+			"type":                 "object",
+			"properties":           inHeaderParams,
+			"additionalProperties": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		},
+		"in_cookie_params": map[string]any{
+			"description":          "The parameters that are in the cookies",
+			"type":                 "object",
+			"properties":           inCookieParams,
+			"additionalProperties": map[string]any{"type": "string"},
+		},
+	}
+	if requestBody := getRequestBodyResponseSchema(operation); requestBody != nil {
+		properties["request_body"] = requestBody
+	}
+
+	return json.Marshal(map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             []string{"in_path_params", "in_query_params", "in_header_params"},
+		"additionalProperties": false,
+	})
+}
+
+// getParameterValueSchema describes a parameter, always written as a string
+func getParameterValueSchema(parameter *openapi3.Parameter) map[string]any {
+	schema := map[string]any{"type": "string"}
+	if description := truncateDescription(parameter.Description); description != "" {
+		schema["description"] = description
+	}
+	if parameter.Schema != nil && parameter.Schema.Value != nil {
+		enum := parameter.Schema.Value.Enum
+		if items := parameter.Schema.Value.Items; len(enum) == 0 && items != nil && items.Value != nil {
+			enum = items.Value.Enum
+		}
+		if len(enum) > 0 {
+			values := []string{}
+			for _, value := range enum {
+				values = append(values, formatFieldValue(value))
+			}
+			schema["enum"] = values
+		}
+	}
+	return schema
+}
+
+func getParameterValuesSchema(parameter *openapi3.Parameter) map[string]any {
+	item := getParameterValueSchema(parameter)
+	schema := map[string]any{"type": "array", "items": item}
+	if description, present := item["description"]; present {
+		schema["description"] = description
+		delete(item, "description")
+	}
+	return schema
+}
+
+// getRequestBodyResponseSchema inlines the schema of the request body, cut at
+// MAX_REQUEST_BODY_SCHEMA_DEPTH nested levels for the recursive schemas.
+func getRequestBodyResponseSchema(operation *openapi3.Operation) *openapi3.SchemaRef {
+	if operation == nil || operation.RequestBody == nil || operation.RequestBody.Value == nil {
+		return nil
+	}
+	mediaType := getRequestBodyContentType(operation)
+	schema := compactSchemaRef(getRequestBodySchema(operation, mediaType), 0, MAX_REQUEST_BODY_SCHEMA_DEPTH, false)
+	if schema == nil {
+		// Any JSON value
+		schema = &openapi3.SchemaRef{Value: &openapi3.Schema{}}
+	}
+	description := schema.Value.Description
+	if description == "" {
+		description = truncateDescription(operation.RequestBody.Value.Description)
+	}
+	if description == "" {
+		description = "The body of the request"
+	}
+	if isFieldsMediaType(mediaType) {
+		description = appendDescriptionNote(description, "the fields are sent as "+mediaType)
+	}
+	schema.Value.Description = description
+	return schema
+}
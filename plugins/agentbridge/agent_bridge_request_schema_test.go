@@ -0,0 +1,140 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildStructuredOASResponse(t *testing.T) {
+	t.Run("JSON body", func(t *testing.T) {
+		route := getTestRoute(t, validationTestSpec, "/releases/{id}", http.MethodPut)
+		schema, err := buildStructuredOASResponse(route.Operation)
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{
+			"type": "object",
+			"properties": {
+				"in_path_params": {
+					"description": "The parameters that are inside the path",
+					"type": "object",
+					"properties": {"id": {"type": "string"}},
+					"additionalProperties": {"type": "string"}
+				},
+				"in_query_params": {
+					"description": "The parameters that are part of the query string. Each parameter is an array of strings",
+					"type": "object",
+					"properties": {"notify": {"type": "array", "items": {"type": "string"}}},
+					"additionalProperties": {"type": "array", "items": {"type": "string"}}
+				},
+				"in_header_params": {
+					"description": "The parameters that are in the headers. Each parameter is an array of strings",
+					"type": "object",
+					"properties": {},
+					"additionalProperties": {"type": "array", "items": {"type": "string"}}
+				},
+				"in_cookie_params": {
+					"description": "The parameters that are in the cookies",
+					"type": "object",
+					"properties": {},
+					"additionalProperties": {"type": "string"}
+				},
+				"request_body": {
+					"description": "The body of the request",
+					"type": "object",
+					"required": ["name"],
+					"properties": {"name": {"type": "string"}}
+				}
+			},
+			"required": ["in_path_params", "in_query_params", "in_header_params"],
+			"additionalProperties": false
+		}`, string(schema))
+	})
+
+	t.Run("Form body", func(t *testing.T) {
+		route := getTestRoute(t, requestBodyTestSpec, "/pets", http.MethodPost)
+		schema, err := buildStructuredOASResponse(route.Operation)
+		assert.Nil(t, err)
+		requestBody := getStructuredProperty(t, schema, "request_body")
+		assert.JSONEq(t, `{
+			"description": "The body of the request (the fields are sent as application/x-www-form-urlencoded)",
+			"type": "object",
+			"required": ["name"],
+			"properties": {
+				"id": {"type": "integer"},
+				"name": {"type": "string"},
+				"tags": {"type": "array", "items": {"type": "string"}}
+			}
+		}`, string(requestBody))
+	})
+
+	t.Run("Parameters and no body", func(t *testing.T) {
+		operation := openapi3.NewOperation()
+		operation.AddParameter(&openapi3.Parameter{
+			Name: "state", In: openapi3.ParameterInQuery, Description: "The state of the issues",
+			Schema: openapi3.NewStringSchema().WithEnum("open", "closed").NewRef(),
+		})
+		operation.AddParameter(&openapi3.Parameter{Name: "theme", In: openapi3.ParameterInCookie, Schema: openapi3.NewStringSchema().NewRef()})
+		operation.AddParameter(&openapi3.Parameter{
+			Name: "X-Per-Page", In: openapi3.ParameterInHeader,
+			Schema: openapi3.NewArraySchema().WithItems(openapi3.NewIntegerSchema().WithEnum(float64(10), float64(50))).NewRef(),
+		})
+		schema, err := buildStructuredOASResponse(operation)
+		assert.Nil(t, err)
+
+		query := getStructuredProperty(t, schema, "in_query_params")
+		assert.Contains(t, string(query), `"state":{"description":"The state of the issues","items":{"enum":["open","closed"],"type":"string"},"type":"array"}`)
+		header := getStructuredProperty(t, schema, "in_header_params")
+		assert.Contains(t, string(header), `"X-Per-Page":{"items":{"enum":["10","50"],"type":"string"},"type":"array"}`)
+		cookie := getStructuredProperty(t, schema, "in_cookie_params")
+		assert.Contains(t, string(cookie), `"properties":{"theme":{"type":"string"}}`)
+		assert.Nil(t, getStructuredProperty(t, schema, "request_body"))
+	})
+
+	t.Run("Recursive body", func(t *testing.T) {
+		doc, err := openapi3.NewLoader().LoadFromData([]byte(operationPromptTestSpec))
+		assert.Nil(t, err)
+		schema, err := buildStructuredOASResponse(doc.Paths["/users"].Post)
+		assert.Nil(t, err)
+		requestBody := string(getStructuredProperty(t, schema, "request_body"))
+		assert.NotContains(t, requestBody, "$ref")
+		assert.Contains(t, requestBody, `"manager":{"description":"(nested fields omitted)","type":"object"}`)
+	})
+}
+
+func TestBuildStructuredOASResponseConfigs(t *testing.T) {
+	specFilenames, err := filepath.Glob("../../configs/*.oas.json")
+	assert.Nil(t, err)
+	for _, specFilename := range specFilenames {
+		doc, err := openapi3.NewLoader().LoadFromFile(specFilename)
+		if err != nil {
+			// The loader doesn't handle every recursive schema
+			t.Logf("Skipping %s: %s", specFilename, err)
+			continue
+		}
+		t.Run(filepath.Base(specFilename), func(t *testing.T) {
+			for path, pathItem := range doc.Paths {
+				for method, operation := range pathItem.Operations() {
+					schema, err := buildStructuredOASResponse(operation)
+					assert.Nil(t, err, "%s %s", method, path)
+					requestBody := getStructuredProperty(t, schema, "request_body")
+					assert.Equal(t, operation.RequestBody != nil, requestBody != nil, "%s %s", method, path)
+				}
+			}
+		})
+	}
+}
+
+func getStructuredProperty(t *testing.T, schema []byte, name string) json.RawMessage {
+	response := struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}{}
+	assert.Nil(t, json.Unmarshal(schema, &response))
+	return response.Properties[name]
+}
@@ -0,0 +1,233 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"text/template"
+
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+	"github.com/TykTechnologies/kin-openapi/routers"
+	"github.com/TykTechnologies/tyk/apidef/oas"
+)
+
+const (
+	DEFAULT_SELECT_TOP_K                = 1 // Only the nearest example, no re-ranking
+	DEFAULT_RERANK_CONFIDENCE_THRESHOLD = 0.5
+)
+
+var (
+	tmplRerankSystemPrompt *template.Template
+	tmplRerankUserPrompt   *template.Template
+
+	structuredRerankResponse = []byte(`{
+"type": "object",
+"properties": {
+  "operationId": {
+    "type": "string",
+    "description": "The operationId of the selected operation, empty if none of them matches the sentence"
+  },
+  "confidence": {
+    "type": "number",
+    "description": "How sure you are that the operation matches the sentence, between 0 and 1"
+  }
+},
+"required": ["operationId", "confidence"],
+"additionalProperties": false
+}`)
+)
+
+type TmplPromptRerank struct {
+	Sentence   string
+	Candidates []rerankCandidate
+}
+
+// rerankCandidate is the short description of an operation given to the LLM.
+type rerankCandidate struct {
+	OperationID string
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Parameters  []string
+}
+
+// OperationSelection is the operation selected for a query, with the
+// relevance from the embeddings and, when the LLM re-ranked the candidates,
+// its confidence.
+type OperationSelection struct {
+	OperationID string
+	Relevance   float64
+	Confidence  float64 // 0 when the candidates were not re-ranked
+}
+
+type rerankResponse struct {
+	OperationID string  `json:"operationId"`
+	Confidence  float64 `json:"confidence"`
+}
+
+// SelectOperation finds the operation matching the input. With selectTopK > 1,
+// the nearest operations reaching the relevance threshold are given to the LLM
+// which chooses one of them, if it is confident enough. The relevance is
+// always the one from the embeddings. The nearest operation is used when the
+// LLM fails. A nil selection means that no operation was found.
+func SelectOperation(ctx context.Context, apidef *oas.OAS, config *PluginDataConfig, input string) (*OperationSelection, error) {
+	if config.SelectTopK <= 1 || config.LlmConfig == nil || config.LlmConfig.provider == nil {
+		operationID, relevance, err := findSelectOperation(ctx, config.APIID, input)
+		if err != nil || operationID == nil {
+			return nil, err
+		}
+		return &OperationSelection{OperationID: *operationID, Relevance: relevance}, nil
+	}
+
+	candidates, err := FindSelectOperationCandidates(ctx, config.APIID, input, config.SelectTopK)
+	if err != nil {
+		return nil, err
+	}
+	for index, candidate := range candidates {
+		logger.Debugf("[+] Candidate %d: %s / %f", index, candidate.OperationID, candidate.Relevance)
+	}
+	relevantCandidates := slices.DeleteFunc(slices.Clone(candidates), func(candidate OperationCandidate) bool {
+		return candidate.Relevance < config.RelevanceThreshold
+	})
+	if len(relevantCandidates) == 0 {
+		if len(candidates) > 0 {
+			// Rejected by the caller with the relevance threshold
+			return &OperationSelection{OperationID: candidates[0].OperationID, Relevance: candidates[0].Relevance}, nil
+		}
+		return nil, nil
+	}
+	if len(relevantCandidates) == 1 {
+		return &OperationSelection{OperationID: relevantCandidates[0].OperationID, Relevance: relevantCandidates[0].Relevance}, nil
+	}
+
+	operationID, confidence, err := rerankOperations(ctx, config, apidef, input, relevantCandidates)
+	if err != nil {
+		logger.Warningf("[+] Error while re-ranking the operations, using the embeddings: %s", err)
+		return &OperationSelection{OperationID: relevantCandidates[0].OperationID, Relevance: relevantCandidates[0].Relevance}, nil
+	}
+	if operationID == nil || confidence < config.RerankConfidenceThreshold {
+		logger.Debugf("[+] No candidate selected by the LLM with enough confidence: %f", confidence)
+		return nil, nil
+	}
+	index := slices.IndexFunc(relevantCandidates, func(candidate OperationCandidate) bool {
+		return candidate.OperationID == *operationID
+	})
+	return &OperationSelection{OperationID: *operationID, Relevance: relevantCandidates[index].Relevance, Confidence: confidence}, nil
+}
+
+// rerankOperations asks the LLM to choose among the candidates. A nil
+// operation means that none of them matches the input.
+func rerankOperations(ctx context.Context, config *PluginDataConfig, apidef *oas.OAS, input string, candidates []OperationCandidate) (*string, float64, error) {
+	rerankCandidates := []rerankCandidate{}
+	for _, candidate := range candidates {
+		route := getRouteForOperation(apidef, candidate.OperationID)
+		if route == nil {
+			continue
+		}
+		rerankCandidates = append(rerankCandidates, newRerankCandidate(route))
+	}
+	if len(rerankCandidates) == 0 {
+		return nil, 0, fmt.Errorf("no candidate found in the API definition")
+	}
+
+	systemPromptBuf := new(bytes.Buffer)
+	if err := tmplRerankSystemPrompt.Execute(systemPromptBuf, TmplPromptRerank{Sentence: input, Candidates: rerankCandidates}); err != nil {
+		return nil, 0, fmt.Errorf("error while creating the system prompt: %w", err)
+	}
+	userPromptBuf := new(bytes.Buffer)
+	if err := tmplRerankUserPrompt.Execute(userPromptBuf, TmplPromptRerank{Sentence: input, Candidates: rerankCandidates}); err != nil {
+		return nil, 0, fmt.Errorf("error while creating the user prompt: %w", err)
+	}
+
+	selectTool := JsonSchemaResponse{
+		Name:        "select_operation",
+		Description: "Select the operation matching the sentence",
+		Schema:      structuredRerankResponse,
+	}
+	answer, err := llmCall(ctx, systemPromptBuf.String(), userPromptBuf.String(), &selectTool, config.LlmConfig)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	response := rerankResponse{}
+	if err := json.Unmarshal([]byte(answer), &response); err != nil {
+		return nil, 0, fmt.Errorf("invalid answer from the LLM: %w", err)
+	}
+	if response.OperationID == "" {
+		logger.Debugf("[+] No candidate selected by the LLM")
+		return nil, 0, nil
+	}
+	known := slices.ContainsFunc(rerankCandidates, func(candidate rerankCandidate) bool {
+		return candidate.OperationID == response.OperationID
+	})
+	if !known {
+		return nil, 0, fmt.Errorf("unknown operation selected by the LLM: %s", response.OperationID)
+	}
+
+	confidence := min(max(response.Confidence, 0), 1)
+	logger.Debugf("[+] Operation selected by the LLM: %s - %f", response.OperationID, confidence)
+	return &response.OperationID, confidence, nil
+}
+
+// newRerankCandidate describes the operation of the route for the LLM.
+func newRerankCandidate(route *routers.Route) rerankCandidate {
+	summary, description := getOperationDescriptions(route.Operation)
+	rerankCandidate := rerankCandidate{
+		OperationID: route.Operation.OperationID,
+		Method:      route.Method,
+		Path:        route.Path,
+		Summary:     summary,
+		Description: description,
+		Parameters:  []string{},
+	}
+	for _, parameters := range []openapi3.Parameters{route.PathItem.Parameters, route.Operation.Parameters} {
+		for _, parameter := range parameters {
+			if parameter.Value == nil {
+				continue
+			}
+			rerankCandidate.Parameters = append(rerankCandidate.Parameters, fmt.Sprintf("%s (in %s): %s", parameter.Value.Name, parameter.Value.In, parameter.Value.Description))
+		}
+	}
+	if route.Operation.RequestBody != nil && route.Operation.RequestBody.Value != nil {
+		rerankCandidate.Parameters = append(rerankCandidate.Parameters, fmt.Sprintf("%s: %s", REQUEST_BODY_FIELD, route.Operation.RequestBody.Value.Description))
+	}
+	return rerankCandidate
+}
+
+func initRerankTemplates() {
+	var err error
+
+	systemPrompt := `You select the operation of an API matching a natural language sentence.
+Only choose among the following operations. If none of them matches the sentence, answer with an empty operationId.
+
+The operations:
+====
+{{range .Candidates}}operationId: {{.OperationID}}
+{{.Method}} {{.Path}}
+{{if .Summary}}Summary: {{.Summary}}
+{{end}}{{if .Description}}Description: {{.Description}}
+{{end}}{{if .Parameters}}Parameters:
+{{range .Parameters}}- {{.}}
+{{end}}{{end}}
+{{end}}====`
+
+	userPrompt := `The natural language sentence:
+====
+{{.Sentence}}
+====`
+
+	tmplRerankSystemPrompt, err = template.New("system_prompt_rerank").Parse(systemPrompt)
+	if err != nil {
+		logger.Fatalf("[+] Error parsing the re-ranking system prompt template: %s", err)
+	}
+	tmplRerankUserPrompt, err = template.New("user_prompt_rerank").Parse(userPrompt)
+	if err != nil {
+		logger.Fatalf("[+] Error parsing the re-ranking user prompt template: %s", err)
+	}
+}
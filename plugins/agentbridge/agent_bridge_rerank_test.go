@@ -0,0 +1,154 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"context"
+	"testing"
+
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+	"github.com/TykTechnologies/tyk/apidef/oas"
+	"github.com/stretchr/testify/assert"
+)
+
+const rerankTestSpec = `{
+	"openapi": "3.0.0",
+	"info": {"title": "Issues", "version": "1.0.0"},
+	"paths": {
+		"/issues": {
+			"get": {
+				"operationId": "listIssues",
+				"summary": "List the issues",
+				"parameters": [{"name": "labels", "in": "query", "description": "Comma separated labels", "schema": {"type": "string"}}],
+				"responses": {"200": {"description": "OK"}}
+			}
+		},
+		"/issues/{id}": {
+			"parameters": [{"name": "id", "in": "path", "required": true, "description": "The issue number", "schema": {"type": "integer"}}],
+			"get": {
+				"operationId": "getIssue",
+				"summary": "Get an issue",
+				"responses": {"200": {"description": "OK"}}
+			}
+		}
+	}
+}`
+
+func TestRerankOperations(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(rerankTestSpec))
+	assert.Nil(t, err)
+	apidef := &oas.OAS{T: *doc}
+	candidates := []OperationCandidate{
+		{OperationID: "listIssues", Relevance: 0.71},
+		{OperationID: "getIssue", Relevance: 0.70},
+	}
+
+	tests := []struct {
+		description        string
+		completion         string
+		expectedOperation  string
+		expectedConfidence float64
+		expectedError      bool
+	}{
+		{"Second candidate selected", `{"operationId": "getIssue", "confidence": 0.9}`, "getIssue", 0.9, false},
+		{"No candidate matches", `{"operationId": "", "confidence": 0.8}`, "", 0, false},
+		{"Confidence out of range", `{"operationId": "listIssues", "confidence": 1.5}`, "listIssues", 1, false},
+		{"Unknown operation", `{"operationId": "deleteIssue", "confidence": 0.9}`, "", 0, true},
+		{"Invalid answer", `getIssue`, "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			fake := &fakeLLMProvider{completions: []string{tt.completion}}
+			config := &PluginDataConfig{LlmConfig: &NLAPIConfig{provider: fake}, SelectTopK: 2}
+
+			operationID, confidence, err := rerankOperations(context.TODO(), config, apidef, "show me the issue 42", candidates)
+			if tt.expectedError {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			if tt.expectedOperation == "" {
+				assert.Nil(t, operationID)
+			} else {
+				assert.Equal(t, tt.expectedOperation, *operationID)
+			}
+			assert.Equal(t, tt.expectedConfidence, confidence)
+
+			// The LLM only sees the candidates, with their summary and parameters
+			assert.Contains(t, fake.systemPrompts[0], "operationId: getIssue\nGET /issues/{id}\nSummary: Get an issue\n")
+			assert.Contains(t, fake.systemPrompts[0], "- id (in path): The issue number")
+			assert.Contains(t, fake.systemPrompts[0], "- labels (in query): Comma separated labels")
+			assert.Contains(t, fake.userPrompts[0], "show me the issue 42")
+			assert.Equal(t, "select_operation", fake.schemas[0].Name)
+		})
+	}
+}
+
+func TestSelectOperationWithRerank(t *testing.T) {
+	const apiId = "rerank-test"
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(rerankTestSpec))
+	assert.Nil(t, err)
+	apidef := &oas.OAS{T: *doc}
+
+	embedder := &keywordEmbedder{keywords: []string{"issue", "list"}}
+	embeddingModelsLock.Lock()
+	embeddingModels[embedder.Name()] = embedder
+	embeddingModelsLock.Unlock()
+	pluginConfigLock.Lock()
+	pluginConfig[apiId] = &PluginDataConfig{
+		APIID:                apiId,
+		SelectModelEmbedding: embedder.Name(),
+		SelectOperations: map[string]*AIExtensionConfig{
+			"listIssues": {InputExamples: []string{"list the issues"}},
+			"getIssue":   {InputExamples: []string{"get the issue"}},
+		},
+	}
+	pluginConfigLock.Unlock()
+	t.Cleanup(func() {
+		deletePluginConfig(apiId)
+		embeddingModelsLock.Lock()
+		delete(embeddingModels, embedder.Name())
+		embeddingModelsLock.Unlock()
+	})
+	assert.Nil(t, reloadLearnedExamples(apiId))
+
+	tests := []struct {
+		description        string
+		relevanceThreshold float64
+		completion         string
+		expectedOperation  string
+		expectedScore      float64
+		expectedConfidence float64
+		expectedLLMCalls   int
+	}{
+		{"Candidates below the threshold are not re-ranked", 0.8, "", "getIssue", 1, 0, 0},
+		{"The score is the relevance of the selected operation", 0.5, `{"operationId": "listIssues", "confidence": 0.9}`, "listIssues", 0.709, 0.9, 1},
+		{"The LLM is not confident enough", 0.5, `{"operationId": "listIssues", "confidence": 0.3}`, "", 0, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			fake := &fakeLLMProvider{completions: []string{tt.completion}}
+			config := &PluginDataConfig{
+				APIID:                     apiId,
+				LlmConfig:                 &NLAPIConfig{provider: fake},
+				SelectTopK:                2,
+				RelevanceThreshold:        tt.relevanceThreshold,
+				RerankConfidenceThreshold: DEFAULT_RERANK_CONFIDENCE_THRESHOLD,
+			}
+
+			selection, err := SelectOperation(context.TODO(), apidef, config, "show me the issue 42")
+			assert.Nil(t, err)
+			if tt.expectedOperation == "" {
+				assert.Nil(t, selection)
+			} else {
+				assert.Equal(t, tt.expectedOperation, selection.OperationID)
+				assert.InDelta(t, tt.expectedScore, selection.Relevance, 1e-3)
+				assert.InDelta(t, tt.expectedConfidence, selection.Confidence, 1e-9)
+			}
+			assert.Len(t, fake.systemPrompts, tt.expectedLLMCalls)
+		})
+	}
+}
@@ -1,7 +1,7 @@
 // Copyright AGNTCY Contributors (https://github.com/agntcy)
 // SPDX-License-Identifier: Apache-2.0
 
-package main
+package agentbridge
 
 import (
 	"bytes"
@@ -10,6 +10,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"net/url"
 	"slices"
@@ -19,29 +20,28 @@ import (
 
 	"github.com/TykTechnologies/tyk/ctx"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/ai/azopenai"
-	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
-
 	"github.com/TykTechnologies/kin-openapi/openapi3"
 	"github.com/TykTechnologies/kin-openapi/routers"
-	"github.com/TykTechnologies/kin-openapi/routers/gorillamux"
 )
 
 var (
-	noOverrideHeaders = []string{"Authorization"}
+	// The cookies are set with the cookie parameters
+	noOverrideHeaders = []string{"Authorization", "Cookie"}
 
 	tmplQuerySystemPrompt    *template.Template
 	tmplQueryUserPrompt      *template.Template
+	tmplRepairUserPrompt     *template.Template
 	tmplResponseSystemPrompt *template.Template
 	tmplResponseUserPrompt   *template.Template
-
-	structuredOASResponse []byte = []byte("{}") // Will be updated in init()
 )
 
 // Struct given when rendering the templates
 type TmplPromptOpenAPI struct {
 	Operation string // The OpenAPI operation
 	Sentence  string // The Natural Language sentence
+
+	PreviousRequest  string   // The previous JSON object, when repairing it
+	ValidationErrors []string // Why the previous JSON object was rejected
 }
 
 type TmplPromptResponse struct {
@@ -75,13 +75,16 @@ func getRoute(req *http.Request) (*routers.Route, map[string]string, error) {
 	// In order to find the route we need to strip the listenPath from the URL
 	listenPath := oasDef.GetTykExtension().Server.ListenPath.Value
 	strippedPath := stripListenPath(listenPath, req.URL.Path)
-	oasDef.Servers = openapi3.Servers{}
 	fakeReq := &http.Request{
 		Method: req.Method,
 		URL:    &url.URL{Path: strippedPath},
 	}
 
-	router, _ := gorillamux.NewRouter(&oasDef.T)
+	router, err := getAPIRouter(oasDef.GetTykExtension().Info.ID, oasDef)
+	if err != nil {
+		logger.Errorf("[+] Error building the router: %s", err)
+		return nil, nil, err
+	}
 	route, pathParams, err := router.FindRoute(fakeReq)
 	if err != nil {
 		logger.Errorf("[+] Error finding route %s %s: %s", req.Method, strippedPath, err)
@@ -91,14 +94,14 @@ func getRoute(req *http.Request) (*routers.Route, map[string]string, error) {
 	return route, pathParams, nil
 }
 
-func rewriteQuery(r *http.Request) error {
+func rewriteQuery(r *http.Request) (*routers.Route, error) {
 	route, pathParams, err := getRoute(r)
 	if err != nil {
 		logger.Errorf("[+] Error getting the route: %s", err)
-		return errors.New("i'm sorry but I was not able to find the service you are asking for")
+		return nil, errors.New("i'm sorry but I was not able to find the service you are asking for")
 	}
 
-	return rewriteQueryForRoute(r, route, pathParams)
+	return route, rewriteQueryForRoute(r, route, pathParams)
 }
 
 func rewriteQueryForRoute(r *http.Request, route *routers.Route, pathParams map[string]string) error {
@@ -106,24 +109,92 @@ func rewriteQueryForRoute(r *http.Request, route *routers.Route, pathParams map[
 	if err != nil {
 		return fmt.Errorf("can't retreive the LLM configuration: %w", err)
 	}
+	if !isRouteEnabled(config, route) {
+		return newOperationDisabledError(route)
+	}
 
 	nlSentence, err := io.ReadAll(r.Body)
 	if err != nil {
 		logger.Errorf("[+] Error while reading the body: %s", err)
 		return errors.New("i'm sorry but I was not able to understand your query")
 	}
-	newParams := llmNlToOpenAPIRequest(r.Context(), route.Operation, string(nlSentence), config.LlmConfig)
+	sentence := string(nlSentence)
+
+	// The query may be the answer to a previous clarification request
+	pending, err := getConversation(r)
+	if err != nil {
+		return err
+	}
+	r.Header.Del(HEADER_X_NL_CONVERSATION)
+	conversationID := ""
+	if pending != nil {
+		if pending.APIID != config.APIID || pending.OperationID != route.Operation.OperationID {
+			return &nlQueryError{
+				StatusCode: http.StatusBadRequest,
+				Message:    "i'm sorry but this conversation is about another operation",
+			}
+		}
+		conversationID = pending.ConversationID
+		sentence = pending.Sentence + "\n" + sentence
+		pathParams = maps.Clone(pathParams)
+		for k, v := range pending.PathParams {
+			if _, exists := pathParams[k]; !exists {
+				pathParams[k] = v
+			}
+		}
+		if session := ctx.GetSession(r); session != nil {
+			session.MetaData[METADATA_NLQ] = sentence
+		}
+	}
+
+	newParams := llmNlToOpenAPIRequest(r.Context(), route.Operation, sentence, config.LlmConfig)
 	if newParams == nil {
 		logger.Errorf("[+] Error creating the new request")
 		return errors.New("i'm sorry but I was not able to understand your query")
 	}
+	if pending != nil {
+		newParams = mergeClarification(pending, newParams)
+	}
+
+	if config.AskForClarification {
+		missing := findMissingFields(r, route, pathParams, newParams)
+		if len(missing) > 0 {
+			logger.Debugf("[+] Missing required fields: %v", missing)
+			return askForClarification(conversationID, config, route, sentence, pathParams, newParams, missing)
+		}
+	}
+	if pending != nil {
+		deleteClarification(conversationID)
+	}
+
+	if config.ValidateRequests {
+		newParams, err = validateAndRepairOpenAPIRequest(r, route, pathParams, sentence, newParams, config)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := applyOpenAPIParams(r, route, pathParams, newParams); err != nil {
+		logger.Errorf("[+] Error applying the new request: %s", err)
+		return &nlQueryError{
+			StatusCode: http.StatusBadRequest,
+			Message:    "i'm sorry but I was not able to build a valid request from your query",
+			Errors:     []string{err.Error()},
+		}
+	}
 
+	return nil
+}
+
+// applyOpenAPIParams rewrites the request with the parameters generated by the
+// LLM. It fails when the body can't be serialized in the operation media type.
+func applyOpenAPIParams(r *http.Request, route *routers.Route, pathParams map[string]string, newParams *openAPIOperationParams) error {
 	// Override the method
 	r.Method = route.Method
 
 	// Override headers
 	for hName, hValues := range newParams.InHeaderParams {
-		if slices.Contains(noOverrideHeaders, hName) {
+		if slices.Contains(noOverrideHeaders, http.CanonicalHeaderKey(hName)) {
 			continue
 		}
 		r.Header.Del(hName)
@@ -132,6 +203,11 @@ func rewriteQueryForRoute(r *http.Request, route *routers.Route, pathParams map[
 		}
 	}
 
+	// Override cookies
+	if len(newParams.InCookieParams) > 0 {
+		setCookieParams(r, newParams.InCookieParams, getNoOverrideCookies(route))
+	}
+
 	// Override query parameters
 	queryParams := r.URL.Query()
 	for qName, qValues := range newParams.InQueryParams {
@@ -153,18 +229,74 @@ func rewriteQueryForRoute(r *http.Request, route *routers.Route, pathParams map[
 	}
 
 	// Override the body
-	if newParams.RequestBody != "" {
-		r.Body = io.NopCloser(strings.NewReader(newParams.RequestBody))
-		r.ContentLength = int64(len(newParams.RequestBody))
+	if newParams.hasRequestBody() {
+		mediaType := getRequestBodyContentType(route.Operation)
+		body, contentType, err := encodeRequestBody(mediaType, newParams.RequestBody, getRequestBodySchema(route.Operation, mediaType))
+		if err != nil {
+			return err
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+		r.Header.Set("Content-Type", contentType)
 	} else {
 		r.Body = nil
 		r.ContentLength = 0
 	}
 	r.Header.Del("Content-Length")
-
 	return nil
 }
 
+// setCookieParams replaces the cookies of the request with the cookie
+// parameters, except the ones of noOverrideCookies which are kept as is. The
+// other cookies are kept as sent by the client, even when Go can't parse them.
+func setCookieParams(r *http.Request, cookieParams map[string]string, noOverrideCookies []string) {
+	cookies := []string{}
+	for _, line := range r.Header.Values("Cookie") {
+		for _, pair := range strings.Split(line, ";") {
+			pair = strings.TrimSpace(pair)
+			if pair == "" {
+				continue
+			}
+			name, _, _ := strings.Cut(pair, "=")
+			name = strings.TrimSpace(name)
+			if _, present := cookieParams[name]; present && !slices.Contains(noOverrideCookies, name) {
+				continue
+			}
+			cookies = append(cookies, pair)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(cookieParams)) {
+		if slices.Contains(noOverrideCookies, name) {
+			continue
+		}
+		if cookie := (&http.Cookie{Name: name, Value: cookieParams[name]}).String(); cookie != "" {
+			cookies = append(cookies, cookie)
+		} else {
+			logger.Warningf("[+] Invalid cookie parameter %s, skipping it", name)
+		}
+	}
+
+	r.Header.Del("Cookie")
+	if len(cookies) > 0 {
+		r.Header.Set("Cookie", strings.Join(cookies, "; "))
+	}
+}
+
+// getNoOverrideCookies returns the cookies holding credentials, like
+// noOverrideHeaders: the API keys sent in a cookie.
+func getNoOverrideCookies(route *routers.Route) []string {
+	cookies := []string{}
+	if route.Spec == nil || route.Spec.Components == nil {
+		return cookies
+	}
+	for _, securityScheme := range route.Spec.Components.SecuritySchemes {
+		if securityScheme != nil && securityScheme.Value != nil && securityScheme.Value.Type == "apiKey" && securityScheme.Value.In == openapi3.ParameterInCookie {
+			cookies = append(cookies, securityScheme.Value.Name)
+		}
+	}
+	return cookies
+}
+
 func getOriginalNLQuery(r *http.Request) string {
 	session := ctx.GetSession(r)
 	if session == nil {
@@ -209,33 +341,43 @@ func stripListenPath(listenPath string, path string) string {
 	return path
 }
 
+// getSchemasRef collects the schemas referenced from the given ones, through
+// every keyword. Each schema is visited once, so recursive schemas are fine.
 func getSchemasRef(schemas []*openapi3.SchemaRef, refs map[string]*openapi3.Schema) {
+	visitSchemasRef(schemas, refs, map[*openapi3.Schema]bool{})
+}
+
+func visitSchemasRef(schemas []*openapi3.SchemaRef, refs map[string]*openapi3.Schema, visited map[*openapi3.Schema]bool) {
 	for _, schema := range schemas {
-		if schema == nil {
-			return
+		if schema == nil || schema.Value == nil {
+			continue
 		}
 
 		if schema.Ref != "" {
-			if _, exists := refs[schema.Ref]; exists {
-				continue
+			if _, exists := refs[schema.Ref]; !exists {
+				refs[schema.Ref] = schema.Value
 			}
-			refs[schema.Ref] = schema.Value
 		}
+		if visited[schema.Value] {
+			continue
+		}
+		visited[schema.Value] = true
+
+		visitSchemasRef(schema.Value.AnyOf, refs, visited)
+		visitSchemasRef(schema.Value.OneOf, refs, visited)
+		visitSchemasRef(schema.Value.AllOf, refs, visited)
+		visitSchemasRef([]*openapi3.SchemaRef{schema.Value.Not, schema.Value.Items, schema.Value.AdditionalProperties.Schema}, refs, visited)
+		for _, prop := range schema.Value.Properties {
+			visitSchemasRef([]*openapi3.SchemaRef{prop}, refs, visited)
+		}
+	}
+}
 
-		if schema.Value.AnyOf != nil {
-			getSchemasRef(schema.Value.AnyOf, refs)
-		} else if schema.Value.OneOf != nil {
-			getSchemasRef(schema.Value.OneOf, refs)
-		} else if schema.Value.AllOf != nil {
-			getSchemasRef(schema.Value.AllOf, refs)
-		} else if schema.Value.Not != nil {
-			getSchemasRef([]*openapi3.SchemaRef{schema.Value.Not}, refs)
-		} else if schema.Value.Items != nil {
-			getSchemasRef([]*openapi3.SchemaRef{schema.Value.Items}, refs)
-		} else if schema.Value.Properties != nil {
-			for _, prop := range schema.Value.Properties {
-				getSchemasRef([]*openapi3.SchemaRef{prop}, refs)
-			}
+// getContentRefs collects the schemas referenced by every media type
+func getContentRefs(content openapi3.Content, refs map[string]*openapi3.Schema) {
+	for _, mediaType := range content {
+		if mediaType != nil && mediaType.Schema != nil {
+			getSchemasRef([]*openapi3.SchemaRef{mediaType.Schema}, refs)
 		}
 	}
 }
@@ -245,28 +387,38 @@ func getRequestBodyRefs(requestBody *openapi3.RequestBody, refs map[string]*open
 		return
 	}
 
-	// Prefer application/json over other content types
-	body := requestBody.Content.Get("application/json")
-	if body == nil {
-		// Fallback
-		body = requestBody.Content.Get("")
-	}
-
-	if body == nil {
-		return
-	}
-
-	getSchemasRef([]*openapi3.SchemaRef{body.Schema}, refs)
+	getContentRefs(requestBody.Content, refs)
 }
 
 func getParameterRefs(parameters openapi3.Parameters, refs map[string]*openapi3.Schema) {
 	for _, p := range parameters {
-		if p.Value != nil && p.Value.Schema != nil {
+		if p == nil || p.Value == nil {
+			continue
+		}
+		if p.Value.Schema != nil {
 			getSchemasRef([]*openapi3.SchemaRef{p.Value.Schema}, refs)
 		}
+		getContentRefs(p.Value.Content, refs)
 	}
 }
 
+// applyNaturalLanguageDescription returns a copy of the operation where the
+// x-tyk-natural-language description replaces the summary and the description
+func applyNaturalLanguageDescription(operation *openapi3.Operation) *openapi3.Operation {
+	nlExtension := getNaturalLanguageExtension(operation)
+	if nlExtension == nil {
+		return operation
+	}
+	overridden := *operation
+	overridden.Extensions = maps.Clone(operation.Extensions)
+	delete(overridden.Extensions, SPEC_EXT_NATURAL_LANGUAGE)
+	if nlExtension.Description != "" {
+		overridden.Summary = ""
+		overridden.Description = nlExtension.Description
+	}
+	return &overridden
+}
+
 // Here we are building a string representation of the operation
 // It contains the list of dereferenced parameters and the list of references used in the operation
 func buildOperationString(operation *openapi3.Operation) (string, error) {
@@ -274,6 +426,8 @@ func buildOperationString(operation *openapi3.Operation) (string, error) {
 
 	var sb strings.Builder
 
+	operation = applyNaturalLanguageDescription(operation)
+
 	operationString, err := operation.MarshalJSON()
 	if err != nil {
 		return "", err
@@ -321,34 +475,70 @@ type openAPIOperationParams struct {
 	InPathParams   map[string]string `json:"in_path_params"`
 	InQueryParams  url.Values        `json:"in_query_params"`
 	InHeaderParams http.Header       `json:"in_header_params"`
-	RequestBody    string            `json:"request_body"`
+	InCookieParams map[string]string `json:"in_cookie_params,omitempty"`
+	// RequestBody is serialized by the gateway in the media type of the operation
+	RequestBody json.RawMessage `json:"request_body,omitempty"`
+}
+
+func (p *openAPIOperationParams) hasRequestBody() bool {
+	return len(p.RequestBody) > 0 && string(p.RequestBody) != "null"
 }
 
 func llmNlToOpenAPIRequest(context context.Context, operation *openapi3.Operation, nlSentence string, llmConfig *NLAPIConfig) *openAPIOperationParams {
-	operationString, err := buildOperationString(operation)
+	return llmTranslateToOpenAPIRequest(context, operation, tmplQueryUserPrompt, TmplPromptOpenAPI{Sentence: nlSentence}, llmConfig)
+}
+
+// llmRepairOpenAPIRequest asks the LLM to fix a request rejected by the validation
+func llmRepairOpenAPIRequest(context context.Context, operation *openapi3.Operation, nlSentence string, previous *openAPIOperationParams, validationErrors []string, llmConfig *NLAPIConfig) *openAPIOperationParams {
+	previousRequest, err := json.Marshal(previous)
+	if err != nil {
+		logger.Errorf("[+] Error while marshalling the previous request: %s", err)
+		return nil
+	}
+
+	data := TmplPromptOpenAPI{
+		Sentence:         nlSentence,
+		PreviousRequest:  string(previousRequest),
+		ValidationErrors: validationErrors,
+	}
+	return llmTranslateToOpenAPIRequest(context, operation, tmplRepairUserPrompt, data, llmConfig)
+}
+
+func llmTranslateToOpenAPIRequest(context context.Context, operation *openapi3.Operation, tmplUserPrompt *template.Template, data TmplPromptOpenAPI, llmConfig *NLAPIConfig) *openAPIOperationParams {
+	maxTokens := 0
+	if llmConfig != nil {
+		maxTokens = llmConfig.MaxOperationPromptTokens
+	}
+	operationString, err := buildBudgetedOperationString(operation, maxTokens)
 	if err != nil {
 		logger.Errorf("[+] Error while building operation string: %s", err)
 		return nil
 	}
+	data.Operation = operationString
 
 	systemPromptBuf := new(bytes.Buffer)
-	err = tmplQuerySystemPrompt.Execute(systemPromptBuf, TmplPromptOpenAPI{Operation: string(operationString), Sentence: nlSentence})
+	err = tmplQuerySystemPrompt.Execute(systemPromptBuf, data)
 	if err != nil {
 		logger.Errorf("[+] Error while creating the System prompt: %s", err)
 		return nil
 	}
 
 	userPromptBuf := new(bytes.Buffer)
-	err = tmplQueryUserPrompt.Execute(userPromptBuf, TmplPromptOpenAPI{Operation: string(operationString), Sentence: nlSentence})
+	err = tmplUserPrompt.Execute(userPromptBuf, data)
 	if err != nil {
 		logger.Errorf("[+] Error while creating the User prompt: %s", err)
 		return nil
 	}
 
+	structuredResponse, err := buildStructuredOASResponse(operation)
+	if err != nil {
+		logger.Errorf("[+] Error while building the request schema: %s", err)
+		return nil
+	}
 	operationTool := JsonSchemaResponse{
 		Name:        "convert_to_openapi",
 		Description: "",
-		Schema:      structuredOASResponse,
+		Schema:      structuredResponse,
 	}
 	translation, err := llmCall(context, systemPromptBuf.String(), userPromptBuf.String(), &operationTool, llmConfig)
 	if err != nil {
@@ -367,52 +557,21 @@ func llmNlToOpenAPIRequest(context context.Context, operation *openapi3.Operatio
 	return &llmOperation
 }
 
-type JsonSchemaResponse struct {
-	Name        string
-	Description string
-	Schema      []byte
-}
-
 func llmCall(ctx context.Context, systemPrompt string, data string, schemaResponse *JsonSchemaResponse, llmConfig *NLAPIConfig) (string, error) {
 	logger.Debugf("[+] Generated system prompt: %s", systemPrompt)
 	logger.Debugf("[+] Generated user prompt: %s", data)
 
-	chatCompletions := azopenai.ChatCompletionsOptions{
-		Messages: []azopenai.ChatRequestMessageClassification{
-			&azopenai.ChatRequestSystemMessage{
-				Content: azopenai.NewChatRequestSystemMessageContent(systemPrompt),
-			},
-			&azopenai.ChatRequestUserMessage{
-				Content: azopenai.NewChatRequestUserMessageContent(data),
-			},
-		},
-		MaxTokens:      to.Ptr(int32(2048)),
-		Temperature:    to.Ptr(float32(DEFAULT_LLM_TEMPERATURE)),
-		Seed:           to.Ptr(int64(DEFAULT_LLM_SEED)),
-		DeploymentName: &llmConfig.AzureConfig.ModelDeployment,
-	}
-
-	if schemaResponse != nil {
-		chatCompletions.ResponseFormat = &azopenai.ChatCompletionsJSONSchemaResponseFormat{
-			JSONSchema: &azopenai.ChatCompletionsJSONSchemaResponseFormatJSONSchema{
-				Name:        &schemaResponse.Name,
-				Description: &schemaResponse.Description,
-				Schema:      schemaResponse.Schema,
-				Strict:      to.Ptr(false),
-			},
-		}
+	if llmConfig == nil || llmConfig.provider == nil {
+		return "", fmt.Errorf("no LLM provider configured")
 	}
-	resp, err := llmConfig.azureClient.GetChatCompletions(ctx, chatCompletions, nil)
+
+	resp, err := llmConfig.provider.ChatCompletion(ctx, systemPrompt, data, schemaResponse)
 	if err != nil {
 		logger.Errorf("[+] Error translating text: %s", err)
 		return "", err
 	}
 
-	if len(resp.Choices) > 0 && resp.Choices[0].Message != nil && resp.Choices[0].Message.Content != nil {
-		return *resp.Choices[0].Message.Content, nil
-	}
-
-	return "", fmt.Errorf("unable to get a response from the LLM")
+	return resp, nil
 }
 
 func responseToNL(r *http.Request, upstreamResponse string) (string, error) {
@@ -449,7 +608,7 @@ func initQueryTemplates() {
 	var err error
 
 	systemPrompt := `Given an OpenAPI specification operation you convert the natural language sentence to a JSON object following the OpenAPI operation schema.
-You MUST use the exact name of the parameters. DO NOT invent. If information is missing, DO NOT include it. Do not forget to include the content type in the headers.
+You MUST use the exact name of the parameters. DO NOT invent. If information is missing, DO NOT include it.
 
 The OpenAPI operation specification:
 ====
@@ -461,6 +620,23 @@ The OpenAPI operation specification:
 {{.Sentence}}
 ====`
 
+	repairPrompt := `The natural language sentence:
+====
+{{.Sentence}}
+====
+
+Your previous JSON object was rejected by the OpenAPI validation:
+====
+{{.PreviousRequest}}
+====
+
+The validation errors:
+====
+{{range .ValidationErrors}}- {{.}}
+{{end}}====
+
+Fix the JSON object. Only use information from the natural language sentence, DO NOT invent values.`
+
 	tmplQuerySystemPrompt, err = template.New("system_prompt_convert_to_openapi").Parse(systemPrompt)
 	if err != nil {
 		logger.Fatalf("[+] Error parsing the system prompt template: %s", err)
@@ -469,6 +645,10 @@ The OpenAPI operation specification:
 	if err != nil {
 		logger.Fatalf("[+] Error parsing the user prompt template: %s", err)
 	}
+	tmplRepairUserPrompt, err = template.New("user_prompt_repair_openapi").Parse(repairPrompt)
+	if err != nil {
+		logger.Fatalf("[+] Error parsing the repair prompt template: %s", err)
+	}
 }
 
 func initResponseTemplates() {
@@ -500,43 +680,10 @@ The user's request:
 	}
 }
 
-func initStructuredOasResponse() {
-	structuredOASResponse = []byte(`{
-"type": "object",
-"properties": {
-  "in_path_params": {
-    "description": "The parameters that are inside the path",
-    "type": "object",
-    "additionalProperties": { "type": "string" }
-  },
-  "in_query_params": {
-    "description": "The parameters that are part of the query string. Each parameter is an array of strings",
-    "type": "object",
-    "additionalProperties": {
-      "type": "array",
-      "items": { "type": "string" }
-    }
-  },
-  "in_header_params": {
-    "description": "The parameters that are in the headers. Each parameter is an array of strings",
-    "type": "object",
-    "additionalProperties": {
-      "type": "array",
-      "items": { "type": "string" }
-    }
-  },
-  "request_body": {
-    "description": "The optional content of the body",
-    "type": "string"
-  }
-},
-"required": ["in_path_params", "in_query_params", "in_header_params", "request_body"],
-"additionalProperties": false
-}`)
-}
-
 func init() {
-	initStructuredOasResponse()
 	initQueryTemplates()
 	initResponseTemplates()
+	initPlannerTemplates()
+	initRerankTemplates()
+	initGenerateTemplates()
 }
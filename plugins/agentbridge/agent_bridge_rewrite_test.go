@@ -1,9 +1,15 @@
 // Copyright AGNTCY Contributors (https://github.com/agntcy)
 // SPDX-License-Identifier: Apache-2.0
 
-package main
+package agentbridge
 
 import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/TykTechnologies/kin-openapi/openapi3"
@@ -293,6 +299,71 @@ The list of References:
 - #/components/schemas/age0: {"properties":{"age":{"$ref":"#/components/schemas/age"},"romanage":{"type":"string"}},"type":"object"}
 - #/components/schemas/city: {"type":"string"}
 ===
+`,
+		},
+		{
+			"Every schema keyword, recursive schema and non-JSON content",
+			[]byte(`{
+        "openapi": "3.0.0",
+        "info": {
+          "title": "Minimal API",
+          "version": "1.0.0"
+        },
+        "paths": {
+          "/test": {
+            "get": {
+              "parameters": [
+                {
+                  "in": "query",
+                  "name": "filter",
+                  "content": {
+                    "application/json": {
+                      "schema": { "$ref": "#/components/schemas/Filter" }
+                    }
+                  }
+                }
+              ],
+              "requestBody": {
+                "content": {
+                  "application/xml": {
+                    "schema": { "$ref": "#/components/schemas/Node" }
+                  }
+                }
+              }
+            }
+          }
+        },
+        "components": {
+          "schemas": {
+            "Base": {
+              "type": "object",
+              "properties": { "id": { "type": "string" } }
+            },
+            "Filter": {
+              "type": "object",
+              "properties": { "owner": { "type": "string" } }
+            },
+            "Label": {
+              "type": "string"
+            },
+            "Node": {
+              "allOf": [{ "$ref": "#/components/schemas/Base" }],
+              "properties": {
+                "children": { "type": "array", "items": { "$ref": "#/components/schemas/Node" } },
+                "labels": { "type": "object", "additionalProperties": { "$ref": "#/components/schemas/Label" } }
+              }
+            }
+          }
+        }
+      }`),
+			`{"parameters":[{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Filter"}}},"in":"query","name":"filter"}],"requestBody":{"content":{"application/xml":{"schema":{"$ref":"#/components/schemas/Node"}}}},"responses":null}
+The list of References:
+===
+- #/components/schemas/Base: {"properties":{"id":{"type":"string"}},"type":"object"}
+- #/components/schemas/Filter: {"properties":{"owner":{"type":"string"}},"type":"object"}
+- #/components/schemas/Label: {"type":"string"}
+- #/components/schemas/Node: {"allOf":[{"$ref":"#/components/schemas/Base"}],"properties":{"children":{"items":{"$ref":"#/components/schemas/Node"},"type":"array"},"labels":{"additionalProperties":{"$ref":"#/components/schemas/Label"},"type":"object"}}}
+===
 `,
 		},
 	}
@@ -310,3 +381,87 @@ The list of References:
 		})
 	}
 }
+
+var updateGolden = flag.Bool("update", false, "update the golden files in testdata")
+
+// The Jira spec has recursive schemas, references in additionalProperties and
+// multipart bodies
+func TestBuildOperationStringGolden(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromFile("../../configs/your-domain.atlassian.net.oas.json")
+	assert.Nil(t, err)
+
+	tests := []struct {
+		path   string
+		method string
+	}{
+		{"/rest/api/3/issue", "POST"},
+		{"/rest/api/3/issue/{issueIdOrKey}/attachments", "POST"},
+		{"/rest/api/3/bulk/issues/move", "POST"},
+		{"/rest/api/3/workflowscheme", "POST"},
+		{"/rest/api/3/issue/properties/multi", "POST"},
+	}
+
+	for _, tt := range tests {
+		operation := doc.Paths[tt.path].GetOperation(tt.method)
+		t.Run(operation.OperationID, func(t *testing.T) {
+			got, err := buildOperationString(operation)
+			assert.Nil(t, err)
+
+			golden := filepath.Join("testdata", "operation_strings", operation.OperationID+".txt")
+			if *updateGolden {
+				assert.Nil(t, os.MkdirAll(filepath.Dir(golden), 0o755))
+				assert.Nil(t, os.WriteFile(golden, []byte(got), 0o644))
+			}
+			expected, err := os.ReadFile(golden)
+			assert.Nil(t, err)
+			assert.Equal(t, string(expected), got)
+			assert.NotContains(t, got, "kin-openapi")
+		})
+	}
+}
+
+const cookieTestSpec = `{
+	"openapi": "3.0.0",
+	"info": {"title": "Dashboards", "version": "1.0.0"},
+	"paths": {
+		"/dashboards": {
+			"get": {
+				"operationId": "listDashboards",
+				"parameters": [
+					{"name": "theme", "in": "cookie", "required": true, "schema": {"type": "string", "enum": ["light", "dark"]}},
+					{"name": "lang", "in": "cookie", "schema": {"type": "string"}}
+				],
+				"responses": {"200": {"description": "OK"}}
+			}
+		}
+	},
+	"components": {
+		"securitySchemes": {"session": {"type": "apiKey", "in": "cookie", "name": "session"}}
+	}
+}`
+
+func TestApplyOpenAPIParamsWithCookies(t *testing.T) {
+	route := getTestRoute(t, cookieTestSpec, "/dashboards", http.MethodGet)
+
+	operationString, err := buildOperationString(route.Operation)
+	assert.Nil(t, err)
+	assert.Contains(t, operationString, `{"in":"cookie","name":"theme","required":true,`)
+
+	params := &openAPIOperationParams{
+		InHeaderParams: http.Header{"cookie": {"session=stolen"}},
+		InCookieParams: map[string]string{"theme": "dark", "lang": "fr", "session": "stolen"},
+	}
+	r := httptest.NewRequest(http.MethodPost, "/dashboards", strings.NewReader("list my dashboards in dark mode"))
+	r.Header.Set("Cookie", "session=secret; theme=light; legacy=a,b; tracking=1")
+	assert.Empty(t, validateOpenAPIParams(r, route, map[string]string{}, params))
+
+	assert.Nil(t, applyOpenAPIParams(r, route, map[string]string{}, params))
+	// The credentials are never overridden
+	// The other cookies are kept byte for byte
+	assert.Equal(t, "session=secret; legacy=a,b; tracking=1; lang=fr; theme=dark", r.Header.Get("Cookie"))
+	assert.Equal(t, []string{"session"}, getNoOverrideCookies(route))
+
+	// An invalid cookie is rejected by the validation
+	params.InCookieParams["theme"] = "blue"
+	assert.Len(t, validateOpenAPIParams(r, route, map[string]string{}, params), 1)
+}
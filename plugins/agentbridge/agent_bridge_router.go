@@ -0,0 +1,62 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+	"github.com/TykTechnologies/kin-openapi/routers"
+	"github.com/TykTechnologies/kin-openapi/routers/gorillamux"
+	"github.com/TykTechnologies/tyk/apidef/oas"
+)
+
+var apiRouters = map[string]routers.Router{} // api id -> router on the paths of the spec
+var apiRoutersLock = &sync.RWMutex{}
+
+// newAPIRouter compiles the routes of the spec. The servers are ignored since
+// the listen path is stripped from the requests: the router is built on a
+// copy of the spec, the definition of the gateway is left as is.
+func newAPIRouter(apiDef *oas.OAS) (routers.Router, error) {
+	spec := apiDef.T
+	spec.Servers = openapi3.Servers{}
+	return gorillamux.NewRouter(&spec)
+}
+
+func initAPIRouter(apiId string, apiDef *oas.OAS) error {
+	router, err := newAPIRouter(apiDef)
+	if err != nil {
+		return fmt.Errorf("failed to build the router for api id %s: %w", apiId, err)
+	}
+
+	apiRoutersLock.Lock()
+	apiRouters[apiId] = router
+	apiRoutersLock.Unlock()
+	return nil
+}
+
+// getAPIRouter returns the router of the API, built on the first request when
+// the plugin configuration was not initialized on this gateway node.
+func getAPIRouter(apiId string, apiDef *oas.OAS) (routers.Router, error) {
+	apiRoutersLock.RLock()
+	router, present := apiRouters[apiId]
+	apiRoutersLock.RUnlock()
+	if present {
+		return router, nil
+	}
+
+	if err := initAPIRouter(apiId, apiDef); err != nil {
+		return nil, err
+	}
+	apiRoutersLock.RLock()
+	defer apiRoutersLock.RUnlock()
+	return apiRouters[apiId], nil
+}
+
+func deleteAPIRouter(apiId string) {
+	apiRoutersLock.Lock()
+	delete(apiRouters, apiId)
+	apiRoutersLock.Unlock()
+}
@@ -0,0 +1,91 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+	"github.com/TykTechnologies/kin-openapi/routers/gorillamux"
+	"github.com/TykTechnologies/tyk/apidef/oas"
+	"github.com/TykTechnologies/tyk/ctx"
+	"github.com/stretchr/testify/assert"
+)
+
+const jiraSpecFilename = "../../configs/your-domain.atlassian.net.oas.json"
+
+func loadJiraRouteRequest(tb testing.TB) (*oas.OAS, *http.Request) {
+	doc, err := openapi3.NewLoader().LoadFromFile(jiraSpecFilename)
+	assert.Nil(tb, err)
+	oasDef := &oas.OAS{T: *doc}
+
+	r := httptest.NewRequest(http.MethodGet, "/jira/rest/api/3/issue/PROJ-42/comment", nil)
+	r = r.WithContext(context.WithValue(r.Context(), ctx.OASDefinition, oasDef))
+	return oasDef, r
+}
+
+func TestGetRoute(t *testing.T) {
+	oasDef, r := loadJiraRouteRequest(t)
+	apiId := oasDef.GetTykExtension().Info.ID
+	t.Cleanup(func() { deleteAPIRouter(apiId) })
+
+	route, pathParams, err := getRoute(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "getComments", route.Operation.OperationID)
+	assert.Equal(t, "PROJ-42", pathParams["issueIdOrKey"])
+	// The definition of the gateway keeps its servers
+	assert.Equal(t, "https://your-domain.atlassian.net", oasDef.Servers[0].URL)
+
+	// The router is built once
+	apiRoutersLock.RLock()
+	router := apiRouters[apiId]
+	apiRoutersLock.RUnlock()
+	assert.NotNil(t, router)
+	_, _, err = getRoute(r)
+	assert.Nil(t, err)
+	apiRoutersLock.RLock()
+	assert.Same(t, router, apiRouters[apiId])
+	apiRoutersLock.RUnlock()
+
+	// Until the API is deleted
+	deletePluginConfig(apiId)
+	apiRoutersLock.RLock()
+	_, present := apiRouters[apiId]
+	apiRoutersLock.RUnlock()
+	assert.False(t, present)
+
+	r.URL.Path = "/jira/unknown"
+	_, _, err = getRoute(r)
+	assert.NotNil(t, err)
+}
+
+func BenchmarkGetRoute(b *testing.B) {
+	oasDef, r := loadJiraRouteRequest(b)
+	b.Cleanup(func() { deleteAPIRouter(oasDef.GetTykExtension().Info.ID) })
+
+	b.Run("Cached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, _, err := getRoute(r); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	// What getRoute did before the routers were cached
+	b.Run("PerRequest", func(b *testing.B) {
+		fakeReq := &http.Request{Method: r.Method, URL: &url.URL{Path: "/rest/api/3/issue/PROJ-42/comment"}}
+		for i := 0; i < b.N; i++ {
+			spec := oasDef.T
+			spec.Servers = openapi3.Servers{}
+			router, _ := gorillamux.NewRouter(&spec)
+			if _, _, err := router.FindRoute(fakeReq); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
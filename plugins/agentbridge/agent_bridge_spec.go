@@ -0,0 +1,96 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+	"github.com/TykTechnologies/tyk/apidef/oas"
+)
+
+// SpecFilenames are the OpenAPI specs of the command line tools, by apiId. As
+// a flag, each value is an "apiId=path".
+type SpecFilenames map[string]string
+
+func (specs SpecFilenames) String() string {
+	values := []string{}
+	for apiId, path := range specs {
+		values = append(values, apiId+"="+path)
+	}
+	sort.Strings(values)
+	return strings.Join(values, ",")
+}
+
+func (specs SpecFilenames) Set(value string) error {
+	apiId, path, found := strings.Cut(value, "=")
+	if !found || apiId == "" || path == "" {
+		return fmt.Errorf("expecting apiId=path, got '%s'", value)
+	}
+	specs[apiId] = path
+	return nil
+}
+
+// APIIDs returns the apiIds of the specs, sorted.
+func (specs SpecFilenames) APIIDs() []string {
+	apiIds := make([]string, 0, len(specs))
+	for apiId := range specs {
+		apiIds = append(apiIds, apiId)
+	}
+	sort.Strings(apiIds)
+	return apiIds
+}
+
+// LoadSpec reads an OpenAPI spec with the plugin configuration found in its
+// x-tyk-api-gateway extension, and creates its LLM provider. Nothing is
+// registered: see LoadAPI.
+func LoadSpec(apiId string, specFilename string) (*PluginDataConfig, *oas.OAS, error) {
+	doc, err := openapi3.NewLoader().LoadFromFile(specFilename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't load spec %s: %s", specFilename, err)
+	}
+	apiDef := &oas.OAS{T: *doc}
+
+	configData := map[string]any{}
+	if middleware := apiDef.GetTykMiddleware(); middleware != nil && middleware.Global != nil &&
+		middleware.Global.PluginConfig != nil && middleware.Global.PluginConfig.Data != nil {
+		configData = middleware.Global.PluginConfig.Data.Value
+	}
+	pluginDataConfig, err := parseConfigData(apiId, configData)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't parse the plugin config of %s: %s", specFilename, err)
+	}
+	provider, err := newLLMProvider(pluginDataConfig.LlmProvider, configData)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to create LLM provider %s: %s", pluginDataConfig.LlmProvider, err)
+	}
+	pluginDataConfig.LlmConfig = &NLAPIConfig{
+		AzureConfig:              pluginDataConfig.AzureConfig,
+		MaxOperationPromptTokens: pluginDataConfig.MaxOperationPromptTokens,
+		provider:                 provider,
+	}
+	return pluginDataConfig, apiDef, nil
+}
+
+// LoadAPI initializes the operation selection of an API loaded by LoadSpec,
+// as the gateway does when the API is loaded, so that SelectOperation works
+// without the gateway. The store isn't needed.
+func LoadAPI(pluginDataConfig *PluginDataConfig, apiDef *oas.OAS) error {
+	apiId := pluginDataConfig.APIID
+	addSpecSelectOperations(pluginDataConfig, apiDef)
+	if len(pluginDataConfig.SelectOperations) == 0 {
+		return fmt.Errorf("no operation to select for api id: %s", apiId)
+	}
+
+	pluginConfigLock.Lock()
+	pluginConfig[apiId] = pluginDataConfig
+	pluginConfigLock.Unlock()
+	// Note: create embedder before initializing indices!
+	if err := loadEmbedder(apiId, pluginDataConfig); err != nil {
+		return err
+	}
+	return initSelectOperations(apiId, pluginDataConfig, apiDef)
+}
@@ -1,12 +1,13 @@
 // Copyright AGNTCY Contributors (https://github.com/agntcy)
 // SPDX-License-Identifier: Apache-2.0
 
-package main
+package agentbridge
 
 import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/TykTechnologies/tyk/config"
@@ -18,6 +19,15 @@ const (
 	AGENT_BRIDGE_DEFAULT_TTL        = -1
 )
 
+// Keys with these prefixes are not API configurations
+var reservedStoreKeyPrefixes = []string{
+	CLARIFICATION_KEY_PREFIX,
+	PENDING_ACTION_KEY_PREFIX,
+	GENERATED_EXAMPLES_KEY_PREFIX,
+	SELECTION_KEY_PREFIX,
+	LEARNED_EXAMPLE_KEY_PREFIX,
+}
+
 var agentBridgeStore *storage.RedisCluster
 var storeVersion int64 = 0
 var storeVersionLock = &sync.RWMutex{}
@@ -30,6 +40,23 @@ func safeIncrementStoreVersion() int64 {
 	return storeVersion
 }
 
+func isReservedStoreKey(key string) bool {
+	for _, prefix := range reservedStoreKeyPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ConnectStore connects the plugin to the Redis store of the gateway. Without
+// it, nothing is shared between the gateway nodes nor kept across restarts.
+func ConnectStore(ctx context.Context) {
+	if agentBridgeStore == nil {
+		agentBridgeStore = getStorageForPlugin(ctx)
+	}
+}
+
 func getStorageForPlugin(ctx context.Context) *storage.RedisCluster {
 	rc := storage.NewConnectionHandler(ctx)
 
@@ -42,9 +69,6 @@ func getStorageForPlugin(ctx context.Context) *storage.RedisCluster {
 }
 
 func saveApiUterances(apiID string, pluginDataConfig *PluginDataConfig) error {
-	pluginConfigLock.Lock()
-	defer pluginConfigLock.Unlock()
-
 	if pluginDataConfig == nil {
 		return fmt.Errorf("pluginDataConfig is nil")
 	}
@@ -0,0 +1,36 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+	"github.com/TykTechnologies/kin-openapi/routers"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMain connects the store, as the plugin does when the gateway loads it.
+func TestMain(m *testing.M) {
+	ConnectStore(context.TODO())
+	os.Exit(m.Run())
+}
+
+// getTestRoute loads the inline spec of a test and returns the route of one
+// of its operations.
+func getTestRoute(t *testing.T, spec string, path string, method string) *routers.Route {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(spec))
+	assert.Nil(t, err)
+
+	pathItem := doc.Paths[path]
+	return &routers.Route{
+		Spec:      doc,
+		Path:      path,
+		PathItem:  pathItem,
+		Method:    method,
+		Operation: pathItem.GetOperation(method),
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"mime"
+	"net/http"
+
+	"github.com/TykTechnologies/kin-openapi/openapi3"
+	"github.com/TykTechnologies/kin-openapi/openapi3filter"
+	"github.com/TykTechnologies/kin-openapi/routers"
+)
+
+const (
+	DEFAULT_VALIDATE_REQUESTS   = false
+	DEFAULT_MAX_REPAIR_ATTEMPTS = 2
+)
+
+// nlQueryError is returned when a natural language query can't be turned
+// into a valid request. It is sent back to the client as a JSON document.
+type nlQueryError struct {
+	StatusCode int      `json:"-"`
+	Message    string   `json:"message"`
+	Errors     []string `json:"errors,omitempty"`
+
+	// Set when the user is asked for more information
+	MissingFields  []string `json:"missing_fields,omitempty"`
+	ConversationID string   `json:"conversation_id,omitempty"`
+}
+
+func (e *nlQueryError) Error() string {
+	return e.Message
+}
+
+// writeRewriteError sends the error back to the client, using the status code
+// of nlQueryError when there is one.
+func writeRewriteError(rw http.ResponseWriter, err error) {
+	var queryErr *nlQueryError
+	if !errors.As(err, &queryErr) {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response, err := json.Marshal(queryErr)
+	if err != nil {
+		http.Error(rw, queryErr.Message, queryErr.StatusCode)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(queryErr.StatusCode)
+	_, _ = rw.Write(response)
+}
+
+// validateAndRepairOpenAPIRequest validates the parameters generated by the
+// LLM against the operation. On failure, the validation errors are given back
+// to the LLM to fix the request, up to MaxRepairAttempts times.
+func validateAndRepairOpenAPIRequest(r *http.Request, route *routers.Route, pathParams map[string]string, nlSentence string, params *openAPIOperationParams, config *PluginDataConfig) (*openAPIOperationParams, error) {
+	for attempt := 0; ; attempt++ {
+		validationErrors := validateOpenAPIParams(r, route, pathParams, params)
+		if len(validationErrors) == 0 {
+			return params, nil
+		}
+		logger.Debugf("[+] Generated request is invalid (attempt %d/%d): %v", attempt, config.MaxRepairAttempts, validationErrors)
+
+		if attempt >= config.MaxRepairAttempts {
+			return nil, &nlQueryError{
+				StatusCode: http.StatusBadRequest,
+				Message:    "i'm sorry but I was not able to build a valid request from your query",
+				Errors:     validationErrors,
+			}
+		}
+
+		repairedParams := llmRepairOpenAPIRequest(r.Context(), route.Operation, nlSentence, params, validationErrors, config.LlmConfig)
+		if repairedParams == nil {
+			logger.Errorf("[+] Error repairing the request")
+			return nil, &nlQueryError{
+				StatusCode: http.StatusBadRequest,
+				Message:    "i'm sorry but I was not able to build a valid request from your query",
+				Errors:     validationErrors,
+			}
+		}
+		params = repairedParams
+	}
+}
+
+// validateOpenAPIParams returns the list of problems of the request that would
+// be sent upstream, or nothing if it follows the operation specification.
+func validateOpenAPIParams(r *http.Request, route *routers.Route, pathParams map[string]string, params *openAPIOperationParams) []string {
+	if route.Spec == nil || route.PathItem == nil {
+		logger.Debugf("[+] Incomplete route for %s %s, skipping validation", route.Method, route.Path)
+		return nil
+	}
+
+	candidate := r.Clone(r.Context())
+	if err := applyOpenAPIParams(candidate, route, pathParams, params); err != nil {
+		return []string{err.Error()}
+	}
+
+	candidatePathParams := maps.Clone(pathParams)
+	if candidatePathParams == nil {
+		candidatePathParams = map[string]string{}
+	}
+	maps.Copy(candidatePathParams, params.InPathParams)
+
+	// The bodies kin-openapi can't decode, such as XML, are sent as is
+	mediaType, _, _ := mime.ParseMediaType(candidate.Header.Get("Content-Type"))
+	excludeRequestBody := candidate.Body != nil && openapi3filter.RegisteredBodyDecoder(mediaType) == nil
+
+	input := &openapi3filter.RequestValidationInput{
+		Request:    candidate,
+		PathParams: candidatePathParams,
+		Route:      route,
+		Options: &openapi3filter.Options{
+			MultiError:         true,
+			ExcludeRequestBody: excludeRequestBody,
+			// Authentication is the job of the gateway and the upstream
+			AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+		},
+	}
+	err := openapi3filter.ValidateRequest(r.Context(), input)
+	if err == nil {
+		return nil
+	}
+
+	return flattenValidationErrors(err)
+}
+
+func flattenValidationErrors(err error) []string {
+	var multiError openapi3.MultiError
+	if errors.As(err, &multiError) {
+		messages := []string{}
+		for _, e := range multiError {
+			messages = append(messages, flattenValidationErrors(e)...)
+		}
+		return messages
+	}
+	return []string{err.Error()}
+}
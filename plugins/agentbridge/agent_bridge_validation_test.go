@@ -0,0 +1,142 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package agentbridge
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const validationTestSpec = `{
+	"openapi": "3.0.0",
+	"info": {"title": "Releases", "version": "1.0.0"},
+	"paths": {
+		"/releases/{id}": {
+			"put": {
+				"operationId": "updateRelease",
+				"parameters": [
+					{"name": "id", "in": "path", "required": true, "schema": {"type": "integer"}},
+					{"name": "notify", "in": "query", "schema": {"type": "boolean"}}
+				],
+				"requestBody": {
+					"required": true,
+					"content": {
+						"application/json": {
+							"schema": {
+								"type": "object",
+								"required": ["name"],
+								"properties": {"name": {"type": "string"}}
+							}
+						}
+					}
+				},
+				"responses": {"200": {"description": "OK"}}
+			}
+		}
+	}
+}`
+
+func TestValidateOpenAPIParams(t *testing.T) {
+	tests := []struct {
+		description    string
+		params         string
+		expectedErrors []string
+	}{
+		{
+			"Valid request",
+			`{"in_path_params": {"id": "250"}, "in_query_params": {"notify": ["true"]}, "request_body": {"name": "v1.0"}}`,
+			nil,
+		},
+		{
+			"Wrong type in path",
+			`{"in_path_params": {"id": "latest"}, "request_body": {"name": "v1.0"}}`,
+			[]string{`parameter "id" in path has an error`},
+		},
+		{
+			"Missing required body and invalid query",
+			`{"in_path_params": {"id": "250"}, "in_query_params": {"notify": ["maybe"]}}`,
+			[]string{`parameter "notify" in query has an error`, "request body has an error"},
+		},
+	}
+
+	route := getTestRoute(t, validationTestSpec, "/releases/{id}", http.MethodPut)
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			params := &openAPIOperationParams{}
+			assert.Nil(t, json.Unmarshal([]byte(tt.params), params))
+
+			r := httptest.NewRequest(http.MethodPost, "/releases/{id}", strings.NewReader("rename the release 250 to v1.0"))
+			validationErrors := validateOpenAPIParams(r, route, map[string]string{}, params)
+			assert.Len(t, validationErrors, len(tt.expectedErrors))
+			for i, expectedError := range tt.expectedErrors {
+				assert.Contains(t, validationErrors[i], expectedError)
+			}
+		})
+	}
+}
+
+func TestValidateAndRepairOpenAPIRequest(t *testing.T) {
+	invalid := `{"in_path_params": {"id": "latest"}, "request_body": {"name": "v1.0"}}`
+	valid := `{"in_path_params": {"id": "250"}, "request_body": {"name": "v1.0"}}`
+
+	tests := []struct {
+		description       string
+		maxRepairAttempts int
+		completions       []string
+		expectedCalls     int
+		expectedError     bool
+	}{
+		{"Repaired on the first attempt", 2, []string{valid}, 1, false},
+		{"Repaired on the last attempt", 2, []string{invalid, valid}, 2, false},
+		{"Still invalid after all the attempts", 2, []string{invalid, invalid}, 2, true},
+		{"No repair allowed", 0, []string{}, 0, true},
+	}
+
+	route := getTestRoute(t, validationTestSpec, "/releases/{id}", http.MethodPut)
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			fake := &fakeLLMProvider{completions: tt.completions}
+			config := &PluginDataConfig{
+				LlmConfig:         &NLAPIConfig{provider: fake},
+				ValidateRequests:  true,
+				MaxRepairAttempts: tt.maxRepairAttempts,
+			}
+			params := &openAPIOperationParams{}
+			assert.Nil(t, json.Unmarshal([]byte(invalid), params))
+
+			r := httptest.NewRequest(http.MethodPost, "/releases/{id}", nil)
+			newParams, err := validateAndRepairOpenAPIRequest(r, route, map[string]string{}, "rename the release 250 to v1.0", params, config)
+			assert.Len(t, fake.userPrompts, tt.expectedCalls)
+			if tt.expectedError {
+				var queryErr *nlQueryError
+				assert.ErrorAs(t, err, &queryErr)
+				assert.Equal(t, http.StatusBadRequest, queryErr.StatusCode)
+				assert.NotEmpty(t, queryErr.Errors)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, map[string]string{"id": "250"}, newParams.InPathParams)
+			// The LLM must be told why its previous answer was rejected
+			assert.Contains(t, fake.userPrompts[0], `parameter "id" in path has an error`)
+		})
+	}
+}
+
+func TestWriteRewriteError(t *testing.T) {
+	rw := httptest.NewRecorder()
+	writeRewriteError(rw, &nlQueryError{StatusCode: http.StatusBadRequest, Message: "invalid", Errors: []string{"missing id"}})
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+	assert.Equal(t, "application/json", rw.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"message": "invalid", "errors": ["missing id"]}`, rw.Body.String())
+
+	rw = httptest.NewRecorder()
+	writeRewriteError(rw, assert.AnError)
+	assert.Equal(t, http.StatusInternalServerError, rw.Code)
+}
@@ -0,0 +1,345 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+// nl-eval evaluates the operation selection of some APIs on labeled queries,
+// as the gateway selects the operations: top-k, re-ranking and hybrid search.
+// It doesn't need the store of the gateway.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/TykTechnologies/tyk/apidef/oas"
+
+	"agent-bridge-plugin/agentbridge"
+)
+
+const (
+	NL_EVAL_COMMAND = "nl-eval"
+
+	NL_EVAL_DEFAULT_QUERIES = "agentbridge/testdata/endpoint_selection_requests_to_test.json"
+	NL_EVAL_DEFAULT_TOP_K   = 5
+	NL_EVAL_THRESHOLD_STEP  = 0.05
+	NL_EVAL_ALL_APIS        = "all"
+
+	NL_EVAL_FORMAT_TABLE = "table"
+	NL_EVAL_FORMAT_JSON  = "json"
+)
+
+// nlEvalQuery is a labeled query, in the format of the endpoint selection
+// tests.
+type nlEvalQuery struct {
+	TargetApiID       string `json:"api_id"`
+	Query             string `json:"query"`
+	ExpectedOperation string `json:"expected"` // Empty when no operation should be selected
+	ReachThreshold    bool   `json:"reach_threshold"`
+}
+
+func loadNLEvalQueries(filename string) ([]nlEvalQuery, error) {
+	var tests []nlEvalQuery
+	file, err := os.Open(filename)
+	if err != nil {
+		return tests, fmt.Errorf("can't open file %s: %s", filename, err)
+	}
+	defer file.Close()
+	byteValue, _ := io.ReadAll(file)
+	err = json.Unmarshal(byteValue, &tests)
+	if err != nil {
+		return tests, fmt.Errorf("can't unmarshal file %s: %s", filename, err)
+	}
+	return tests, nil
+}
+
+// nlEvalResult is the outcome of the selection for one labeled query.
+type nlEvalResult struct {
+	APIID      string
+	Query      string
+	Expected   string
+	Selected   *string // As selected by the gateway
+	Score      float64
+	Candidates []agentbridge.OperationCandidate // The top-k operations
+}
+
+type nlEvalConfusion struct {
+	Expected string `json:"expected"`
+	Selected string `json:"selected"` // Empty when no operation was found
+	Count    int    `json:"count"`
+}
+
+type nlEvalThreshold struct {
+	Threshold float64 `json:"threshold"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	F1        float64 `json:"f1"`
+}
+
+type nlEvalReport struct {
+	APIID     string `json:"apiId"`
+	Queries   int    `json:"queries"`   // Queries with an expected operation
+	Negatives int    `json:"negatives"` // Queries that shouldn't select any operation
+	// Accuracy is the rate of queries whose best operation is the expected one, whatever its score
+	Accuracy   float64 `json:"accuracy"`
+	TopK       int     `json:"topK"`
+	TopKRecall float64 `json:"topKRecall"` // Rate of queries with the expected operation in the top-k
+	// Confusions are the expected operations and the ones selected instead, the most frequent first
+	Confusions []nlEvalConfusion `json:"confusions"`
+	// Curve is the precision and recall of the selection for each relevanceThreshold
+	Curve []nlEvalThreshold `json:"curve"`
+	// RecommendedThreshold has the best F1 score, the highest one on a tie
+	RecommendedThreshold float64 `json:"recommendedThreshold"`
+}
+
+type nlEvalSummary struct {
+	APIs    []nlEvalReport `json:"apis"`
+	Overall nlEvalReport   `json:"overall"`
+}
+
+func main() {
+	os.Exit(runNLEval(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// nlEvalAPI is an API loaded as the gateway does.
+type nlEvalAPI struct {
+	config *agentbridge.PluginDataConfig
+	apiDef *oas.OAS
+}
+
+// runNLEval returns the exit code of the command.
+func runNLEval(args []string, stdout io.Writer, stderr io.Writer) int {
+	specs := agentbridge.SpecFilenames{}
+	flags := flag.NewFlagSet(NL_EVAL_COMMAND, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Var(specs, "spec", "OpenAPI spec of an API, as apiId=path (repeatable)")
+	queriesFilename := flags.String("queries", NL_EVAL_DEFAULT_QUERIES, "JSON file of the labeled queries")
+	modelsPath := flags.String("models", "", "directory of the embedding models, overrides selectModelsPath")
+	topK := flags.Int("k", NL_EVAL_DEFAULT_TOP_K, "number of candidate operations for the top-k recall")
+	lexicalWeight := flags.Float64("lexical-weight", -1, "overrides lexicalWeight when between 0 and 1")
+	format := flags.String("format", NL_EVAL_FORMAT_TABLE, "output format: table or json")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if len(specs) == 0 || *topK < 1 || (*format != NL_EVAL_FORMAT_TABLE && *format != NL_EVAL_FORMAT_JSON) {
+		flags.Usage()
+		return 2
+	}
+
+	queries, err := loadNLEvalQueries(*queriesFilename)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s\n", err)
+		return 1
+	}
+	apis := map[string]nlEvalAPI{}
+	for _, apiId := range specs.APIIDs() {
+		api, err := loadNLEvalAPI(apiId, specs[apiId], *modelsPath, *lexicalWeight)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s\n", err)
+			return 1
+		}
+		apis[apiId] = api
+	}
+
+	results := []nlEvalResult{}
+	for _, query := range queries {
+		api, present := apis[query.TargetApiID]
+		if !present {
+			continue
+		}
+		result, err := evalNLQuery(context.Background(), api, query, *topK)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s\n", err)
+			return 1
+		}
+		results = append(results, result)
+	}
+
+	summary := newNLEvalSummary(results, *topK)
+	if *format == NL_EVAL_FORMAT_JSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(summary)
+	} else {
+		err = writeNLEvalTable(stdout, summary)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "%s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// loadNLEvalAPI initializes the API as the gateway does, with the plugin
+// configuration found in the x-tyk-api-gateway extension of the spec.
+func loadNLEvalAPI(apiId string, specFilename string, modelsPath string, lexicalWeight float64) (nlEvalAPI, error) {
+	pluginDataConfig, apiDef, err := agentbridge.LoadSpec(apiId, specFilename)
+	if err != nil {
+		return nlEvalAPI{}, err
+	}
+	if modelsPath != "" {
+		pluginDataConfig.SelectModelsPath = modelsPath
+	}
+	if lexicalWeight >= 0 && lexicalWeight <= 1 {
+		pluginDataConfig.LexicalWeight = lexicalWeight
+	}
+	if err := agentbridge.LoadAPI(pluginDataConfig, apiDef); err != nil {
+		return nlEvalAPI{}, fmt.Errorf("can't load %s: %s", specFilename, err)
+	}
+	return nlEvalAPI{config: pluginDataConfig, apiDef: apiDef}, nil
+}
+
+// evalNLQuery selects the operation of the query as selectAndRewrite does,
+// so with the re-ranking by the LLM when selectTopK is set. The re-ranking
+// only sees the candidates reaching relevanceThreshold.
+func evalNLQuery(ctx context.Context, api nlEvalAPI, query nlEvalQuery, topK int) (nlEvalResult, error) {
+	result := nlEvalResult{APIID: query.TargetApiID, Query: query.Query, Expected: query.ExpectedOperation}
+
+	selection, err := agentbridge.SelectOperation(ctx, api.apiDef, api.config, query.Query)
+	if err != nil {
+		return result, fmt.Errorf("selection failed for query '%s': %s", query.Query, err)
+	}
+	if selection != nil {
+		result.Selected, result.Score = &selection.OperationID, selection.Relevance
+	}
+	result.Candidates, err = agentbridge.FindSelectOperationCandidates(ctx, query.TargetApiID, query.Query, topK)
+	if err != nil {
+		return result, fmt.Errorf("selection failed for query '%s': %s", query.Query, err)
+	}
+	return result, nil
+}
+
+func newNLEvalSummary(results []nlEvalResult, topK int) nlEvalSummary {
+	resultsByAPI := map[string][]nlEvalResult{}
+	for _, result := range results {
+		resultsByAPI[result.APIID] = append(resultsByAPI[result.APIID], result)
+	}
+
+	summary := nlEvalSummary{APIs: []nlEvalReport{}}
+	for apiId, apiResults := range resultsByAPI {
+		summary.APIs = append(summary.APIs, newNLEvalReport(apiId, apiResults, topK))
+	}
+	sort.Slice(summary.APIs, func(i, j int) bool { return summary.APIs[i].APIID < summary.APIs[j].APIID })
+	summary.Overall = newNLEvalReport(NL_EVAL_ALL_APIS, results, topK)
+	return summary
+}
+
+func newNLEvalReport(apiId string, results []nlEvalResult, topK int) nlEvalReport {
+	report := nlEvalReport{APIID: apiId, TopK: topK, Confusions: []nlEvalConfusion{}, Curve: []nlEvalThreshold{}}
+
+	correct, inTopK := 0, 0
+	confusions := map[[2]string]int{}
+	for _, result := range results {
+		if result.Expected == "" {
+			report.Negatives++
+			continue
+		}
+		report.Queries++
+		if result.Selected != nil && *result.Selected == result.Expected {
+			correct++
+		} else {
+			selected := ""
+			if result.Selected != nil {
+				selected = *result.Selected
+			}
+			confusions[[2]string{result.Expected, selected}]++
+		}
+		for _, candidate := range result.Candidates {
+			if candidate.OperationID == result.Expected {
+				inTopK++
+				break
+			}
+		}
+	}
+	if report.Queries > 0 {
+		report.Accuracy = float64(correct) / float64(report.Queries)
+		report.TopKRecall = float64(inTopK) / float64(report.Queries)
+	}
+
+	for pair, count := range confusions {
+		report.Confusions = append(report.Confusions, nlEvalConfusion{Expected: pair[0], Selected: pair[1], Count: count})
+	}
+	sort.Slice(report.Confusions, func(i, j int) bool {
+		if report.Confusions[i].Count != report.Confusions[j].Count {
+			return report.Confusions[i].Count > report.Confusions[j].Count
+		}
+		if report.Confusions[i].Expected != report.Confusions[j].Expected {
+			return report.Confusions[i].Expected < report.Confusions[j].Expected
+		}
+		return report.Confusions[i].Selected < report.Confusions[j].Selected
+	})
+
+	bestF1 := -1.0
+	steps := int(math.Round(1 / NL_EVAL_THRESHOLD_STEP))
+	for step := 0; step <= steps; step++ {
+		point := newNLEvalThreshold(results, report.Queries, float64(step)/float64(steps))
+		report.Curve = append(report.Curve, point)
+		if point.F1 >= bestF1 {
+			bestF1 = point.F1
+			report.RecommendedThreshold = point.Threshold
+		}
+	}
+	return report
+}
+
+// newNLEvalThreshold computes the precision and the recall of the selection
+// when the operations below the threshold are rejected, as the gateway does
+// with relevanceThreshold.
+func newNLEvalThreshold(results []nlEvalResult, queries int, threshold float64) nlEvalThreshold {
+	accepted, correct := 0, 0
+	for _, result := range results {
+		if result.Selected == nil || result.Score < threshold {
+			continue
+		}
+		accepted++
+		if *result.Selected == result.Expected {
+			correct++
+		}
+	}
+
+	point := nlEvalThreshold{Threshold: threshold, Precision: 1}
+	if accepted > 0 {
+		point.Precision = float64(correct) / float64(accepted)
+	}
+	if queries > 0 {
+		point.Recall = float64(correct) / float64(queries)
+	}
+	if point.Precision+point.Recall > 0 {
+		point.F1 = 2 * point.Precision * point.Recall / (point.Precision + point.Recall)
+	}
+	return point
+}
+
+func writeNLEvalTable(w io.Writer, summary nlEvalSummary) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, "API\tQUERIES\tNEGATIVES\tACCURACY\tRECALL@%d\tTHRESHOLD\n", summary.Overall.TopK)
+	for _, report := range append(summary.APIs, summary.Overall) {
+		fmt.Fprintf(table, "%s\t%d\t%d\t%.3f\t%.3f\t%.2f\n", report.APIID, report.Queries, report.Negatives,
+			report.Accuracy, report.TopKRecall, report.RecommendedThreshold)
+	}
+
+	for _, report := range summary.APIs {
+		fmt.Fprintf(table, "\n%s\n", report.APIID)
+		if len(report.Confusions) > 0 {
+			fmt.Fprintf(table, "EXPECTED\tSELECTED\tCOUNT\n")
+			for _, confusion := range report.Confusions {
+				selected := confusion.Selected
+				if selected == "" {
+					selected = "-"
+				}
+				fmt.Fprintf(table, "%s\t%s\t%d\n", confusion.Expected, selected, confusion.Count)
+			}
+			fmt.Fprintf(table, "\n")
+		}
+		fmt.Fprintf(table, "THRESHOLD\tPRECISION\tRECALL\tF1\n")
+		for _, point := range report.Curve {
+			fmt.Fprintf(table, "%.2f\t%.3f\t%.3f\t%.3f\n", point.Threshold, point.Precision, point.Recall, point.F1)
+		}
+	}
+	return table.Flush()
+}
@@ -0,0 +1,86 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"agent-bridge-plugin/agentbridge"
+)
+
+func newNLEvalResultForTests(apiId string, expected string, selected string, score float64, candidates ...string) nlEvalResult {
+	result := nlEvalResult{APIID: apiId, Query: "query", Expected: expected, Score: score}
+	if selected != "" {
+		result.Selected = &selected
+	}
+	for _, candidate := range candidates {
+		result.Candidates = append(result.Candidates, agentbridge.OperationCandidate{OperationID: candidate})
+	}
+	return result
+}
+
+func TestNLEvalReport(t *testing.T) {
+	results := []nlEvalResult{
+		newNLEvalResultForTests("jira", "getIssue", "getIssue", 0.9, "getIssue", "searchIssues"),
+		newNLEvalResultForTests("jira", "searchIssues", "getIssue", 0.6, "getIssue", "searchIssues"),
+		newNLEvalResultForTests("jira", "searchIssues", "getIssue", 0.55, "getIssue", "editIssue"),
+		newNLEvalResultForTests("jira", "createIssue", "createIssue", 0.7, "createIssue"),
+		// A query that shouldn't select any operation
+		newNLEvalResultForTests("jira", "", "deleteIssue", 0.4, "deleteIssue"),
+	}
+
+	report := newNLEvalReport("jira", results, 2)
+	assert.Equal(t, 4, report.Queries)
+	assert.Equal(t, 1, report.Negatives)
+	assert.Equal(t, 0.5, report.Accuracy)
+	assert.Equal(t, 0.75, report.TopKRecall)
+	assert.Equal(t, []nlEvalConfusion{{Expected: "searchIssues", Selected: "getIssue", Count: 2}}, report.Confusions)
+
+	assert.Len(t, report.Curve, 21)
+	// Everything is accepted: 2 correct out of 5
+	assert.InDelta(t, 0.4, report.Curve[0].Precision, 1e-9)
+	assert.InDelta(t, 0.5, report.Curve[0].Recall, 1e-9)
+	// The wrong operations are rejected above 0.6
+	assert.InDelta(t, 0.65, report.Curve[13].Threshold, 1e-9)
+	assert.InDelta(t, 1, report.Curve[13].Precision, 1e-9)
+	assert.InDelta(t, 0.5, report.Curve[13].Recall, 1e-9)
+	// Nothing is accepted
+	assert.InDelta(t, 1, report.Curve[20].Precision, 1e-9)
+	assert.InDelta(t, 0, report.Curve[20].F1, 1e-9)
+
+	assert.InDelta(t, 0.7, report.RecommendedThreshold, 1e-9)
+}
+
+func TestNLEvalSummary(t *testing.T) {
+	results := []nlEvalResult{
+		newNLEvalResultForTests("jira", "getIssue", "getIssue", 0.9, "getIssue"),
+		newNLEvalResultForTests("gmail", "sendMessage", "", 0, "listMessages"),
+	}
+
+	summary := newNLEvalSummary(results, 1)
+	assert.Len(t, summary.APIs, 2)
+	assert.Equal(t, "gmail", summary.APIs[0].APIID)
+	assert.Equal(t, []nlEvalConfusion{{Expected: "sendMessage", Selected: "", Count: 1}}, summary.APIs[0].Confusions)
+	assert.Equal(t, "jira", summary.APIs[1].APIID)
+	assert.Equal(t, NL_EVAL_ALL_APIS, summary.Overall.APIID)
+	assert.Equal(t, 2, summary.Overall.Queries)
+	assert.Equal(t, 0.5, summary.Overall.Accuracy)
+
+	output := bytes.Buffer{}
+	assert.Nil(t, writeNLEvalTable(&output, summary))
+	assert.Contains(t, output.String(), "RECALL@1")
+	assert.Contains(t, output.String(), "sendMessage  -         1")
+}
+
+func TestNLEvalUsage(t *testing.T) {
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+	assert.Equal(t, 2, runNLEval([]string{}, &stdout, &stderr))
+	assert.Equal(t, 2, runNLEval([]string{"-spec", "jira"}, &stdout, &stderr))
+	assert.Equal(t, 2, runNLEval([]string{"-spec", "jira=jira.json", "-format", "csv"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "apiId=path")
+	assert.Empty(t, stdout.String())
+}
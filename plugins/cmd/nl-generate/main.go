@@ -0,0 +1,74 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+// nl-generate asks the LLM for examples of the operations without any, and
+// saves them in the store or in an annotated copy of the specs.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"agent-bridge-plugin/agentbridge"
+)
+
+const (
+	NL_GENERATE_COMMAND = "nl-generate"
+
+	NL_GENERATE_DEFAULT_COUNT = 5
+)
+
+func main() {
+	os.Exit(runNLGenerate(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// runNLGenerate returns the exit code of the command.
+func runNLGenerate(args []string, stdout io.Writer, stderr io.Writer) int {
+	specs := agentbridge.SpecFilenames{}
+	flags := flag.NewFlagSet(NL_GENERATE_COMMAND, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Var(specs, "spec", "OpenAPI spec of an API, as apiId=path (repeatable)")
+	count := flags.Int("n", NL_GENERATE_DEFAULT_COUNT, "number of examples generated per operation")
+	store := flags.Bool("store", false, "save the examples in the store, used when the API is loaded")
+	outputDir := flags.String("output", "", "directory of the annotated copies of the specs, with x-nl-input-examples")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if len(specs) == 0 || *count < 1 || (!*store && *outputDir == "") {
+		flags.Usage()
+		return 2
+	}
+
+	if *store {
+		agentbridge.ConnectStore(context.TODO())
+	}
+	for _, apiId := range specs.APIIDs() {
+		specFilename := specs[apiId]
+		examples, err := agentbridge.GenerateSpecExamples(context.Background(), apiId, specFilename, *count)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "%s: examples generated for %d operations\n", apiId, len(examples))
+
+		if *store {
+			if err := agentbridge.SaveGeneratedExamples(apiId, examples); err != nil {
+				fmt.Fprintf(stderr, "%s\n", err)
+				return 1
+			}
+		}
+		if *outputDir != "" {
+			outputFilename := filepath.Join(*outputDir, filepath.Base(specFilename))
+			if err := agentbridge.WriteAnnotatedSpec(specFilename, outputFilename, examples); err != nil {
+				fmt.Fprintf(stderr, "%s\n", err)
+				return 1
+			}
+			fmt.Fprintf(stdout, "%s: annotated spec written to %s\n", apiId, outputFilename)
+		}
+	}
+	return 0
+}
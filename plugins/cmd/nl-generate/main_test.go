@@ -0,0 +1,21 @@
+// Copyright AGNTCY Contributors (https://github.com/agntcy)
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNLGenerateUsage(t *testing.T) {
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+	assert.Equal(t, 2, runNLGenerate([]string{}, &stdout, &stderr))
+	// Either -store or -output is required
+	assert.Equal(t, 2, runNLGenerate([]string{"-spec", "jira=jira.json"}, &stdout, &stderr))
+	assert.Equal(t, 2, runNLGenerate([]string{"-spec", "jira=jira.json", "-store", "-n", "0"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "apiId=path")
+	assert.Empty(t, stdout.String())
+}